@@ -0,0 +1,48 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+// AckPolicy Decides when an acknowledgement is sent to the peer.
+// unackedBytes is the number of bytes received since the last acknowledgement,
+// and windowSize is the window acknowledgement size notified by the peer.
+type AckPolicy interface {
+	ShouldAck(unackedBytes uint32, windowSize uint32) bool
+}
+
+// AckPolicyFunc An adapter to use ordinary functions as AckPolicy.
+type AckPolicyFunc func(unackedBytes uint32, windowSize uint32) bool
+
+func (f AckPolicyFunc) ShouldAck(unackedBytes uint32, windowSize uint32) bool {
+	return f(unackedBytes, windowSize)
+}
+
+// WindowAckPolicy Sends an acknowledgement after receiving bytes equal to the window size (5.4.4).
+// nginx-rtmp behaves like this.
+type WindowAckPolicy struct{}
+
+func (p *WindowAckPolicy) ShouldAck(unackedBytes uint32, windowSize uint32) bool {
+	return windowSize > 0 && unackedBytes >= windowSize
+}
+
+// FractionAckPolicy Sends an acknowledgement after receiving bytes equal to windowSize / Divisor.
+// e.g. Divisor = 2 is the behavior of previous versions of this library, and Divisor = 10 is the behavior of librtmp.
+type FractionAckPolicy struct {
+	Divisor uint32
+}
+
+func (p *FractionAckPolicy) ShouldAck(unackedBytes uint32, windowSize uint32) bool {
+	divisor := p.Divisor
+	if divisor == 0 {
+		divisor = 1
+	}
+
+	threshold := windowSize / divisor
+	return threshold > 0 && unackedBytes >= threshold
+}
+
+var defaultAckPolicy AckPolicy = &WindowAckPolicy{}
@@ -0,0 +1,135 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package amf3 implements AMF3 encoding and decoding.
+//
+// Values are decoded into Go values as follows when the receiver is interface{}:
+//
+//	undefined, null    -> nil
+//	false, true        -> bool
+//	integer            -> int32
+//	double             -> float64
+//	string             -> string
+//	xml-doc, xml       -> XMLDocument, XML
+//	date               -> time.Time
+//	array              -> []interface{} (dense only) or Array
+//	object (anonymous) -> map[string]interface{}
+//	object (typed)     -> *Object, or a registered Externalizable
+//	byte-array         -> []byte
+//	vector-int/uint    -> []int32, []uint32
+//	vector-double      -> []float64
+//	vector-object      -> *VectorObject
+//	dictionary         -> *Dictionary
+//
+// The encoder does the reverse. Maps and structs are encoded as anonymous objects,
+// other slices and arrays are encoded as dense arrays.
+package amf3
+
+import (
+	"io"
+)
+
+// Marker Represents AMF3 object types
+type Marker byte
+
+const (
+	// MarkerUndefined A marker for Undefined types
+	MarkerUndefined Marker = 0x00
+	// MarkerNull A marker for Null types
+	MarkerNull Marker = 0x01
+	// MarkerFalse A marker for False
+	MarkerFalse Marker = 0x02
+	// MarkerTrue A marker for True
+	MarkerTrue Marker = 0x03
+	// MarkerInteger A marker for Integer types
+	MarkerInteger Marker = 0x04
+	// MarkerDouble A marker for Double types
+	MarkerDouble Marker = 0x05
+	// MarkerString A marker for String types
+	MarkerString Marker = 0x06
+	// MarkerXMLDocument A marker for XMLDocument types
+	MarkerXMLDocument Marker = 0x07
+	// MarkerDate A marker for Date types
+	MarkerDate Marker = 0x08
+	// MarkerArray A marker for Array types
+	MarkerArray Marker = 0x09
+	// MarkerObject A marker for Object types
+	MarkerObject Marker = 0x0A
+	// MarkerXML A marker for XML types
+	MarkerXML Marker = 0x0B
+	// MarkerByteArray A marker for ByteArray types
+	MarkerByteArray Marker = 0x0C
+	// MarkerVectorInt A marker for Vector.<int> types
+	MarkerVectorInt Marker = 0x0D
+	// MarkerVectorUint A marker for Vector.<uint> types
+	MarkerVectorUint Marker = 0x0E
+	// MarkerVectorDouble A marker for Vector.<Number> types
+	MarkerVectorDouble Marker = 0x0F
+	// MarkerVectorObject A marker for Vector.<Object> types
+	MarkerVectorObject Marker = 0x10
+	// MarkerDictionary A marker for Dictionary types
+	MarkerDictionary Marker = 0x11
+)
+
+// Range of values which can be encoded as an integer type (29bits signed)
+const (
+	MinInteger = -(1 << 28)
+	MaxInteger = (1 << 28) - 1
+)
+
+// Undefined Undefined representation in Golang
+type Undefined struct{}
+
+// XMLDocument XMLDocument (flash.xml.XMLDocument) representation in Golang
+type XMLDocument string
+
+// XML XML (E4X) representation in Golang
+type XML string
+
+// Array Array which has associative entries representation in Golang
+type Array struct {
+	Associative map[string]interface{}
+	Dense       []interface{}
+}
+
+// Object Typed object representation in Golang
+type Object struct {
+	ClassName string
+	Dynamic   bool
+	Sealed    []string // Names of sealed members in order
+	Members   map[string]interface{}
+}
+
+// VectorObject Vector.<T> representation in Golang
+type VectorObject struct {
+	TypeName string // "*" means any type
+	Fixed    bool
+	Items    []interface{}
+}
+
+// DictionaryEntry An entry of Dictionary
+type DictionaryEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Dictionary Dictionary (flash.utils.Dictionary) representation in Golang
+type Dictionary struct {
+	WeakKeys bool
+	Entries  []DictionaryEntry
+}
+
+// Externalizable is implemented by types which serialize own members by themselves (flash.utils.IExternalizable).
+// Decoder and Encoder implement io.Reader and io.Writer to read and write raw data.
+type Externalizable interface {
+	ClassName() string
+	ReadExternal(d *Decoder) error
+	WriteExternal(e *Encoder) error
+}
+
+var _ io.Reader = (*Decoder)(nil)
+var _ io.Writer = (*Encoder)(nil)
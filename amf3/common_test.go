@@ -0,0 +1,244 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"time"
+)
+
+type testCase struct {
+	Name   string
+	Value  interface{}
+	Binary []byte
+}
+
+type sampleObject struct {
+	A string `amf3:"a"`
+	B int    `amf3:"b"`
+}
+
+var testCases = []testCase{
+	{
+		Name:   "Nil",
+		Value:  nil,
+		Binary: []byte{0x01},
+	},
+	{
+		Name:   "False",
+		Value:  false,
+		Binary: []byte{0x02},
+	},
+	{
+		Name:   "True",
+		Value:  true,
+		Binary: []byte{0x03},
+	},
+	{
+		Name:  "Integer (1 byte)",
+		Value: int32(1),
+		// 0x04: Integer Marker
+		// 0x01: U29
+		Binary: []byte{0x04, 0x01},
+	},
+	{
+		Name:   "Integer (2 bytes)",
+		Value:  int32(200),
+		Binary: []byte{0x04, 0x81, 0x48},
+	},
+	{
+		Name:   "Integer (negative)",
+		Value:  int32(-1),
+		Binary: []byte{0x04, 0xff, 0xff, 0xff, 0xff},
+	},
+	{
+		Name:  "Double",
+		Value: float64(1.5),
+		Binary: []byte{
+			// Double Marker
+			0x05,
+			// Value(1.5: double) BigEndian
+			0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	},
+	{
+		Name:  "String",
+		Value: "abc",
+		// 0x06: String Marker
+		// 0x07: U29S (Length = 3, inline)
+		// 0x61, 0x62, 0x63: Value(abc: []byte)
+		Binary: []byte{0x06, 0x07, 0x61, 0x62, 0x63},
+	},
+	{
+		Name:   "XML",
+		Value:  XML("<a/>"),
+		Binary: []byte{0x0b, 0x09, 0x3c, 0x61, 0x2f, 0x3e},
+	},
+	{
+		Name:  "Date",
+		Value: time.Unix(1, 0).In(time.UTC),
+		Binary: []byte{
+			// Date Marker
+			0x08,
+			// U29D (inline)
+			0x01,
+			// Value(1000: double) BigEndian
+			0x40, 0x8f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	},
+	{
+		Name:  "Array (dense)",
+		Value: []interface{}{int32(1), "a"},
+		Binary: []byte{
+			// Array Marker, U29A (Length = 2, inline)
+			0x09, 0x05,
+			// No associative entries
+			0x01,
+			// Integer(1), String(a)
+			0x04, 0x01, 0x06, 0x03, 0x61,
+		},
+	},
+	{
+		Name: "Array (associative)",
+		Value: Array{
+			Associative: map[string]interface{}{"k": int32(1)},
+			Dense:       []interface{}{int32(2)},
+		},
+		Binary: []byte{
+			0x09, 0x03,
+			// k: Integer(1)
+			0x03, 0x6b, 0x04, 0x01,
+			// End of associative entries
+			0x01,
+			// Integer(2)
+			0x04, 0x02,
+		},
+	},
+	{
+		Name:  "Array (string references)",
+		Value: []interface{}{"ab", "ab"},
+		Binary: []byte{
+			0x09, 0x05, 0x01,
+			// String(ab)
+			0x06, 0x05, 0x61, 0x62,
+			// String(Reference = 0)
+			0x06, 0x00,
+		},
+	},
+	{
+		Name: "Array (traits references)",
+		Value: []interface{}{
+			map[string]interface{}{"a": int32(1)},
+			map[string]interface{}{"a": int32(2)},
+		},
+		Binary: []byte{
+			0x09, 0x05, 0x01,
+			// Object Marker, U29O-traits (Dynamic, No sealed members), Class name("")
+			0x0a, 0x0b, 0x01,
+			// a: Integer(1), End of dynamic members
+			0x03, 0x61, 0x04, 0x01, 0x01,
+			// Object Marker, U29O-traits-ref (Reference = 0)
+			0x0a, 0x01,
+			// a(Reference = 0): Integer(2), End of dynamic members
+			0x00, 0x04, 0x02, 0x01,
+		},
+	},
+	{
+		Name:  "Object (anonymous)",
+		Value: map[string]interface{}{"a": int32(1)},
+		Binary: []byte{
+			0x0a, 0x0b, 0x01,
+			0x03, 0x61, 0x04, 0x01,
+			0x01,
+		},
+	},
+	{
+		Name: "Object (typed)",
+		Value: &Object{
+			ClassName: "C",
+			Sealed:    []string{"x"},
+			Members:   map[string]interface{}{"x": true},
+		},
+		Binary: []byte{
+			// Object Marker, U29O-traits (Not dynamic, Sealed members = 1)
+			0x0a, 0x13,
+			// Class name(C), Sealed member names(x)
+			0x03, 0x43, 0x03, 0x78,
+			// x: True
+			0x03,
+		},
+	},
+	{
+		Name:  "Object (externalizable)",
+		Value: &ArrayCollection{Source: []interface{}{int32(1)}},
+		Binary: append(
+			append(
+				// Object Marker, U29O-traits-ext, Class name(Length = 33)
+				[]byte{0x0a, 0x07, 0x43},
+				[]byte(ArrayCollectionClassName)...,
+			),
+			// Source: Array
+			0x09, 0x03, 0x01, 0x04, 0x01,
+		),
+	},
+	{
+		Name:   "ByteArray",
+		Value:  []byte{0x01, 0x02},
+		Binary: []byte{0x0c, 0x05, 0x01, 0x02},
+	},
+	{
+		Name:  "Vector (int)",
+		Value: []int32{-1},
+		Binary: []byte{
+			// Vector Marker, U29V (Length = 1, inline), Not fixed
+			0x0d, 0x03, 0x00,
+			// Value(-1: int32) BigEndian
+			0xff, 0xff, 0xff, 0xff,
+		},
+	},
+	{
+		Name:   "Vector (uint)",
+		Value:  []uint32{1},
+		Binary: []byte{0x0e, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01},
+	},
+	{
+		Name:  "Vector (double)",
+		Value: []float64{1.5},
+		Binary: []byte{
+			0x0f, 0x03, 0x00,
+			0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	},
+	{
+		Name: "Vector (object)",
+		Value: &VectorObject{
+			TypeName: "*",
+			Items:    []interface{}{"a"},
+		},
+		Binary: []byte{
+			0x10, 0x03, 0x00,
+			// Type name(*)
+			0x03, 0x2a,
+			// String(a)
+			0x06, 0x03, 0x61,
+		},
+	},
+	{
+		Name: "Dictionary",
+		Value: &Dictionary{
+			Entries: []DictionaryEntry{
+				{Key: "k", Value: int32(1)},
+			},
+		},
+		Binary: []byte{
+			// Dictionary Marker, U29Dict (Length = 1, inline), Not weak keys
+			0x11, 0x03, 0x00,
+			// String(k): Integer(1)
+			0x06, 0x03, 0x6b, 0x04, 0x01,
+		},
+	},
+}
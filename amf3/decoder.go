@@ -0,0 +1,855 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Capacity of slices which are preallocated by lengths in the data
+const maxPreallocLen = 1024
+
+type traits struct {
+	className      string
+	dynamic        bool
+	externalizable bool
+	sealed         []string
+}
+
+// Decoder Read from the reader and decode them into objects in Golang
+type Decoder struct {
+	r io.Reader
+
+	strings []string
+	objects []interface{}
+	traits  []*traits
+}
+
+// NewDecoder Create a new instance of Decoder
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: r,
+	}
+}
+
+// Decode Decode objects. Reference tables are shared among values decoded by the same decoder until Reset is called.
+func (dec *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &NotAssignableError{
+			Message: "Not non-nil pointer",
+			Kind:    rv.Kind(),
+			Type:    reflect.TypeOf(v),
+		}
+	}
+
+	value, err := dec.readValue()
+	if err != nil {
+		return err
+	}
+
+	return assign(rv.Elem(), value)
+}
+
+// Reset Reset a state of the decoder
+func (dec *Decoder) Reset(r io.Reader) {
+	dec.r = r
+
+	dec.strings = nil
+	dec.objects = nil
+	dec.traits = nil
+}
+
+// Read Reads raw data. This is intended to be used by Externalizable.ReadExternal.
+func (dec *Decoder) Read(p []byte) (int, error) {
+	return dec.r.Read(p)
+}
+
+func (dec *Decoder) readValue() (interface{}, error) {
+	marker, err := dec.readU8()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := dec.readValueOf(Marker(marker))
+	if err != nil {
+		return nil, wrapEOF(err)
+	}
+
+	return v, nil
+}
+
+func (dec *Decoder) readValueOf(marker Marker) (interface{}, error) {
+	switch marker {
+	case MarkerUndefined, MarkerNull:
+		return nil, nil
+
+	case MarkerFalse:
+		return false, nil
+
+	case MarkerTrue:
+		return true, nil
+
+	case MarkerInteger:
+		return dec.readInteger()
+
+	case MarkerDouble:
+		return dec.readDouble()
+
+	case MarkerString:
+		return dec.readString()
+
+	case MarkerXMLDocument:
+		return dec.readXML(func(s string) interface{} { return XMLDocument(s) })
+
+	case MarkerDate:
+		return dec.readDate()
+
+	case MarkerArray:
+		return dec.readArray()
+
+	case MarkerObject:
+		return dec.readObject()
+
+	case MarkerXML:
+		return dec.readXML(func(s string) interface{} { return XML(s) })
+
+	case MarkerByteArray:
+		return dec.readByteArray()
+
+	case MarkerVectorInt:
+		return dec.readVectorInt()
+
+	case MarkerVectorUint:
+		return dec.readVectorUint()
+
+	case MarkerVectorDouble:
+		return dec.readVectorDouble()
+
+	case MarkerVectorObject:
+		return dec.readVectorObject()
+
+	case MarkerDictionary:
+		return dec.readDictionary()
+
+	default:
+		return nil, &UnexpectedMarkerError{
+			Marker: uint8(marker),
+		}
+	}
+}
+
+func (dec *Decoder) readInteger() (int32, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return 0, err
+	}
+
+	// sign extension of 29bits
+	return int32(u<<3) >> 3, nil
+}
+
+func (dec *Decoder) readString() (string, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return "", err
+	}
+
+	if u&0x01 == 0 {
+		idx := int(u >> 1)
+		if idx >= len(dec.strings) {
+			return "", &DecodeError{
+				Message: fmt.Sprintf("String reference is out of range: Index = %d", idx),
+			}
+		}
+		return dec.strings[idx], nil
+	}
+
+	b, err := dec.readBytes(u >> 1)
+	if err != nil {
+		return "", err
+	}
+
+	s := string(b)
+	if s != "" { // empty strings are never sent by reference
+		dec.strings = append(dec.strings, s)
+	}
+
+	return s, nil
+}
+
+func (dec *Decoder) readXML(conv func(string) interface{}) (interface{}, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return nil, err
+	}
+	if u&0x01 == 0 {
+		return dec.lookupObject(u >> 1)
+	}
+
+	b, err := dec.readBytes(u >> 1)
+	if err != nil {
+		return nil, err
+	}
+
+	v := conv(string(b))
+	dec.objects = append(dec.objects, v)
+
+	return v, nil
+}
+
+func (dec *Decoder) readDate() (interface{}, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return nil, err
+	}
+	if u&0x01 == 0 {
+		return dec.lookupObject(u >> 1)
+	}
+
+	unixMs, err := dec.readDouble()
+	if err != nil {
+		return nil, err
+	}
+
+	ms := int64(unixMs)
+	t := time.Unix(ms/1000, ms%1000*int64(time.Millisecond)).In(time.UTC)
+	dec.objects = append(dec.objects, t)
+
+	return t, nil
+}
+
+func (dec *Decoder) readArray() (interface{}, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return nil, err
+	}
+	if u&0x01 == 0 {
+		return dec.lookupObject(u >> 1)
+	}
+	denseLen := int(u >> 1)
+
+	// Reserve a slot to keep the order of the table. It will be filled after decoding
+	idx := dec.reserveObject()
+
+	key, err := dec.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	var assoc map[string]interface{}
+	if key != "" {
+		assoc = make(map[string]interface{})
+		dec.objects[idx] = Array{Associative: assoc}
+	}
+	for ; key != ""; key, err = dec.readString() {
+		v, err := dec.readValue()
+		if err != nil {
+			return nil, err
+		}
+		assoc[key] = v
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dense := make([]interface{}, 0, minInt(denseLen, maxPreallocLen))
+	for i := 0; i < denseLen; i++ {
+		v, err := dec.readValue()
+		if err != nil {
+			return nil, err
+		}
+		dense = append(dense, v)
+	}
+
+	var v interface{} = dense
+	if assoc != nil {
+		v = Array{
+			Associative: assoc,
+			Dense:       dense,
+		}
+	}
+	dec.objects[idx] = v
+
+	return v, nil
+}
+
+func (dec *Decoder) readObject() (interface{}, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return nil, err
+	}
+	if u&0x01 == 0 {
+		return dec.lookupObject(u >> 1)
+	}
+
+	t, err := dec.readTraits(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.externalizable {
+		ext, ok := newExternalizable(t.className)
+		if !ok {
+			return nil, &UnknownExternalizableError{
+				ClassName: t.className,
+			}
+		}
+		dec.objects = append(dec.objects, ext)
+
+		if err := ext.ReadExternal(dec); err != nil {
+			return nil, err
+		}
+
+		return ext, nil
+	}
+
+	members := make(map[string]interface{})
+
+	var v interface{} = members
+	if t.className != "" {
+		v = &Object{
+			ClassName: t.className,
+			Dynamic:   t.dynamic,
+			Sealed:    append([]string(nil), t.sealed...),
+			Members:   members,
+		}
+	}
+	dec.objects = append(dec.objects, v)
+
+	for _, name := range t.sealed {
+		value, err := dec.readValue()
+		if err != nil {
+			return nil, err
+		}
+		members[name] = value
+	}
+
+	if t.dynamic {
+		for {
+			key, err := dec.readString()
+			if err != nil {
+				return nil, err
+			}
+			if key == "" {
+				break
+			}
+
+			value, err := dec.readValue()
+			if err != nil {
+				return nil, err
+			}
+			members[key] = value
+		}
+	}
+
+	return v, nil
+}
+
+func (dec *Decoder) readTraits(u uint32) (*traits, error) {
+	if u&0x02 == 0 {
+		idx := int(u >> 2)
+		if idx >= len(dec.traits) {
+			return nil, &DecodeError{
+				Message: fmt.Sprintf("Traits reference is out of range: Index = %d", idx),
+			}
+		}
+		return dec.traits[idx], nil
+	}
+
+	t := &traits{
+		externalizable: u&0x04 != 0,
+		dynamic:        u&0x08 != 0,
+	}
+
+	className, err := dec.readString()
+	if err != nil {
+		return nil, err
+	}
+	t.className = className
+
+	if !t.externalizable {
+		count := int(u >> 4)
+		t.sealed = make([]string, 0, minInt(count, maxPreallocLen))
+		for i := 0; i < count; i++ {
+			name, err := dec.readString()
+			if err != nil {
+				return nil, err
+			}
+			t.sealed = append(t.sealed, name)
+		}
+	}
+
+	dec.traits = append(dec.traits, t)
+
+	return t, nil
+}
+
+func (dec *Decoder) readByteArray() (interface{}, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return nil, err
+	}
+	if u&0x01 == 0 {
+		return dec.lookupObject(u >> 1)
+	}
+
+	b, err := dec.readBytes(u >> 1)
+	if err != nil {
+		return nil, err
+	}
+	dec.objects = append(dec.objects, b)
+
+	return b, nil
+}
+
+func (dec *Decoder) readVectorHeader() (int, bool, interface{}, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return 0, false, nil, err
+	}
+	if u&0x01 == 0 {
+		v, err := dec.lookupObject(u >> 1)
+		return 0, false, v, err
+	}
+
+	fixed, err := dec.readU8()
+	if err != nil {
+		return 0, false, nil, err
+	}
+
+	return int(u >> 1), fixed != 0, nil, nil
+}
+
+func (dec *Decoder) readVectorInt() (interface{}, error) {
+	n, _, ref, err := dec.readVectorHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	vec := make([]int32, 0, minInt(n, maxPreallocLen))
+	for i := 0; i < n; i++ {
+		u, err := dec.readU32()
+		if err != nil {
+			return nil, err
+		}
+		vec = append(vec, int32(u))
+	}
+	dec.objects = append(dec.objects, vec)
+
+	return vec, nil
+}
+
+func (dec *Decoder) readVectorUint() (interface{}, error) {
+	n, _, ref, err := dec.readVectorHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	vec := make([]uint32, 0, minInt(n, maxPreallocLen))
+	for i := 0; i < n; i++ {
+		u, err := dec.readU32()
+		if err != nil {
+			return nil, err
+		}
+		vec = append(vec, u)
+	}
+	dec.objects = append(dec.objects, vec)
+
+	return vec, nil
+}
+
+func (dec *Decoder) readVectorDouble() (interface{}, error) {
+	n, _, ref, err := dec.readVectorHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	vec := make([]float64, 0, minInt(n, maxPreallocLen))
+	for i := 0; i < n; i++ {
+		f, err := dec.readDouble()
+		if err != nil {
+			return nil, err
+		}
+		vec = append(vec, f)
+	}
+	dec.objects = append(dec.objects, vec)
+
+	return vec, nil
+}
+
+func (dec *Decoder) readVectorObject() (interface{}, error) {
+	n, fixed, ref, err := dec.readVectorHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	typeName, err := dec.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	vec := &VectorObject{
+		TypeName: typeName,
+		Fixed:    fixed,
+		Items:    make([]interface{}, 0, minInt(n, maxPreallocLen)),
+	}
+	dec.objects = append(dec.objects, vec)
+
+	for i := 0; i < n; i++ {
+		v, err := dec.readValue()
+		if err != nil {
+			return nil, err
+		}
+		vec.Items = append(vec.Items, v)
+	}
+
+	return vec, nil
+}
+
+func (dec *Decoder) readDictionary() (interface{}, error) {
+	n, weakKeys, ref, err := dec.readVectorHeader() // same layout as vectors
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	dict := &Dictionary{
+		WeakKeys: weakKeys,
+		Entries:  make([]DictionaryEntry, 0, minInt(n, maxPreallocLen)),
+	}
+	dec.objects = append(dec.objects, dict)
+
+	for i := 0; i < n; i++ {
+		key, err := dec.readValue()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := dec.readValue()
+		if err != nil {
+			return nil, err
+		}
+
+		dict.Entries = append(dict.Entries, DictionaryEntry{
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	return dict, nil
+}
+
+func (dec *Decoder) reserveObject() int {
+	dec.objects = append(dec.objects, nil)
+	return len(dec.objects) - 1
+}
+
+func (dec *Decoder) lookupObject(idx uint32) (interface{}, error) {
+	if int(idx) >= len(dec.objects) {
+		return nil, &DecodeError{
+			Message: fmt.Sprintf("Object reference is out of range: Index = %d", idx),
+		}
+	}
+
+	return dec.objects[idx], nil
+}
+
+func (dec *Decoder) readU29() (uint32, error) {
+	var n uint32
+	for i := 0; i < 3; i++ {
+		b, err := dec.readU8()
+		if err != nil {
+			return 0, err
+		}
+
+		n = n<<7 | uint32(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+
+	// The 4th byte uses all 8bits
+	b, err := dec.readU8()
+	if err != nil {
+		return 0, err
+	}
+
+	return n<<8 | uint32(b), nil
+}
+
+func (dec *Decoder) readU8() (uint8, error) {
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(dec.r, buf); err != nil {
+		return 0, err
+	}
+
+	return buf[0], nil
+}
+
+func (dec *Decoder) readU32() (uint32, error) {
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(dec.r, buf); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(buf), nil
+}
+
+func (dec *Decoder) readDouble() (float64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(dec.r, buf); err != nil {
+		return 0, err
+	}
+
+	return math.Float64frombits(binary.BigEndian.Uint64(buf)), nil
+}
+
+func (dec *Decoder) readBytes(n uint32) ([]byte, error) {
+	// Do not trust the length to allocate a buffer
+	b, err := ioutil.ReadAll(io.LimitReader(dec.r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != int(n) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return b, nil
+}
+
+func assign(rv reflect.Value, v interface{}) error {
+	if rv.Kind() == reflect.Ptr {
+		if v == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+
+		if vv := reflect.ValueOf(v); vv.Type().AssignableTo(rv.Type()) {
+			rv.Set(vv)
+			return nil
+		}
+
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return assign(rv.Elem(), v)
+	}
+
+	if v == nil {
+		switch rv.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Interface:
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		default:
+			return &NotAssignableError{
+				Message: "Not reference type",
+				Kind:    rv.Kind(),
+				Type:    rv.Type(),
+			}
+		}
+	}
+
+	vv := reflect.ValueOf(v)
+	if vv.Type().AssignableTo(rv.Type()) {
+		rv.Set(vv)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch vv.Kind() {
+		case reflect.Int32, reflect.Uint32, reflect.Float64:
+			rv.Set(vv.Convert(rv.Type()))
+			return nil
+		}
+
+	case reflect.Bool:
+		if vv.Kind() == reflect.Bool {
+			rv.SetBool(vv.Bool())
+			return nil
+		}
+
+	case reflect.String:
+		if vv.Kind() == reflect.String {
+			rv.SetString(vv.String())
+			return nil
+		}
+
+	case reflect.Map:
+		if members, ok := membersOf(v); ok {
+			return assignMap(rv, members)
+		}
+
+	case reflect.Struct:
+		if members, ok := membersOf(v); ok {
+			return assignStruct(rv, members)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if items, ok := itemsOf(v); ok {
+			return assignSlice(rv, items)
+		}
+	}
+
+	return &NotAssignableError{
+		Message: fmt.Sprintf("Not assignable from %T", v),
+		Kind:    rv.Kind(),
+		Type:    rv.Type(),
+	}
+}
+
+func membersOf(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, true
+
+	case *Object:
+		return v.Members, true
+
+	case Array:
+		members := make(map[string]interface{}, len(v.Associative)+len(v.Dense))
+		for i, e := range v.Dense {
+			members[strconv.Itoa(i)] = e
+		}
+		for k, e := range v.Associative {
+			members[k] = e
+		}
+		return members, true
+
+	default:
+		return nil, false
+	}
+}
+
+func itemsOf(v interface{}) (reflect.Value, bool) {
+	switch v := v.(type) {
+	case []interface{}, []int32, []uint32, []float64, []byte:
+		return reflect.ValueOf(v), true
+
+	case Array:
+		return reflect.ValueOf(v.Dense), true
+
+	case *VectorObject:
+		return reflect.ValueOf(v.Items), true
+
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+func assignMap(rv reflect.Value, members map[string]interface{}) error {
+	keyTy := rv.Type().Key()
+	if keyTy.Kind() != reflect.String {
+		return &NotAssignableError{
+			Message: "Key of map is not string type",
+			Kind:    keyTy.Kind(),
+			Type:    keyTy,
+		}
+	}
+
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(rv.Type()))
+	}
+
+	for k, v := range members {
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if err := assign(elem, v); err != nil {
+			return err
+		}
+		rv.SetMapIndex(reflect.ValueOf(k).Convert(keyTy), elem)
+	}
+
+	return nil
+}
+
+func assignStruct(rv reflect.Value, members map[string]interface{}) error {
+	ty := rv.Type()
+	for i := 0; i < ty.NumField(); i++ {
+		name, ok := fieldName(ty.Field(i))
+		if !ok {
+			continue
+		}
+
+		v, ok := members[name]
+		if !ok {
+			continue
+		}
+
+		if err := assign(rv.Field(i), v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func assignSlice(rv reflect.Value, items reflect.Value) error {
+	n := items.Len()
+	switch rv.Kind() {
+	case reflect.Slice:
+		rv.Set(reflect.MakeSlice(rv.Type(), n, n))
+
+	case reflect.Array:
+		if n > rv.Len() {
+			return &NotAssignableError{
+				Message: fmt.Sprintf("Too many elements: Length = %d", n),
+				Kind:    rv.Kind(),
+				Type:    rv.Type(),
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		if err := assign(rv.Index(i), items.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldName Returns a name of the field on the wire. Unexported fields and fields tagged by "-" are ignored.
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+
+	name, ok := f.Tag.Lookup("amf3")
+	if !ok {
+		return f.Name, true
+	}
+	if name == "-" {
+		return "", false
+	}
+
+	return name, true
+}
+
+func wrapEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCommon(t *testing.T) {
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			r := bytes.NewReader(tc.Binary)
+			dec := NewDecoder(r)
+
+			var v interface{}
+			err := dec.Decode(&v)
+			require.Nil(t, err)
+			require.Equal(t, tc.Value, v)
+
+			require.Equal(t, 0, r.Len())
+		})
+	}
+}
+
+func TestDecodeInteger(t *testing.T) {
+	bin := []byte{0x04, 0x81, 0x48} // Integer: 200
+
+	t.Run("int64", func(t *testing.T) {
+		var v int64
+		err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, int64(200), v)
+	})
+
+	t.Run("float64", func(t *testing.T) {
+		var v float64
+		err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, float64(200), v)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		var v string
+		err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+		require.IsType(t, &NotAssignableError{}, err)
+	})
+}
+
+func TestDecodeStruct(t *testing.T) {
+	bin := []byte{
+		0x0a, 0x0b, 0x01,
+		// a: String(str)
+		0x03, 0x61, 0x06, 0x07, 0x73, 0x74, 0x72,
+		// b: Integer(10)
+		0x03, 0x62, 0x04, 0x0a,
+		// c: Null (discarded)
+		0x03, 0x63, 0x01,
+		0x01,
+	}
+
+	var v sampleObject
+	err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+	require.Nil(t, err)
+	require.Equal(t, sampleObject{A: "str", B: 10}, v)
+
+	var pv *sampleObject
+	err = NewDecoder(bytes.NewReader(bin)).Decode(&pv)
+	require.Nil(t, err)
+	require.Equal(t, &sampleObject{A: "str", B: 10}, pv)
+}
+
+func TestDecodeObjectReference(t *testing.T) {
+	bin := []byte{
+		0x09, 0x05, 0x01,
+		// Object (Index = 1, because the array is 0)
+		0x0a, 0x0b, 0x01, 0x03, 0x61, 0x04, 0x01, 0x01,
+		// Object(Reference = 1)
+		0x0a, 0x02,
+	}
+
+	var v []interface{}
+	err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+	require.Nil(t, err)
+	require.Len(t, v, 2)
+	require.Equal(t, map[string]interface{}{"a": int32(1)}, v[0])
+
+	// Same instance
+	v[0].(map[string]interface{})["b"] = true
+	require.Equal(t, true, v[1].(map[string]interface{})["b"])
+}
+
+func TestDecodeReferenceOutOfRange(t *testing.T) {
+	for _, bin := range [][]byte{
+		{0x06, 0x00}, // String
+		{0x0a, 0x00}, // Object
+		{0x0a, 0x01}, // Traits
+	} {
+		var v interface{}
+		err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+		require.IsType(t, &DecodeError{}, err)
+	}
+}
+
+func TestDecodeUnknownExternalizable(t *testing.T) {
+	bin := []byte{0x0a, 0x07, 0x03, 0x58} // Class name(X)
+
+	var v interface{}
+	err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+	require.Equal(t, &UnknownExternalizableError{ClassName: "X"}, err)
+}
+
+func TestDecodeEOF(t *testing.T) {
+	var v interface{}
+
+	err := NewDecoder(bytes.NewReader(nil)).Decode(&v)
+	require.Equal(t, io.EOF, err)
+
+	err = NewDecoder(bytes.NewReader([]byte{0x06, 0x07, 0x61})).Decode(&v)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+}
@@ -0,0 +1,582 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxU29 = 0x1fffffff
+
+// Encoder Encode objects in Golang into AMF3 and write them to the writer
+type Encoder struct {
+	w io.Writer
+
+	strings map[string]int
+	traits  map[string]int
+}
+
+// NewEncoder Create a new instance of Encoder
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: w,
+
+		strings: make(map[string]int),
+		traits:  make(map[string]int),
+	}
+}
+
+// Encode Encode objects. Reference tables are shared among values encoded by the same encoder until Reset is called.
+func (enc *Encoder) Encode(v interface{}) error {
+	return enc.encode(reflect.ValueOf(v))
+}
+
+// Reset Reset a state of the encoder
+func (enc *Encoder) Reset(w io.Writer) {
+	enc.w = w
+
+	enc.strings = make(map[string]int)
+	enc.traits = make(map[string]int)
+}
+
+// Write Writes raw data. This is intended to be used by Externalizable.WriteExternal.
+func (enc *Encoder) Write(p []byte) (int, error) {
+	return enc.w.Write(p)
+}
+
+func (enc *Encoder) encode(rv reflect.Value) error {
+	if !rv.IsValid() {
+		return enc.writeMarker(MarkerNull)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return enc.writeMarker(MarkerNull)
+		}
+	}
+
+	if rv.CanInterface() {
+		switch v := rv.Interface().(type) {
+		case Undefined:
+			return enc.writeMarker(MarkerUndefined)
+		case time.Time:
+			return enc.encodeDate(v)
+		case XMLDocument:
+			return enc.encodeXML(MarkerXMLDocument, string(v))
+		case XML:
+			return enc.encodeXML(MarkerXML, string(v))
+		case []byte:
+			return enc.encodeByteArray(v)
+		case []int32:
+			return enc.encodeVectorInt(v)
+		case []uint32:
+			return enc.encodeVectorUint(v)
+		case []float64:
+			return enc.encodeVectorDouble(v)
+		case Array:
+			return enc.encodeArray(v.Associative, v.Dense)
+		case *Array:
+			return enc.encodeArray(v.Associative, v.Dense)
+		case Object:
+			return enc.encodeObject(&v)
+		case *Object:
+			return enc.encodeObject(v)
+		case VectorObject:
+			return enc.encodeVectorObject(&v)
+		case *VectorObject:
+			return enc.encodeVectorObject(v)
+		case Dictionary:
+			return enc.encodeDictionary(&v)
+		case *Dictionary:
+			return enc.encodeDictionary(v)
+		case Externalizable:
+			return enc.encodeExternalizable(v)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return enc.writeMarker(MarkerTrue)
+		}
+		return enc.writeMarker(MarkerFalse)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		if n < MinInteger || n > MaxInteger {
+			return enc.encodeDouble(float64(n))
+		}
+		return enc.encodeInteger(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := rv.Uint()
+		if n > MaxInteger {
+			return enc.encodeDouble(float64(n))
+		}
+		return enc.encodeInteger(int64(n))
+
+	case reflect.Float32, reflect.Float64:
+		return enc.encodeDouble(rv.Float())
+
+	case reflect.String:
+		if err := enc.writeMarker(MarkerString); err != nil {
+			return err
+		}
+		return enc.writeString(rv.String())
+
+	case reflect.Ptr, reflect.Interface:
+		return enc.encode(rv.Elem())
+
+	case reflect.Slice, reflect.Array:
+		return enc.encodeDenseArray(rv)
+
+	case reflect.Map:
+		return enc.encodeMap(rv)
+
+	case reflect.Struct:
+		return enc.encodeStruct(rv)
+
+	default:
+		return &UnexpectedValueError{
+			Kind: rv.Kind(),
+		}
+	}
+}
+
+func (enc *Encoder) encodeInteger(n int64) error {
+	if err := enc.writeMarker(MarkerInteger); err != nil {
+		return err
+	}
+	return enc.writeU29(uint32(n) & maxU29)
+}
+
+func (enc *Encoder) encodeDouble(f float64) error {
+	if err := enc.writeMarker(MarkerDouble); err != nil {
+		return err
+	}
+	return enc.writeDouble(f)
+}
+
+func (enc *Encoder) encodeXML(marker Marker, s string) error {
+	if err := enc.writeMarker(marker); err != nil {
+		return err
+	}
+	if err := enc.writeLength(len(s)); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(enc.w, s)
+	return err
+}
+
+func (enc *Encoder) encodeDate(t time.Time) error {
+	if err := enc.writeMarker(MarkerDate); err != nil {
+		return err
+	}
+	if err := enc.writeU29(0x01); err != nil {
+		return err
+	}
+
+	unixMs := t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+	return enc.writeDouble(float64(unixMs))
+}
+
+func (enc *Encoder) encodeDenseArray(rv reflect.Value) error {
+	if err := enc.writeMarker(MarkerArray); err != nil {
+		return err
+	}
+	if err := enc.writeLength(rv.Len()); err != nil {
+		return err
+	}
+	if err := enc.writeString(""); err != nil { // no associative entries
+		return err
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.encode(rv.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) encodeArray(assoc map[string]interface{}, dense []interface{}) error {
+	if err := enc.writeMarker(MarkerArray); err != nil {
+		return err
+	}
+	if err := enc.writeLength(len(dense)); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(assoc) {
+		if key == "" {
+			continue // cannot be represented
+		}
+		if err := enc.writeString(key); err != nil {
+			return err
+		}
+		if err := enc.Encode(assoc[key]); err != nil {
+			return err
+		}
+	}
+	if err := enc.writeString(""); err != nil {
+		return err
+	}
+
+	for _, v := range dense {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeObject Encodes a typed object. Members which are not sealed are encoded only if the object is dynamic.
+func (enc *Encoder) encodeObject(o *Object) error {
+	if err := enc.writeMarker(MarkerObject); err != nil {
+		return err
+	}
+	if err := enc.writeTraits(o.ClassName, o.Dynamic, o.Sealed); err != nil {
+		return err
+	}
+
+	sealed := make(map[string]struct{}, len(o.Sealed))
+	for _, name := range o.Sealed {
+		sealed[name] = struct{}{}
+		if err := enc.Encode(o.Members[name]); err != nil {
+			return err
+		}
+	}
+
+	if !o.Dynamic {
+		return nil
+	}
+
+	for _, key := range sortedKeys(o.Members) {
+		if _, ok := sealed[key]; ok || key == "" {
+			continue
+		}
+		if err := enc.writeString(key); err != nil {
+			return err
+		}
+		if err := enc.Encode(o.Members[key]); err != nil {
+			return err
+		}
+	}
+
+	return enc.writeString("")
+}
+
+// encodeMap Encodes a map as an anonymous dynamic object
+func (enc *Encoder) encodeMap(rv reflect.Value) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return &UnexpectedValueError{
+			Kind: rv.Type().Key().Kind(),
+		}
+	}
+
+	if err := enc.writeMarker(MarkerObject); err != nil {
+		return err
+	}
+	if err := enc.writeTraits("", true, nil); err != nil {
+		return err
+	}
+
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, key := range keys {
+		if key.String() == "" {
+			continue // cannot be represented
+		}
+		if err := enc.writeString(key.String()); err != nil {
+			return err
+		}
+		if err := enc.encode(rv.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+
+	return enc.writeString("")
+}
+
+// encodeStruct Encodes a struct as an anonymous dynamic object
+func (enc *Encoder) encodeStruct(rv reflect.Value) error {
+	if err := enc.writeMarker(MarkerObject); err != nil {
+		return err
+	}
+	if err := enc.writeTraits("", true, nil); err != nil {
+		return err
+	}
+
+	ty := rv.Type()
+	for i := 0; i < ty.NumField(); i++ {
+		name, ok := fieldName(ty.Field(i))
+		if !ok || name == "" {
+			continue
+		}
+		if err := enc.writeString(name); err != nil {
+			return err
+		}
+		if err := enc.encode(rv.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return enc.writeString("")
+}
+
+func (enc *Encoder) encodeExternalizable(ext Externalizable) error {
+	if err := enc.writeMarker(MarkerObject); err != nil {
+		return err
+	}
+
+	key := "ext:" + ext.ClassName()
+	if idx, ok := enc.traits[key]; ok {
+		if err := enc.writeU29(uint32(idx)<<2 | 0x01); err != nil {
+			return err
+		}
+	} else {
+		enc.traits[key] = len(enc.traits)
+		if err := enc.writeU29(0x07); err != nil {
+			return err
+		}
+		if err := enc.writeString(ext.ClassName()); err != nil {
+			return err
+		}
+	}
+
+	return ext.WriteExternal(enc)
+}
+
+func (enc *Encoder) encodeByteArray(b []byte) error {
+	if err := enc.writeMarker(MarkerByteArray); err != nil {
+		return err
+	}
+	if err := enc.writeLength(len(b)); err != nil {
+		return err
+	}
+
+	_, err := enc.w.Write(b)
+	return err
+}
+
+func (enc *Encoder) encodeVectorInt(vec []int32) error {
+	if err := enc.writeVectorHeader(MarkerVectorInt, len(vec), false); err != nil {
+		return err
+	}
+
+	for _, v := range vec {
+		if err := enc.writeU32(uint32(v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) encodeVectorUint(vec []uint32) error {
+	if err := enc.writeVectorHeader(MarkerVectorUint, len(vec), false); err != nil {
+		return err
+	}
+
+	for _, v := range vec {
+		if err := enc.writeU32(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) encodeVectorDouble(vec []float64) error {
+	if err := enc.writeVectorHeader(MarkerVectorDouble, len(vec), false); err != nil {
+		return err
+	}
+
+	for _, v := range vec {
+		if err := enc.writeDouble(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) encodeVectorObject(vec *VectorObject) error {
+	if err := enc.writeVectorHeader(MarkerVectorObject, len(vec.Items), vec.Fixed); err != nil {
+		return err
+	}
+
+	typeName := vec.TypeName
+	if typeName == "" {
+		typeName = "*"
+	}
+	if err := enc.writeString(typeName); err != nil {
+		return err
+	}
+
+	for _, v := range vec.Items {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) encodeDictionary(dict *Dictionary) error {
+	if err := enc.writeVectorHeader(MarkerDictionary, len(dict.Entries), dict.WeakKeys); err != nil {
+		return err
+	}
+
+	for _, e := range dict.Entries {
+		if err := enc.Encode(e.Key); err != nil {
+			return err
+		}
+		if err := enc.Encode(e.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) writeVectorHeader(marker Marker, n int, flag bool) error {
+	if err := enc.writeMarker(marker); err != nil {
+		return err
+	}
+	if err := enc.writeLength(n); err != nil {
+		return err
+	}
+
+	var b byte
+	if flag {
+		b = 0x01
+	}
+	_, err := enc.w.Write([]byte{b})
+	return err
+}
+
+func (enc *Encoder) writeTraits(className string, dynamic bool, sealed []string) error {
+	key := fmt.Sprintf("obj:%s\x00%t\x00%s", className, dynamic, strings.Join(sealed, "\x00"))
+	if idx, ok := enc.traits[key]; ok {
+		return enc.writeU29(uint32(idx)<<2 | 0x01)
+	}
+	enc.traits[key] = len(enc.traits)
+
+	if len(sealed) > maxU29>>4 {
+		return fmt.Errorf("Too many sealed members: %d", len(sealed))
+	}
+	flags := uint32(0x03)
+	if dynamic {
+		flags |= 0x08
+	}
+	if err := enc.writeU29(uint32(len(sealed))<<4 | flags); err != nil {
+		return err
+	}
+
+	if err := enc.writeString(className); err != nil {
+		return err
+	}
+	for _, name := range sealed {
+		if err := enc.writeString(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) writeString(s string) error {
+	if s == "" { // empty strings are never sent by reference
+		return enc.writeU29(0x01)
+	}
+
+	if idx, ok := enc.strings[s]; ok {
+		return enc.writeU29(uint32(idx) << 1)
+	}
+	enc.strings[s] = len(enc.strings)
+
+	if err := enc.writeLength(len(s)); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(enc.w, s)
+	return err
+}
+
+// writeLength Writes a U29 which has an inline flag and the length
+func (enc *Encoder) writeLength(n int) error {
+	if n > maxU29>>1 {
+		return fmt.Errorf("Length is too large: %d", n)
+	}
+
+	return enc.writeU29(uint32(n)<<1 | 0x01)
+}
+
+func (enc *Encoder) writeU29(n uint32) error {
+	var buf []byte
+	switch {
+	case n < 0x80:
+		buf = []byte{byte(n)}
+	case n < 0x4000:
+		buf = []byte{byte(n>>7) | 0x80, byte(n & 0x7f)}
+	case n < 0x200000:
+		buf = []byte{byte(n>>14) | 0x80, byte(n>>7) | 0x80, byte(n & 0x7f)}
+	case n <= maxU29:
+		buf = []byte{byte(n>>22) | 0x80, byte(n>>15) | 0x80, byte(n>>8) | 0x80, byte(n)}
+	default:
+		return fmt.Errorf("U29 is out of range: %d", n)
+	}
+
+	_, err := enc.w.Write(buf)
+	return err
+}
+
+func (enc *Encoder) writeMarker(marker Marker) error {
+	_, err := enc.w.Write([]byte{byte(marker)})
+	return err
+}
+
+func (enc *Encoder) writeU32(n uint32) error {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, n)
+
+	_, err := enc.w.Write(buf)
+	return err
+}
+
+func (enc *Encoder) writeDouble(f float64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, math.Float64bits(f))
+
+	_, err := enc.w.Write(buf)
+	return err
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeCommon(t *testing.T) {
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			enc := NewEncoder(buf)
+
+			err := enc.Encode(tc.Value)
+			require.Nil(t, err)
+			require.Equal(t, tc.Binary, buf.Bytes())
+		})
+	}
+}
+
+func TestEncodeNumber(t *testing.T) {
+	type testCase struct {
+		name   string
+		value  interface{}
+		binary []byte
+	}
+	testCases := []testCase{
+		{name: "int", value: 1, binary: []byte{0x04, 0x01}},
+		{name: "uint8", value: uint8(1), binary: []byte{0x04, 0x01}},
+		{name: "max integer", value: MaxInteger, binary: []byte{0x04, 0xbf, 0xff, 0xff, 0xff}},
+		{name: "min integer", value: MinInteger, binary: []byte{0x04, 0xc0, 0x80, 0x80, 0x00}},
+		{
+			name:   "out of integer range",
+			value:  MaxInteger + 1,
+			binary: []byte{0x05, 0x41, 0xb0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			err := NewEncoder(buf).Encode(tc.value)
+			require.Nil(t, err)
+			require.Equal(t, tc.binary, buf.Bytes())
+		})
+	}
+}
+
+func TestEncodeStruct(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := NewEncoder(buf).Encode(&sampleObject{A: "str", B: 10})
+	require.Nil(t, err)
+
+	var v map[string]interface{}
+	err = NewDecoder(buf).Decode(&v)
+	require.Nil(t, err)
+	require.Equal(t, map[string]interface{}{"a": "str", "b": int32(10)}, v)
+}
+
+func TestEncodeReset(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+
+	require.Nil(t, enc.Encode("ab"))
+	require.Nil(t, enc.Encode("ab"))
+	require.Equal(t, []byte{0x06, 0x05, 0x61, 0x62, 0x06, 0x00}, buf.Bytes())
+
+	// The string table is cleared
+	buf.Reset()
+	enc.Reset(buf)
+	require.Nil(t, enc.Encode("ab"))
+	require.Equal(t, []byte{0x06, 0x05, 0x61, 0x62}, buf.Bytes())
+}
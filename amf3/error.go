@@ -0,0 +1,69 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// UnexpectedMarkerError Occurs when an unexpected marker is passed to the decoder
+type UnexpectedMarkerError struct {
+	Marker uint8
+}
+
+// Error Returns a string representation of the error
+func (e *UnexpectedMarkerError) Error() string {
+	return fmt.Sprintf("Unexpected marker: Marker = %+v", e.Marker)
+}
+
+// UnexpectedValueError Occurs when an unexpected value is passed to the encoder
+type UnexpectedValueError struct {
+	Kind reflect.Kind
+}
+
+// Error Returns a string representation of the error
+func (e *UnexpectedValueError) Error() string {
+	return fmt.Sprintf("Unexpected value: Kind = %+v", e.Kind)
+}
+
+// DecodeError Occurs when general errors are happen in the decoder
+type DecodeError struct {
+	Message string
+}
+
+// Error Returns a string representation of the error
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Message = %s", e.Message)
+}
+
+// NotAssignableError Occurs when failed to assign a decoded value to the receiver value
+type NotAssignableError struct {
+	Message string
+	Kind    reflect.Kind
+	Type    reflect.Type
+}
+
+// Error Returns a string representation of the error
+func (e *NotAssignableError) Error() string {
+	return fmt.Sprintf("Not assignable to receiver value: Message=%+v, Kind=%s, Type=%s",
+		e.Message,
+		e.Kind.String(),
+		e.Type.String(),
+	)
+}
+
+// UnknownExternalizableError Occurs when an externalizable class which is not registered is decoded
+type UnknownExternalizableError struct {
+	ClassName string
+}
+
+// Error Returns a string representation of the error
+func (e *UnknownExternalizableError) Error() string {
+	return fmt.Sprintf("Unknown externalizable class: ClassName = %s", e.ClassName)
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"sync"
+)
+
+var externalizables = struct {
+	m         sync.RWMutex
+	factories map[string]func() Externalizable
+}{
+	factories: make(map[string]func() Externalizable),
+}
+
+// RegisterExternalizable Registers a factory of the externalizable class.
+// Externalizable objects whose classes are not registered cannot be decoded, because their formats are private.
+func RegisterExternalizable(className string, factory func() Externalizable) {
+	externalizables.m.Lock()
+	defer externalizables.m.Unlock()
+
+	externalizables.factories[className] = factory
+}
+
+func newExternalizable(className string) (Externalizable, bool) {
+	externalizables.m.RLock()
+	defer externalizables.m.RUnlock()
+
+	factory, ok := externalizables.factories[className]
+	if !ok {
+		return nil, false
+	}
+
+	return factory(), true
+}
+
+func init() {
+	RegisterExternalizable(ArrayCollectionClassName, func() Externalizable { return &ArrayCollection{} })
+	RegisterExternalizable(ObjectProxyClassName, func() Externalizable { return &ObjectProxy{} })
+}
+
+// ArrayCollectionClassName A class name of ArrayCollection
+const ArrayCollectionClassName = "flex.messaging.io.ArrayCollection"
+
+// ArrayCollection flex.messaging.io.ArrayCollection representation in Golang
+type ArrayCollection struct {
+	Source interface{}
+}
+
+// ClassName Returns the class name
+func (a *ArrayCollection) ClassName() string {
+	return ArrayCollectionClassName
+}
+
+// ReadExternal Decodes the source array
+func (a *ArrayCollection) ReadExternal(d *Decoder) error {
+	return d.Decode(&a.Source)
+}
+
+// WriteExternal Encodes the source array
+func (a *ArrayCollection) WriteExternal(e *Encoder) error {
+	return e.Encode(a.Source)
+}
+
+// ObjectProxyClassName A class name of ObjectProxy
+const ObjectProxyClassName = "flex.messaging.io.ObjectProxy"
+
+// ObjectProxy flex.messaging.io.ObjectProxy representation in Golang
+type ObjectProxy struct {
+	Object interface{}
+}
+
+// ClassName Returns the class name
+func (o *ObjectProxy) ClassName() string {
+	return ObjectProxyClassName
+}
+
+// ReadExternal Decodes the proxied object
+func (o *ObjectProxy) ReadExternal(d *Decoder) error {
+	return d.Decode(&o.Object)
+}
+
+// WriteExternal Encodes the proxied object
+func (o *ObjectProxy) WriteExternal(e *Encoder) error {
+	return e.Encode(o.Object)
+}
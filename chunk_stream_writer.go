@@ -9,6 +9,7 @@ package rtmp
 
 import (
 	"context"
+	"math"
 	"sync"
 )
 
@@ -20,12 +21,27 @@ type ChunkStreamWriter struct {
 	lastErr  error
 	aqM      sync.Mutex
 	newChunk bool
+
+	abortRequested uint32 // Accessed atomically
+	aborted        bool   // True if a partially sent message was aborted
 }
 
 func (w *ChunkStreamWriter) Write(b []byte) (int, error) {
 	return w.buf.Write(b)
 }
 
+// abort Discards the rest of a message. A next message will be sent with a full header (fmt 0)
+// because the peer does not update its state by the aborted message.
+func (w *ChunkStreamWriter) abort() {
+	w.aborted = w.buf.Len() < int(w.messageLength)
+	w.buf.Reset()
+	if w.aborted {
+		w.messageHeader = chunkMessageHeader{
+			timestamp: math.MaxUint32, // as same as initial state
+		}
+	}
+}
+
 func (w *ChunkStreamWriter) Wait(ctx context.Context) error {
 	w.aqM.Lock()
 	defer w.aqM.Unlock()
@@ -12,6 +12,7 @@ import (
 	"io"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -22,6 +23,11 @@ import (
 
 const ctrlMsgChunkStreamID = 2
 
+// commandChunkStreamID A chunk stream which is used for commands. Conn allocates chunk streams after it.
+const commandChunkStreamID = 3
+
+const maxChunkStreamID = 65599 // 5.3.1.1
+
 const maxWriterQueueSize = 64
 
 type ChunkMessage struct {
@@ -38,9 +44,12 @@ type ChunkStreamer struct {
 	mu      sync.Mutex
 
 	writerSched *chunkStreamerWriterSched
+	bandwidth   *bandwidthWindow
+
+	aggregators map[int]*chunkMessageAggregator
+	aggMu       sync.Mutex
 
 	msgDec *message.Decoder
-	msgEnc *message.Encoder
 
 	selfState *StreamControlState
 	peerState *StreamControlState
@@ -50,6 +59,8 @@ type ChunkStreamer struct {
 
 	controlStreamWriter func(chunkStreamID int, timestamp uint32, msg message.Message) error
 
+	createdAt time.Time
+
 	cacheBuffer []byte
 	config      *StreamControlStateConfig
 	logger      logrus.FieldLogger
@@ -73,17 +84,23 @@ func NewChunkStreamer(r io.Reader, w io.Writer, config *StreamControlStateConfig
 
 		writerSched: &chunkStreamerWriterSched{
 			writers: make(chan *ChunkStreamWriter, maxWriterQueueSize),
+			abortCh: make(chan struct{}, 1),
 			stopCh:  make(chan struct{}),
 		},
 
+		bandwidth: newBandwidthWindow(math.MaxInt32), // Unlimited until the peer sets bandwidth
+
+		aggregators: make(map[int]*chunkMessageAggregator),
+
 		msgDec: message.NewDecoder(nil),
-		msgEnc: message.NewEncoder(nil),
 
 		selfState: NewStreamControlState(config),
 		peerState: NewStreamControlState(config),
 
 		done: make(chan struct{}),
 
+		createdAt: time.Now(),
+
 		cacheBuffer: make([]byte, 64*1024), // cache 64KB
 		config:      config,
 		logger:      logrus.StandardLogger(),
@@ -115,6 +132,19 @@ func (cs *ChunkStreamer) Write(
 	chunkStreamID int,
 	timestamp uint32,
 	cmsg *ChunkMessage,
+) error {
+	if cs.config.AggregateMessageMaxSize > 0 {
+		return cs.writeWithAggregation(ctx, chunkStreamID, timestamp, cmsg)
+	}
+
+	return cs.writeMessage(ctx, chunkStreamID, timestamp, cmsg)
+}
+
+func (cs *ChunkStreamer) writeMessage(
+	ctx context.Context,
+	chunkStreamID int,
+	timestamp uint32,
+	cmsg *ChunkMessage,
 ) error {
 	writer, err := cs.NewChunkWriter(ctx, chunkStreamID)
 	if err != nil {
@@ -122,8 +152,8 @@ func (cs *ChunkStreamer) Write(
 	}
 	//defer writer.Close()
 
-	cs.msgEnc.Reset(writer)
-	if err := cs.msgEnc.Encode(cmsg.Message); err != nil {
+	// An encoder per a call, because Write is called from multiple goroutines
+	if err := message.NewEncoder(writer).Encode(cmsg.Message); err != nil {
 		return err
 	}
 	writer.timestamp = timestamp
@@ -134,13 +164,69 @@ func (cs *ChunkStreamer) Write(
 	return cs.Sched(writer)
 }
 
+// AbortWrite Cancels a message which is being written to the chunk stream, and sends an AbortMessage to the peer
+// if the message has been partially sent.
+func (cs *ChunkStreamer) AbortWrite(ctx context.Context, chunkStreamID int) error {
+	cs.mu.Lock()
+	writer, ok := cs.writers[chunkStreamID]
+	cs.mu.Unlock()
+	if ok {
+		atomic.StoreUint32(&writer.abortRequested, 1)
+		cs.writerSched.notifyAbort() // The writer may be blocked by the bandwidth window
+	}
+
+	// Batched messages which are not sent yet are discarded by a writer of the chunk stream
+	cs.requestDiscardAggregation(chunkStreamID)
+
+	if !ok {
+		return nil
+	}
+
+	if err := writer.Wait(ctx); err != nil {
+		atomic.StoreUint32(&writer.abortRequested, 0)
+		return errors.Wrapf(err, "Failed to wait chunk writer")
+	}
+	atomic.StoreUint32(&writer.abortRequested, 0) // The message may have been completed
+
+	aborted := writer.aborted
+	writer.aborted = false
+	defer close(writer.doneCh) // Release the writer after the AbortMessage is queued to keep the order
+
+	if !aborted {
+		return nil
+	}
+
+	// Do not use controlStreamWriter because it is not goroutine-safe
+	return cs.writeMessage(ctx, ctrlMsgChunkStreamID, 0, &ChunkMessage{
+		StreamID: ControlStreamID,
+		Message: &message.AbortMessage{
+			ChunkStreamID: uint32(chunkStreamID),
+		},
+	})
+}
+
+// abortRead Discards a partially received message of the chunk stream.
+func (cs *ChunkStreamer) abortRead(chunkStreamID int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	reader, ok := cs.readers[chunkStreamID]
+	if !ok {
+		return
+	}
+
+	if !reader.completed {
+		reader.buf.Reset()
+	}
+}
+
 func (cs *ChunkStreamer) NewChunkReader() (*ChunkStreamReader, error) {
 again:
 	reader, err := cs.readChunk()
 	if err != nil {
 		return nil, err
 	}
-	if cs.r.FragmentReadBytes() >= uint32(cs.peerState.ackWindowSize/2) { // TODO: fix size
+	if cs.ackPolicy().ShouldAck(cs.r.FragmentReadBytes(), uint32(cs.peerState.AckWindowSize())) {
 		if err := cs.sendAck(cs.r.TotalReadBytes()); err != nil {
 			return nil, err
 		}
@@ -401,6 +487,40 @@ func (cs *ChunkStreamer) prepareChunkReader(chunkStreamID int) (*ChunkStreamRead
 	return reader, nil
 }
 
+// removeChunkWriter Flushes batched messages of the chunk stream, waits for a message being written,
+// and then removes the writer. A next writer of the same ID starts with a full header.
+func (cs *ChunkStreamer) removeChunkWriter(ctx context.Context, chunkStreamID int) error {
+	cs.aggMu.Lock()
+	agg, ok := cs.aggregators[chunkStreamID]
+	delete(cs.aggregators, chunkStreamID)
+	cs.aggMu.Unlock()
+	if ok {
+		agg.m.Lock()
+		err := cs.flushAggregator(ctx, agg)
+		agg.m.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	cs.mu.Lock()
+	writer, ok := cs.writers[chunkStreamID]
+	cs.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if err := writer.Wait(ctx); err != nil {
+		return errors.Wrapf(err, "Failed to wait chunk writer")
+	}
+
+	cs.mu.Lock()
+	delete(cs.writers, chunkStreamID)
+	cs.mu.Unlock()
+
+	return nil
+}
+
 func (cs *ChunkStreamer) prepareChunkWriter(chunkStreamID int) (*ChunkStreamWriter, error) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
@@ -434,10 +554,54 @@ func (cs *ChunkStreamer) prepareChunkWriter(chunkStreamID int) (*ChunkStreamWrit
 	return writer, nil
 }
 
+// setPeerBandwidth Limits output bandwidth by the window, and responds WinAckSize to make the peer
+// acknowledge within the window if the size is different from the last one (5.4.5).
+func (cs *ChunkStreamer) setPeerBandwidth(size int32, limitType message.LimitType) error {
+	if err := cs.peerState.SetPeerBandwidth(size, limitType); err != nil {
+		return err
+	}
+
+	windowSize := cs.peerState.BandwidthWindowSize()
+	cs.bandwidth.setSize(windowSize)
+
+	if windowSize == cs.selfState.AckWindowSize() {
+		return nil
+	}
+	if err := cs.selfState.SetAckWindowSize(windowSize); err != nil {
+		return err
+	}
+
+	cs.logger.Debugf("Sending WinAckSize...: Size = %d", windowSize)
+	return cs.controlStreamWriter(ctrlMsgChunkStreamID, cs.elapsedTimestamp(), &message.WinAckSize{
+		Size: windowSize,
+	})
+}
+
+// ackReceived Releases the bandwidth window by the sequence number which the peer acknowledged.
+func (cs *ChunkStreamer) ackReceived(sequenceNumber uint32) {
+	cs.bandwidth.ack(sequenceNumber)
+}
+
+// elapsedTimestamp Returns milliseconds since the streamer is created, used as timestamps of protocol control messages.
+func (cs *ChunkStreamer) elapsedTimestamp() uint32 {
+	return uint32(time.Since(cs.createdAt) / time.Millisecond)
+}
+
+// sendable Returns true if unacknowledged bytes are within the bandwidth window. Called from the writer loop.
+func (cs *ChunkStreamer) sendable() bool {
+	return cs.bandwidth.available(cs.w.TotalWrittenBytes())
+}
+
+func (cs *ChunkStreamer) ackPolicy() AckPolicy {
+	if cs.config.AckPolicy == nil { // config may not be normalized
+		return defaultAckPolicy
+	}
+	return cs.config.AckPolicy
+}
+
 func (cs *ChunkStreamer) sendAck(readBytes uint32) error {
 	cs.logger.Debugf("Sending Ack...: Bytes = %d", readBytes)
-	// TODO: fix timestamp
-	return cs.controlStreamWriter(ctrlMsgChunkStreamID, 0, &message.Ack{
+	return cs.controlStreamWriter(ctrlMsgChunkStreamID, cs.elapsedTimestamp(), &message.Ack{
 		SequenceNumber: readBytes,
 	})
 }
@@ -445,6 +609,7 @@ func (cs *ChunkStreamer) sendAck(readBytes uint32) error {
 type chunkStreamerWriterSched struct {
 	streamer *ChunkStreamer
 	writers  chan *ChunkStreamWriter
+	abortCh  chan struct{}
 	stopCh   chan struct{}
 }
 
@@ -454,6 +619,14 @@ func (sched *chunkStreamerWriterSched) Sched(writer *ChunkStreamWriter) error {
 	return nil
 }
 
+// notifyAbort Wakes the scheduler to abort writers which are blocked by the bandwidth window.
+func (sched *chunkStreamerWriterSched) notifyAbort() {
+	select {
+	case sched.abortCh <- struct{}{}:
+	default:
+	}
+}
+
 func (sched *chunkStreamerWriterSched) Run() (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -465,26 +638,73 @@ func (sched *chunkStreamerWriterSched) Run() (err error) {
 		}
 	}()
 
+	var blocked []*ChunkStreamWriter // Writers which wait for acknowledgements from the peer
 	for {
-		select {
-		case writer := <-sched.writers:
-			isCompleted, err := sched.streamer.writeChunk(writer)
-			if err != nil {
-				writer.lastErr = err
-				close(writer.doneCh)
-				return err
-			}
-			if isCompleted {
-				close(writer.doneCh)
+		var writer *ChunkStreamWriter
+		if len(blocked) > 0 && sched.streamer.sendable() {
+			writer, blocked = blocked[0], blocked[1:]
+		} else {
+			select {
+			case writer = <-sched.writers:
+			case <-sched.streamer.bandwidth.updated():
+				continue
+			case <-sched.abortCh:
+				blocked = abortBlockedWriters(blocked)
 				continue
+			case <-sched.stopCh:
+				releaseBlockedWriters(blocked)
+				return nil
 			}
+		}
 
-			// Enqueue writer
-			sched.writers <- writer
+		if atomic.SwapUint32(&writer.abortRequested, 0) == 1 {
+			writer.abort()
+			close(writer.doneCh)
+			continue
+		}
 
-		case <-sched.stopCh:
-			return nil
+		// Protocol control messages are not limited to avoid deadlocks
+		if writer.basicHeader.chunkStreamID != ctrlMsgChunkStreamID && !sched.streamer.sendable() {
+			blocked = append(blocked, writer)
+			continue
 		}
+
+		isCompleted, err := sched.streamer.writeChunk(writer)
+		if err != nil {
+			writer.lastErr = err
+			close(writer.doneCh)
+			return err
+		}
+		if isCompleted {
+			close(writer.doneCh)
+			continue
+		}
+
+		// Enqueue writer
+		sched.writers <- writer
+	}
+}
+
+// abortBlockedWriters Aborts writers which are requested to be aborted, and returns the rest of them.
+func abortBlockedWriters(blocked []*ChunkStreamWriter) []*ChunkStreamWriter {
+	rest := blocked[:0]
+	for _, writer := range blocked {
+		if atomic.SwapUint32(&writer.abortRequested, 0) == 1 {
+			writer.abort()
+			close(writer.doneCh)
+			continue
+		}
+		rest = append(rest, writer)
+	}
+
+	return rest
+}
+
+// releaseBlockedWriters Releases writers which will not be written anymore because the scheduler is stopped.
+func releaseBlockedWriters(blocked []*ChunkStreamWriter) {
+	for _, writer := range blocked {
+		writer.lastErr = errors.New("Writer scheduler is stopped")
+		close(writer.doneCh)
 	}
 }
 
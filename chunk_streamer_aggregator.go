@@ -0,0 +1,221 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+// A size of a sub message in aggregate messages is header(11) + data + back pointer(4)
+const aggregateSubMessageOverhead = 11 + 4
+
+// chunkMessageAggregator Batches audio/video messages written to a chunk stream.
+// m serializes writes to the chunk stream to keep the order of messages, and cs.aggMu is not held while writing.
+type chunkMessageAggregator struct {
+	chunkStreamID    int
+	batch            *chunkMessageBatch
+	discardRequested uint32 // Accessed atomically. Batched messages are discarded by a next writer if it is 1
+	m                sync.Mutex
+}
+
+// chunkMessageBatch Messages which are batched into an aggregate message.
+type chunkMessageBatch struct {
+	streamID      uint32
+	timestamp     uint32 // A timestamp of the first message
+	lastTimestamp uint32
+	size          uint32
+	messages      []*message.AggregateSubMessage
+	timer         *time.Timer
+}
+
+// writeWithAggregation Writes a message, or batches it if the message is a small audio/video message.
+// Batched messages are written as an aggregate message when they reached to the size or duration limit,
+// or when a message which cannot be batched is written to the same chunk stream.
+// Batched messages are also written by a timer if no messages are written until the duration limit elapses.
+func (cs *ChunkStreamer) writeWithAggregation(
+	ctx context.Context,
+	chunkStreamID int,
+	timestamp uint32,
+	cmsg *ChunkMessage,
+) error {
+	agg := cs.aggregatorOf(chunkStreamID)
+
+	agg.m.Lock()
+	defer agg.m.Unlock()
+
+	agg.discardIfRequested()
+
+	var data []byte
+	var subMsg message.Message
+	switch msg := cmsg.Message.(type) {
+	case *message.AudioMessage:
+		payload, err := ioutil.ReadAll(msg.Payload)
+		if err != nil {
+			return err
+		}
+		data, subMsg = payload, &message.AudioMessage{Payload: bytes.NewReader(payload)}
+
+	case *message.VideoMessage:
+		payload, err := ioutil.ReadAll(msg.Payload)
+		if err != nil {
+			return err
+		}
+		data, subMsg = payload, &message.VideoMessage{Payload: bytes.NewReader(payload)}
+	}
+
+	subMsgSize := uint32(aggregateSubMessageOverhead + len(data))
+	if subMsg == nil || subMsgSize > cs.config.AggregateMessageMaxSize {
+		// Write batched messages first to keep the order of messages
+		if err := cs.flushAggregator(ctx, agg); err != nil {
+			return err
+		}
+
+		if subMsg != nil {
+			// The payload has been consumed, thus use a copied message
+			cmsg = &ChunkMessage{
+				StreamID: cmsg.StreamID,
+				Message:  subMsg,
+			}
+		}
+		return cs.writeMessage(ctx, chunkStreamID, timestamp, cmsg)
+	}
+
+	if batch := agg.batch; batch != nil {
+		if batch.streamID != cmsg.StreamID ||
+			timestamp < batch.lastTimestamp ||
+			batch.size+subMsgSize > cs.config.AggregateMessageMaxSize {
+			if err := cs.flushAggregator(ctx, agg); err != nil {
+				return err
+			}
+		}
+	}
+
+	batch := agg.batch
+	if batch == nil {
+		batch = &chunkMessageBatch{
+			streamID:  cmsg.StreamID,
+			timestamp: timestamp,
+		}
+		duration := time.Duration(cs.config.AggregateMessageMaxDuration) * time.Millisecond
+		batch.timer = time.AfterFunc(duration, func() {
+			cs.flushAggregatorByTimer(agg, batch)
+		})
+		agg.batch = batch
+	}
+
+	batch.messages = append(batch.messages, &message.AggregateSubMessage{
+		Timestamp: timestamp,
+		Message:   subMsg,
+	})
+	batch.size += subMsgSize
+	batch.lastTimestamp = timestamp
+
+	if batch.size >= cs.config.AggregateMessageMaxSize ||
+		batch.lastTimestamp-batch.timestamp >= cs.config.AggregateMessageMaxDuration {
+		return cs.flushAggregator(ctx, agg)
+	}
+
+	return nil
+}
+
+func (cs *ChunkStreamer) aggregatorOf(chunkStreamID int) *chunkMessageAggregator {
+	cs.aggMu.Lock()
+	defer cs.aggMu.Unlock()
+
+	agg, ok := cs.aggregators[chunkStreamID]
+	if !ok {
+		agg = &chunkMessageAggregator{
+			chunkStreamID: chunkStreamID,
+		}
+		cs.aggregators[chunkStreamID] = agg
+	}
+
+	return agg
+}
+
+// requestDiscardAggregation Marks batched messages of the chunk stream to be discarded without waiting for writers.
+func (cs *ChunkStreamer) requestDiscardAggregation(chunkStreamID int) {
+	agg := cs.aggregatorOf(chunkStreamID)
+	atomic.StoreUint32(&agg.discardRequested, 1)
+}
+
+// flushAggregator Writes batched messages as an aggregate message. agg.m must be locked by a caller.
+func (cs *ChunkStreamer) flushAggregator(ctx context.Context, agg *chunkMessageAggregator) error {
+	agg.discardIfRequested()
+
+	batch := agg.batch
+	if batch == nil {
+		return nil
+	}
+	agg.batch = nil
+	batch.timer.Stop()
+
+	return cs.writeMessage(ctx, agg.chunkStreamID, batch.timestamp, &ChunkMessage{
+		StreamID: batch.streamID,
+		Message: &message.AggregateMessage{
+			Messages: batch.messages,
+		},
+	})
+}
+
+func (cs *ChunkStreamer) flushAggregatorByTimer(agg *chunkMessageAggregator, batch *chunkMessageBatch) {
+	agg.m.Lock()
+	defer agg.m.Unlock()
+
+	if agg.batch != batch {
+		return // Already flushed
+	}
+
+	// NOTE: 3s is addhoc value as same as waitWriters
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := cs.flushAggregator(ctx, agg); err != nil {
+		cs.logger.Warnf("Failed to flush aggregated messages: ID = %d, Err = %+v", agg.chunkStreamID, err)
+	}
+}
+
+func (cs *ChunkStreamer) flushAggregators() {
+	cs.aggMu.Lock()
+	aggs := make([]*chunkMessageAggregator, 0, len(cs.aggregators))
+	for _, agg := range cs.aggregators {
+		aggs = append(aggs, agg)
+	}
+	cs.aggMu.Unlock()
+
+	// NOTE: 3s is addhoc value as same as waitWriters
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	for _, agg := range aggs {
+		agg.m.Lock()
+		if err := cs.flushAggregator(ctx, agg); err != nil {
+			cs.logger.Warnf("Failed to flush aggregated messages: ID = %d, Err = %+v", agg.chunkStreamID, err)
+		}
+		agg.m.Unlock()
+	}
+}
+
+// discardIfRequested Discards batched messages if it is requested by AbortWrite. agg.m must be locked by a caller.
+func (agg *chunkMessageAggregator) discardIfRequested() {
+	if atomic.SwapUint32(&agg.discardRequested, 0) == 0 {
+		return
+	}
+
+	if agg.batch != nil {
+		agg.batch.timer.Stop()
+		agg.batch = nil
+	}
+}
@@ -0,0 +1,69 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"sync"
+)
+
+// bandwidthWindow Tracks unacknowledged bytes to limit output bandwidth by the peer (5.4.5).
+type bandwidthWindow struct {
+	size       int32
+	ackedBytes uint32
+	updatedCh  chan struct{}
+	m          sync.Mutex
+}
+
+func newBandwidthWindow(size int32) *bandwidthWindow {
+	return &bandwidthWindow{
+		size:      size,
+		updatedCh: make(chan struct{}, 1),
+	}
+}
+
+func (w *bandwidthWindow) setSize(size int32) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.size = size
+	w.notify()
+}
+
+func (w *bandwidthWindow) ack(sequenceNumber uint32) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.ackedBytes = sequenceNumber
+	w.notify()
+}
+
+// available Returns true if more bytes can be sent.
+func (w *bandwidthWindow) available(sentBytes uint32) bool {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// Counters wrap around. Some peers count bytes of handshakes, thus acked bytes may be ahead of sent bytes.
+	unacked := int32(sentBytes - w.ackedBytes)
+	if unacked < 0 {
+		unacked = 0
+	}
+
+	return unacked < w.size
+}
+
+// updated Returns a channel which is notified when the window may be available.
+func (w *bandwidthWindow) updated() <-chan struct{} {
+	return w.updatedCh
+}
+
+func (w *bandwidthWindow) notify() {
+	select {
+	case w.updatedCh <- struct{}{}:
+	default:
+	}
+}
@@ -13,8 +13,8 @@ import (
 
 type ChunkStreamerReader struct {
 	reader            io.Reader
-	totalReadBytes    uint32 // TODO: Check overflow
-	fragmentReadBytes uint32
+	totalReadBytes    uint32 // Wraps around as same as sequence numbers of Ack (5.4.3)
+	fragmentReadBytes uint32 // Bytes since the last acknowledgement
 }
 
 func (r *ChunkStreamerReader) Read(b []byte) (int, error) {
@@ -12,8 +12,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -541,3 +544,381 @@ func BenchmarkStreamerMultipleChunkRead(b *testing.B) {
 		}
 	}
 }
+
+func TestChunkStreamerAggregatesMessages(t *testing.T) {
+	buf := new(bytes.Buffer)
+	inbuf := bufio.NewReaderSize(buf, 2048)
+	outbuf := bufio.NewWriterSize(buf, 2048)
+
+	streamer := NewChunkStreamer(inbuf, outbuf, (&StreamControlStateConfig{
+		AggregateMessageMaxSize:     64,
+		AggregateMessageMaxDuration: 100,
+	}).normalize())
+	defer streamer.Close()
+
+	chunkStreamID := 6
+	write := func(timestamp uint32, msg message.Message) {
+		err := streamer.Write(context.Background(), chunkStreamID, timestamp, &ChunkMessage{
+			StreamID: 1,
+			Message:  msg,
+		})
+		require.Nil(t, err)
+	}
+
+	// Each sub message uses 25 bytes, thus the 3rd message is not batched into the 1st aggregate message
+	write(10, &message.VideoMessage{Payload: bytes.NewReader([]byte("0123456789"))})
+	write(20, &message.AudioMessage{Payload: bytes.NewReader([]byte("0123456789"))})
+	write(30, &message.VideoMessage{Payload: bytes.NewReader([]byte("0123456789"))})
+	// A message which cannot be batched flushes batched messages
+	write(40, &message.Ack{SequenceNumber: 42})
+
+	streamer.waitWriters()
+
+	read := func() (uint32, message.Message) {
+		var cmsg ChunkMessage
+		_, timestamp, err := streamer.Read(&cmsg)
+		require.Nil(t, err)
+		require.Equal(t, uint32(1), cmsg.StreamID)
+
+		return timestamp, cmsg.Message
+	}
+
+	timestamp, msg := read()
+	require.Equal(t, uint32(10), timestamp)
+	aggMsg, ok := msg.(*message.AggregateMessage)
+	require.True(t, ok)
+	require.Len(t, aggMsg.Messages, 2)
+	require.Equal(t, uint32(10), aggMsg.Messages[0].Timestamp)
+	require.IsType(t, &message.VideoMessage{}, aggMsg.Messages[0].Message)
+	require.Equal(t, uint32(20), aggMsg.Messages[1].Timestamp)
+	require.IsType(t, &message.AudioMessage{}, aggMsg.Messages[1].Message)
+
+	timestamp, msg = read()
+	require.Equal(t, uint32(30), timestamp)
+	aggMsg, ok = msg.(*message.AggregateMessage)
+	require.True(t, ok)
+	require.Len(t, aggMsg.Messages, 1)
+
+	timestamp, msg = read()
+	require.Equal(t, uint32(40), timestamp)
+	require.Equal(t, &message.Ack{SequenceNumber: 42}, msg)
+}
+
+func TestChunkStreamerFlushesAggregatedMessagesByTimer(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	writerStreamer := NewChunkStreamer(nil, pw, (&StreamControlStateConfig{
+		AggregateMessageMaxSize:     64,
+		AggregateMessageMaxDuration: 100,
+	}).normalize())
+	defer writerStreamer.Close()
+
+	readerStreamer := NewChunkStreamer(bufio.NewReader(pr), nil, nil)
+	defer readerStreamer.Close()
+
+	chunkStreamID := 6
+	err := writerStreamer.Write(context.Background(), chunkStreamID, 10, &ChunkMessage{
+		StreamID: 1,
+		Message:  &message.VideoMessage{Payload: bytes.NewReader([]byte("0123456789"))},
+	})
+	require.Nil(t, err)
+
+	// No more messages are written, but the batched message is flushed after the duration limit
+	var cmsg ChunkMessage
+	_, timestamp, err := readerStreamer.Read(&cmsg)
+	require.Nil(t, err)
+	require.Equal(t, uint32(10), timestamp)
+	aggMsg, ok := cmsg.Message.(*message.AggregateMessage)
+	require.True(t, ok)
+	require.Len(t, aggMsg.Messages, 1)
+}
+
+func TestChunkStreamerAbortDiscardsAggregatedMessages(t *testing.T) {
+	buf := new(bytes.Buffer)
+	inbuf := bufio.NewReaderSize(buf, 2048)
+	outbuf := bufio.NewWriterSize(buf, 2048)
+
+	streamer := NewChunkStreamer(inbuf, outbuf, (&StreamControlStateConfig{
+		AggregateMessageMaxSize:     64,
+		AggregateMessageMaxDuration: 100,
+	}).normalize())
+	defer streamer.Close()
+
+	chunkStreamID := 6
+	write := func(timestamp uint32, msg message.Message) {
+		err := streamer.Write(context.Background(), chunkStreamID, timestamp, &ChunkMessage{
+			StreamID: 1,
+			Message:  msg,
+		})
+		require.Nil(t, err)
+	}
+
+	write(10, &message.VideoMessage{Payload: bytes.NewReader([]byte("0123456789"))})
+
+	err := streamer.AbortWrite(context.Background(), chunkStreamID)
+	require.Nil(t, err)
+
+	write(20, &message.Ack{SequenceNumber: 42})
+
+	streamer.waitWriters()
+
+	var cmsg ChunkMessage
+	_, timestamp, err := streamer.Read(&cmsg)
+	require.Nil(t, err)
+	require.Equal(t, uint32(20), timestamp)
+	require.Equal(t, &message.Ack{SequenceNumber: 42}, cmsg.Message)
+}
+
+func TestChunkStreamerAbortPartiallySentMessage(t *testing.T) {
+	buf := new(bytes.Buffer)
+	inbuf := bufio.NewReaderSize(buf, 2048)
+	outbuf := bufio.NewWriterSize(buf, 2048)
+
+	streamer := NewChunkStreamer(inbuf, outbuf, nil)
+	defer streamer.Close()
+
+	chunkStreamID := 6
+	largePayload := []byte(strings.Repeat("a", 1024))
+
+	// Send the first chunk of a large message only
+	w, err := streamer.NewChunkWriter(context.Background(), chunkStreamID)
+	require.Nil(t, err)
+
+	msg := &message.VideoMessage{
+		Payload: bytes.NewReader(largePayload),
+	}
+	err = message.NewEncoder(w).Encode(msg)
+	require.Nil(t, err)
+	w.messageLength = uint32(w.buf.Len())
+	w.messageTypeID = byte(msg.TypeID())
+	w.messageStreamID = 1
+	w.timestamp = 100
+
+	isCompleted, err := streamer.writeChunk(w)
+	require.Nil(t, err)
+	require.False(t, isCompleted)
+
+	atomic.StoreUint32(&w.abortRequested, 1) // Ensure that the rest of the message is aborted
+	err = streamer.Sched(w)
+	require.Nil(t, err)
+
+	err = streamer.AbortWrite(context.Background(), chunkStreamID)
+	require.Nil(t, err)
+
+	// Write a next message to the same chunk stream
+	err = streamer.Write(context.Background(), chunkStreamID, 50, &ChunkMessage{
+		StreamID: 1,
+		Message: &message.VideoMessage{
+			Payload: bytes.NewReader([]byte("next")),
+		},
+	})
+	require.Nil(t, err)
+
+	streamer.waitWriters()
+
+	// The first chunk
+	r, err := streamer.readChunk()
+	require.Nil(t, err)
+	require.False(t, r.completed)
+
+	// AbortMessage
+	var cmsg ChunkMessage
+	_, _, err = streamer.Read(&cmsg)
+	require.Nil(t, err)
+	require.Equal(t, &message.AbortMessage{ChunkStreamID: uint32(chunkStreamID)}, cmsg.Message)
+
+	streamer.abortRead(chunkStreamID)
+
+	// The next message can be read without corruptions
+	_, timestamp, err := streamer.Read(&cmsg)
+	require.Nil(t, err)
+	require.Equal(t, uint32(50), timestamp)
+	videoMsg, ok := cmsg.Message.(*message.VideoMessage)
+	require.True(t, ok)
+	content, err := ioutil.ReadAll(videoMsg.Payload)
+	require.Nil(t, err)
+	require.Equal(t, []byte("next"), content)
+}
+
+func TestChunkStreamerLimitsBandwidth(t *testing.T) {
+	buf := new(bytes.Buffer)
+	inbuf := bufio.NewReaderSize(buf, 2048)
+	outbuf := bufio.NewWriterSize(ioutil.Discard, 2048)
+
+	streamer := NewChunkStreamer(inbuf, outbuf, nil)
+	defer streamer.Close()
+
+	var ctrlMsgs []message.Message
+	streamer.controlStreamWriter = func(chunkStreamID int, timestamp uint32, msg message.Message) error {
+		ctrlMsgs = append(ctrlMsgs, msg)
+		return nil
+	}
+
+	err := streamer.setPeerBandwidth(300, message.LimitTypeHard)
+	require.Nil(t, err)
+	require.Equal(t, []message.Message{&message.WinAckSize{Size: 300}}, ctrlMsgs)
+
+	chunkStreamID := 6
+	err = streamer.Write(context.Background(), chunkStreamID, 0, &ChunkMessage{
+		Message: &message.VideoMessage{
+			Payload: bytes.NewReader(make([]byte, 1024)),
+		},
+	})
+	require.Nil(t, err)
+
+	// Writing is paused because the window is exhausted
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = streamer.NewChunkWriter(ctx, chunkStreamID)
+	require.Error(t, err)
+
+	// Resume writing by acknowledgements
+	streamer.ackReceived(2048)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	_, err = streamer.NewChunkWriter(ctx, chunkStreamID)
+	require.Nil(t, err)
+}
+
+func TestChunkStreamerAbortsMessageBlockedByBandwidth(t *testing.T) {
+	buf := new(bytes.Buffer)
+	inbuf := bufio.NewReaderSize(buf, 2048)
+	outbuf := bufio.NewWriterSize(ioutil.Discard, 2048)
+
+	streamer := NewChunkStreamer(inbuf, outbuf, nil)
+	defer streamer.Close()
+
+	streamer.controlStreamWriter = func(chunkStreamID int, timestamp uint32, msg message.Message) error {
+		return nil
+	}
+
+	err := streamer.setPeerBandwidth(300, message.LimitTypeHard)
+	require.Nil(t, err)
+
+	chunkStreamID := 6
+	err = streamer.Write(context.Background(), chunkStreamID, 0, &ChunkMessage{
+		Message: &message.VideoMessage{
+			Payload: bytes.NewReader(make([]byte, 1024)),
+		},
+	})
+	require.Nil(t, err)
+
+	// Writing is paused because the window is exhausted
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = streamer.NewChunkWriter(ctx, chunkStreamID)
+	require.Error(t, err)
+
+	// The message is aborted without acknowledgements from the peer
+	ctx, cancel = context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	err = streamer.AbortWrite(ctx, chunkStreamID)
+	require.Nil(t, err)
+
+	_, err = streamer.NewChunkWriter(ctx, chunkStreamID)
+	require.Nil(t, err)
+}
+
+func TestChunkStreamerReleasesBlockedWritersOnClose(t *testing.T) {
+	buf := new(bytes.Buffer)
+	inbuf := bufio.NewReaderSize(buf, 2048)
+	outbuf := bufio.NewWriterSize(ioutil.Discard, 2048)
+
+	streamer := NewChunkStreamer(inbuf, outbuf, nil)
+
+	streamer.controlStreamWriter = func(chunkStreamID int, timestamp uint32, msg message.Message) error {
+		return nil
+	}
+
+	err := streamer.setPeerBandwidth(300, message.LimitTypeHard)
+	require.Nil(t, err)
+
+	chunkStreamID := 6
+	err = streamer.Write(context.Background(), chunkStreamID, 0, &ChunkMessage{
+		Message: &message.VideoMessage{
+			Payload: bytes.NewReader(make([]byte, 1024)),
+		},
+	})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = streamer.NewChunkWriter(ctx, chunkStreamID)
+	require.Error(t, err)
+
+	err = streamer.Close()
+	require.Nil(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	_, err = streamer.NewChunkWriter(ctx, chunkStreamID)
+	require.EqualError(t, err, "Failed to wait chunk writer: Writer scheduler is stopped")
+}
+
+func TestChunkStreamerSendsAckWithWraparound(t *testing.T) {
+	testCases := []struct {
+		name         string
+		policy       AckPolicy
+		expectedAcks []uint32
+	}{
+		{
+			name:         "Window",
+			policy:       &WindowAckPolicy{},
+			expectedAcks: []uint32{190}, // Only one ack for the window (300 bytes)
+		},
+		{
+			name:         "Fraction",
+			policy:       &FractionAckPolicy{Divisor: 2},
+			expectedAcks: []uint32{90}, // An ack after 200 bytes (>= 150 bytes)
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			inbuf := bufio.NewReaderSize(buf, 2048)
+			outbuf := bufio.NewWriterSize(buf, 2048)
+
+			streamer := NewChunkStreamer(inbuf, outbuf, (&StreamControlStateConfig{
+				AckPolicy: tc.policy,
+			}).normalize())
+			defer streamer.Close()
+
+			var acks []uint32
+			streamer.controlStreamWriter = func(chunkStreamID int, timestamp uint32, msg message.Message) error {
+				acks = append(acks, msg.(*message.Ack).SequenceNumber)
+				return nil
+			}
+			err := streamer.PeerState().SetAckWindowSize(300)
+			require.Nil(t, err)
+
+			// Sequence numbers wrap around during reading
+			streamer.r.totalReadBytes = math.MaxUint32 - 109
+
+			// 3 messages which use 100 bytes for each (header 12 + payload 88)
+			for i := 0; i < 3; i++ {
+				err := streamer.Write(context.Background(), 6+i, 0, &ChunkMessage{
+					Message: &message.VideoMessage{
+						Payload: bytes.NewReader(make([]byte, 88)),
+					},
+				})
+				require.Nil(t, err)
+			}
+			streamer.waitWriters()
+
+			for i := 0; i < 3; i++ {
+				var cmsg ChunkMessage
+				_, _, err := streamer.Read(&cmsg)
+				require.Nil(t, err)
+			}
+
+			require.Equal(t, tc.expectedAcks, acks)
+		})
+	}
+}
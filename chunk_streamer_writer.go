@@ -13,11 +13,14 @@ import (
 )
 
 type ChunkStreamerWriter struct {
-	writer io.Writer
+	writer            io.Writer
+	totalWrittenBytes uint32 // Wraps around as same as sequence numbers of Ack
 }
 
 func (w *ChunkStreamerWriter) Write(buf []byte) (int, error) {
-	return w.writer.Write(buf)
+	n, err := w.writer.Write(buf)
+	w.totalWrittenBytes += uint32(n)
+	return n, err
 }
 
 func (w *ChunkStreamerWriter) Flush() error {
@@ -27,3 +30,7 @@ func (w *ChunkStreamerWriter) Flush() error {
 	}
 	return bufw.Flush()
 }
+
+func (w *ChunkStreamerWriter) TotalWrittenBytes() uint32 {
+	return w.totalWrittenBytes
+}
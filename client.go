@@ -8,6 +8,7 @@
 package rtmp
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 
@@ -15,38 +16,56 @@ import (
 )
 
 func Dial(protocol, addr string, config *ConnConfig) (*ClientConn, error) {
-	return DialWithDialer(&net.Dialer{}, protocol, addr, config)
+	return DialContext(context.Background(), protocol, addr, config)
+}
+
+// DialContext connects to the address and does a handshake. ctx is used until the handshake is completed.
+func DialContext(ctx context.Context, protocol, addr string, config *ConnConfig) (*ClientConn, error) {
+	return DialWithDialerContext(ctx, &net.Dialer{}, protocol, addr, config)
 }
 
 func TLSDial(protocol, addr string, config *ConnConfig, tlsConfig *tls.Config) (*ClientConn, error) {
-	return DialWithTLSDialer(&tls.Dialer{
+	return TLSDialContext(context.Background(), protocol, addr, config, tlsConfig)
+}
+
+// TLSDialContext connects to the address over TLS and does a handshake. ctx is used until the handshake is completed.
+func TLSDialContext(ctx context.Context, protocol, addr string, config *ConnConfig, tlsConfig *tls.Config) (*ClientConn, error) {
+	return DialWithTLSDialerContext(ctx, &tls.Dialer{
 		NetDialer: &net.Dialer{},
 		Config:    tlsConfig,
 	}, protocol, addr, config)
 }
 
 func DialWithDialer(dialer *net.Dialer, protocol, addr string, config *ConnConfig) (*ClientConn, error) {
+	return DialWithDialerContext(context.Background(), dialer, protocol, addr, config)
+}
+
+func DialWithDialerContext(ctx context.Context, dialer *net.Dialer, protocol, addr string, config *ConnConfig) (*ClientConn, error) {
 	if protocol != "rtmp" {
 		return nil, errors.Errorf("Unknown protocol: %s", protocol)
 	}
 
-	rwc, err := dialer.Dial("tcp", addr)
+	rwc, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return nil, err
 	}
 
-	return newClientConnWithSetup(rwc, config)
+	return newClientConnWithSetup(ctx, rwc, config)
 }
 
 func DialWithTLSDialer(dialer *tls.Dialer, protocol, addr string, config *ConnConfig) (*ClientConn, error) {
+	return DialWithTLSDialerContext(context.Background(), dialer, protocol, addr, config)
+}
+
+func DialWithTLSDialerContext(ctx context.Context, dialer *tls.Dialer, protocol, addr string, config *ConnConfig) (*ClientConn, error) {
 	if protocol != "rtmps" {
 		return nil, errors.Errorf("Unknown protocol: %s", protocol)
 	}
 
-	rwc, err := dialer.Dial("tcp", addr)
+	rwc, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return nil, err
 	}
 
-	return newClientConnWithSetup(rwc, config)
+	return newClientConnWithSetup(ctx, rwc, config)
 }
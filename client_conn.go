@@ -8,6 +8,7 @@
 package rtmp
 
 import (
+	"context"
 	"net"
 	"sync"
 
@@ -24,15 +25,18 @@ type ClientConn struct {
 	m       sync.RWMutex
 }
 
-func newClientConnWithSetup(c net.Conn, config *ConnConfig) (*ClientConn, error) {
+func newClientConnWithSetup(ctx context.Context, c net.Conn, config *ConnConfig) (*ClientConn, error) {
 	conn := newConn(c, config)
 
-	if err := handshake.HandshakeWithServer(conn.rwc, conn.rwc, &handshake.Config{
-		SkipHandshakeVerification: conn.config.SkipHandshakeVerification,
-	}); err != nil {
+	hsCtx, cancel := conn.handshakeContext(ctx)
+	defer cancel()
+
+	result, err := handshake.HandshakeWithServerContext(hsCtx, conn.rwc, conn.rwc, conn.handshakeConfig())
+	if err != nil {
 		_ = conn.Close()
 		return nil, errors.Wrap(err, "Failed to handshake")
 	}
+	conn.setHandshakeResult(result)
 
 	ctrlStream, err := conn.streams.Create(ControlStreamID)
 	if err != nil {
@@ -63,6 +67,11 @@ func (cc *ClientConn) LastError() error {
 }
 
 func (cc *ClientConn) Connect(body *message.NetConnectionConnect) error {
+	return cc.ConnectContext(context.Background(), body)
+}
+
+// ConnectContext Connects to the server. TransactionTimeoutError is returned if ctx is done before the result arrives.
+func (cc *ClientConn) ConnectContext(ctx context.Context, body *message.NetConnectionConnect) error {
 	if err := cc.controllable(); err != nil {
 		return err
 	}
@@ -72,18 +81,33 @@ func (cc *ClientConn) Connect(body *message.NetConnectionConnect) error {
 		return err
 	}
 
-	result, err := stream.Connect(body)
+	result, err := stream.ConnectContext(ctx, body)
 	if err != nil {
 		return err // TODO: wrap an error
 	}
 
-	// TODO: check result
-	_ = result
+	// Use AMF3 only if the server accepted it
+	if body != nil &&
+		body.Command.ObjectEncoding == message.EncodingTypeAMF3 &&
+		result.Information.ObjectEncoding == message.EncodingTypeAMF3 {
+		cc.conn.streams.SetEncodingType(message.EncodingTypeAMF3)
+	}
+
+	stream.handler.ChangeState(streamStateClientConnected)
 
 	return nil
 }
 
 func (cc *ClientConn) CreateStream(body *message.NetConnectionCreateStream, chunkSize uint32) (*Stream, error) {
+	return cc.CreateStreamContext(context.Background(), body, chunkSize)
+}
+
+// CreateStreamContext Creates a stream. TransactionTimeoutError is returned if ctx is done before the result arrives.
+func (cc *ClientConn) CreateStreamContext(
+	ctx context.Context,
+	body *message.NetConnectionCreateStream,
+	chunkSize uint32,
+) (*Stream, error) {
 	if err := cc.controllable(); err != nil {
 		return nil, err
 	}
@@ -93,7 +117,7 @@ func (cc *ClientConn) CreateStream(body *message.NetConnectionCreateStream, chun
 		return nil, err
 	}
 
-	result, err := stream.CreateStream(body, chunkSize)
+	result, err := stream.CreateStreamContext(ctx, body, chunkSize)
 	if err != nil {
 		return nil, err // TODO: wrap an error
 	}
@@ -104,11 +128,16 @@ func (cc *ClientConn) CreateStream(body *message.NetConnectionCreateStream, chun
 	if err != nil {
 		return nil, err
 	}
+	newStream.handler.ChangeState(streamStateClientInactive)
 
 	return newStream, nil
 }
 
 func (cc *ClientConn) DeleteStream(body *message.NetStreamDeleteStream) error {
+	return cc.DeleteStreamContext(context.Background(), body)
+}
+
+func (cc *ClientConn) DeleteStreamContext(ctx context.Context, body *message.NetStreamDeleteStream) error {
 	if err := cc.controllable(); err != nil {
 		return err
 	}
@@ -123,7 +152,7 @@ func (cc *ClientConn) DeleteStream(body *message.NetStreamDeleteStream) error {
 		return err
 	}
 
-	if err := ctrlStream.DeleteStream(body); err != nil {
+	if err := ctrlStream.DeleteStreamContext(ctx, body); err != nil {
 		return err
 	}
 
@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"github.com/yutopp/go-rtmp/internal"
+	"github.com/yutopp/go-rtmp/message"
+)
+
+var _ stateHandler = (*clientControlConnectedHandler)(nil)
+
+// clientControlConnectedHandler Handle control messages and NetConnection statuses from a server after connected.
+//
+//	transitions:
+//	  | _ -> self
+type clientControlConnectedHandler struct {
+	sh *streamHandler
+}
+
+func (h *clientControlConnectedHandler) onMessage(
+	chunkStreamID int,
+	timestamp uint32,
+	msg message.Message,
+) error {
+	switch msg := msg.(type) {
+	case *message.SharedObjectMessage:
+		if soh, ok := h.sh.stream.userHandler().(SharedObjectHandler); ok {
+			return soh.OnSharedObject(timestamp, msg)
+		}
+		return internal.ErrPassThroughMsg
+
+	case *message.UserCtrl:
+		return h.onUserCtrl(timestamp, msg)
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
+
+func (h *clientControlConnectedHandler) onData(
+	chunkStreamID int,
+	timestamp uint32,
+	dataMsg *message.DataMessage,
+	body interface{},
+) error {
+	return internal.ErrPassThroughMsg
+}
+
+func (h *clientControlConnectedHandler) onCommand(
+	chunkStreamID int,
+	timestamp uint32,
+	cmdMsg *message.CommandMessage,
+	body interface{},
+) error {
+	switch cmd := body.(type) {
+	case *message.NetConnectionOnStatus:
+		if cmd.InfoObject.Code != message.NetConnectionConnectCodeReconnectRequest {
+			return internal.ErrPassThroughMsg
+		}
+
+		h.sh.stream.conn.handleReconnectRequest(&ReconnectRequest{
+			TCURL:       cmd.InfoObject.TCURL,
+			Description: cmd.InfoObject.Description,
+		})
+		return nil
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
+
+// onUserCtrl Notifies events of streams to handlers of the target streams.
+func (h *clientControlConnectedHandler) onUserCtrl(timestamp uint32, msg *message.UserCtrl) error {
+	l := h.sh.Logger()
+
+	var streamID uint32
+	var notify func(handler PlayHandler) error
+	switch event := msg.Event.(type) {
+	case *message.UserCtrlEventStreamBegin:
+		streamID = event.StreamID
+		notify = func(handler PlayHandler) error {
+			return handler.OnStreamBegin(timestamp)
+		}
+
+	case *message.UserCtrlEventStreamEOF:
+		streamID = event.StreamID
+		notify = func(handler PlayHandler) error {
+			return handler.OnStreamEOF(timestamp)
+		}
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+
+	if streamID == ControlStreamID {
+		return nil // Sent after connected. Nothing to do
+	}
+
+	stream, err := h.sh.stream.streams().At(streamID)
+	if err != nil {
+		l.Warnf("Ignored an event for the unknown stream: Event = %#v, Err = %+v", msg.Event, err)
+		return nil
+	}
+
+	return notify(stream.playHandler())
+}
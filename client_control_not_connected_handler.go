@@ -28,7 +28,16 @@ func (h *clientControlNotConnectedHandler) onMessage(
 	timestamp uint32,
 	msg message.Message,
 ) error {
-	return internal.ErrPassThroughMsg
+	switch msg := msg.(type) {
+	case *message.SharedObjectMessage:
+		if soh, ok := h.sh.stream.userHandler().(SharedObjectHandler); ok {
+			return soh.OnSharedObject(timestamp, msg)
+		}
+		return internal.ErrPassThroughMsg
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
 }
 
 func (h *clientControlNotConnectedHandler) onData(
@@ -0,0 +1,69 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"github.com/yutopp/go-rtmp/internal"
+	"github.com/yutopp/go-rtmp/message"
+)
+
+var _ stateHandler = (*clientDataInactiveHandler)(nil)
+
+// clientDataInactiveHandler Handle data messages from a server to a non operated stream at client side.
+//
+//	transitions:
+//	  | "onStatus" (NetStream.Publish.Start) -> clientDataPublishHandler
+//	  | "onStatus" (NetStream.Play.Start)    -> clientDataPlayHandler
+//	  | _                                    -> self
+type clientDataInactiveHandler struct {
+	sh *streamHandler
+}
+
+func (h *clientDataInactiveHandler) onMessage(
+	chunkStreamID int,
+	timestamp uint32,
+	msg message.Message,
+) error {
+	return internal.ErrPassThroughMsg
+}
+
+func (h *clientDataInactiveHandler) onData(
+	chunkStreamID int,
+	timestamp uint32,
+	dataMsg *message.DataMessage,
+	body interface{},
+) error {
+	return internal.ErrPassThroughMsg
+}
+
+func (h *clientDataInactiveHandler) onCommand(
+	chunkStreamID int,
+	timestamp uint32,
+	cmdMsg *message.CommandMessage,
+	body interface{},
+) error {
+	l := h.sh.Logger()
+
+	switch cmd := body.(type) {
+	case *message.NetStreamOnStatus:
+		l.Infof("Status: Info = %+v", cmd.InfoObject)
+
+		switch cmd.InfoObject.Code {
+		case message.NetStreamOnStatusCodePublishStart:
+			h.sh.ChangeState(streamStateClientPublish)
+		case message.NetStreamOnStatusCodePlayStart:
+			h.sh.ChangeState(streamStateClientPlay)
+		}
+		h.sh.stream.handleStatus(timestamp, cmd)
+
+		return nil
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
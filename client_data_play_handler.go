@@ -0,0 +1,104 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/yutopp/go-rtmp/internal"
+	"github.com/yutopp/go-rtmp/message"
+)
+
+var _ stateHandler = (*clientDataPlayHandler)(nil)
+
+// clientDataPlayHandler Handle media messages from a server at client side.
+//
+//	transitions:
+//	  | "onStatus" (NetStream.Play.Stop, NetStream.Play.Failed) -> clientDataInactiveHandler
+//	  | _                                                       -> self
+type clientDataPlayHandler struct {
+	sh *streamHandler
+}
+
+func (h *clientDataPlayHandler) onMessage(
+	chunkStreamID int,
+	timestamp uint32,
+	msg message.Message,
+) error {
+	switch msg := msg.(type) {
+	case *message.AudioMessage:
+		return h.sh.stream.playHandler().OnAudio(timestamp, msg.Payload)
+
+	case *message.VideoMessage:
+		return h.sh.stream.playHandler().OnVideo(timestamp, msg.Payload)
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
+
+func (h *clientDataPlayHandler) onData(
+	chunkStreamID int,
+	timestamp uint32,
+	dataMsg *message.DataMessage,
+	body interface{},
+) error {
+	switch data := body.(type) {
+	case *message.NetStreamOnMetaData:
+		return h.sh.stream.playHandler().OnMetaData(timestamp, data)
+
+	case *message.NetStreamSetDataFrame:
+		// Some servers relay metadata from publishers as it is, e.g. "@setDataFrame", "onMetaData", {...}
+		amfDec := message.NewAMFDecoder(bytes.NewReader(data.Payload), dataMsg.Encoding)
+
+		var name string
+		if err := amfDec.Decode(&name); err != nil {
+			return errors.Wrap(err, "Failed to decode a name of '@setDataFrame'")
+		}
+		if name != "onMetaData" {
+			return internal.ErrPassThroughMsg
+		}
+
+		var value message.AMFConvertible
+		if err := message.DecodeBodyOnMetaData(nil, amfDec, &value); err != nil {
+			return err
+		}
+
+		return h.sh.stream.playHandler().OnMetaData(timestamp, value.(*message.NetStreamOnMetaData))
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
+
+func (h *clientDataPlayHandler) onCommand(
+	chunkStreamID int,
+	timestamp uint32,
+	cmdMsg *message.CommandMessage,
+	body interface{},
+) error {
+	l := h.sh.Logger()
+
+	switch cmd := body.(type) {
+	case *message.NetStreamOnStatus:
+		l.Infof("Status: Info = %+v", cmd.InfoObject)
+
+		switch cmd.InfoObject.Code {
+		case message.NetStreamOnStatusCodePlayStop, message.NetStreamOnStatusCodePlayFailed:
+			h.sh.ChangeState(streamStateClientInactive)
+		}
+		h.sh.stream.handleStatus(timestamp, cmd)
+
+		return nil
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
@@ -0,0 +1,66 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"github.com/yutopp/go-rtmp/internal"
+	"github.com/yutopp/go-rtmp/message"
+)
+
+var _ stateHandler = (*clientDataPublishHandler)(nil)
+
+// clientDataPublishHandler Handle messages from a server to a publishing stream at client side.
+//
+//	transitions:
+//	  | "onStatus" (NetStream.Unpublish.Success, NetStream.Publish.Failed) -> clientDataInactiveHandler
+//	  | _                                                                  -> self
+type clientDataPublishHandler struct {
+	sh *streamHandler
+}
+
+func (h *clientDataPublishHandler) onMessage(
+	chunkStreamID int,
+	timestamp uint32,
+	msg message.Message,
+) error {
+	return internal.ErrPassThroughMsg
+}
+
+func (h *clientDataPublishHandler) onData(
+	chunkStreamID int,
+	timestamp uint32,
+	dataMsg *message.DataMessage,
+	body interface{},
+) error {
+	return internal.ErrPassThroughMsg
+}
+
+func (h *clientDataPublishHandler) onCommand(
+	chunkStreamID int,
+	timestamp uint32,
+	cmdMsg *message.CommandMessage,
+	body interface{},
+) error {
+	l := h.sh.Logger()
+
+	switch cmd := body.(type) {
+	case *message.NetStreamOnStatus:
+		l.Infof("Status: Info = %+v", cmd.InfoObject)
+
+		switch cmd.InfoObject.Code {
+		case message.NetStreamOnStatusCodeUnpublishSuccess, message.NetStreamOnStatusCodePublishFailed:
+			h.sh.ChangeState(streamStateClientInactive)
+		}
+		h.sh.stream.handleStatus(timestamp, cmd)
+
+		return nil
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
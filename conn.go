@@ -10,18 +10,28 @@ package rtmp
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"io"
 	"io/ioutil"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/yutopp/go-rtmp/handshake"
 	"github.com/yutopp/go-rtmp/message"
 )
 
 type Conn struct {
+	lastTransactionID int64 // Accessed atomically, placed at first to be aligned on 32-bit platforms
+
+	lastChunkStreamID  int
+	freeChunkStreamIDs []int // Released by deleted streams
+	chunkStreamIDsM    sync.Mutex
+
 	rwc      io.ReadWriteCloser
 	bufr     *bufio.Reader
 	bufw     *bufio.Writer
@@ -29,11 +39,15 @@ type Conn struct {
 	streams  *streams
 	handler  Handler
 
+	handshakeResult handshake.Result
+
 	config *ConnConfig
 	logger logrus.FieldLogger
 
 	ignoredMessages uint32
 
+	reconnectRequestHandler ReconnectRequestHandlerFunc // Used by clients
+
 	m        sync.Mutex
 	isClosed bool
 }
@@ -41,6 +55,10 @@ type Conn struct {
 type ConnConfig struct {
 	Handler                   Handler
 	SkipHandshakeVerification bool
+	DisableComplexHandshake   bool
+	HandshakeTimeout          time.Duration // No timeout if 0
+	EnableEncryption          bool          // RTMPE
+	TLSConfig                 *tls.Config   // Optional, used by clients to dial rtmps URLs
 
 	IgnoreMessagesOnNotExistStream          bool
 	IgnoreMessagesOnNotExistStreamThreshold uint32
@@ -48,8 +66,12 @@ type ConnConfig struct {
 	ReaderBufferSize int
 	WriterBufferSize int
 
+	WriteTimeout time.Duration // A timeout of writes by methods which do not take a context, 5s if 0
+
 	ControlState StreamControlStateConfig
 
+	SharedObjects *SharedObjectRegistry // Optional, shared objects are not handled by the server if nil
+
 	Logger  logrus.FieldLogger
 	RPreset ResponsePreset
 }
@@ -69,6 +91,10 @@ func (cb *ConnConfig) normalize() *ConnConfig {
 		c.WriterBufferSize = 4 * 1024 // 4KB (Default)
 	}
 
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = 5 * time.Second
+	}
+
 	c.ControlState = *c.ControlState.normalize()
 
 	if c.Logger == nil {
@@ -88,6 +114,9 @@ func newConn(rwc io.ReadWriteCloser, config *ConnConfig) *Conn {
 	config = config.normalize()
 
 	conn := &Conn{
+		lastTransactionID: 1, // 1 is reserved for connect (7.2.1.1)
+		lastChunkStreamID: 3, // 2 is used for protocol control messages, 3 is used for commands
+
 		rwc:     rwc,
 		bufr:    bufio.NewReaderSize(rwc, config.ReaderBufferSize),
 		bufw:    bufio.NewWriterSize(rwc, config.WriterBufferSize),
@@ -109,6 +138,58 @@ func (c *Conn) GetChunkStreamer() *ChunkStreamer {
 	return c.streamer
 }
 
+// HandshakeMode returns the kind of the handshake which is negotiated with the peer.
+func (c *Conn) HandshakeMode() handshake.Mode {
+	return c.handshakeResult.Mode
+}
+
+// Encrypted returns true if RTMPE is negotiated with the peer.
+func (c *Conn) Encrypted() bool {
+	return c.handshakeResult.Encrypted
+}
+
+// PeerEpoch returns the time field of C1/S1 which is sent by the peer in the handshake.
+func (c *Conn) PeerEpoch() uint32 {
+	return c.handshakeResult.PeerEpoch
+}
+
+// PeerVersion returns the version field of C1/S1 which is sent by the peer in the handshake.
+// Encoders and players put their version here (e.g. [9 0 124 2]), or zeros if they do not.
+func (c *Conn) PeerVersion() [4]byte {
+	return c.handshakeResult.PeerVersion
+}
+
+// handshakeContext Returns a context which is canceled when HandshakeTimeout has elapsed.
+func (c *Conn) handshakeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.HandshakeTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.config.HandshakeTimeout)
+}
+
+// newWriteContext Returns a context which is canceled when WriteTimeout has elapsed.
+// It is used by methods which write messages without taking a context.
+func (c *Conn) newWriteContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.config.WriteTimeout)
+}
+
+func (c *Conn) handshakeConfig() *handshake.Config {
+	return &handshake.Config{
+		SkipHandshakeVerification: c.config.SkipHandshakeVerification,
+		DisableComplexHandshake:   c.config.DisableComplexHandshake,
+		EnableEncryption:          c.config.EnableEncryption,
+	}
+}
+
+// setHandshakeResult Stores the result and switches buffers to encrypted ones if RTMPE is negotiated.
+// ChunkStreamer holds bufr and bufw, thus they are reset instead of being replaced.
+func (c *Conn) setHandshakeResult(result *handshake.Result) {
+	c.handshakeResult = *result
+
+	c.bufr.Reset(result.NewReader(c.rwc))
+	c.bufw.Reset(result.NewWriter(c.rwc))
+}
+
 func (c *Conn) Close() error {
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -122,8 +203,13 @@ func (c *Conn) Close() error {
 		c.handler.OnClose()
 	}
 
+	if c.config.SharedObjects != nil {
+		c.config.SharedObjects.releaseAll(c)
+	}
+
 	var result error
 	if c.streamer != nil {
+		c.streamer.flushAggregators()
 		c.streamer.waitWriters()
 		if err := c.streamer.Close(); err != nil {
 			result = multierror.Append(result, err)
@@ -141,6 +227,51 @@ func (c *Conn) Write(ctx context.Context, chunkStreamID int, timestamp uint32, c
 	return c.streamer.Write(ctx, chunkStreamID, timestamp, cmsg)
 }
 
+// newTransactionID Allocates a monotonic transaction ID which is unique in the connection.
+func (c *Conn) newTransactionID() int64 {
+	return atomic.AddInt64(&c.lastTransactionID, 1)
+}
+
+// newChunkStreamID Allocates a chunk stream ID which is not used by protocol control messages and commands.
+// IDs released by deleted streams are reused first.
+func (c *Conn) newChunkStreamID() (int, error) {
+	c.chunkStreamIDsM.Lock()
+	defer c.chunkStreamIDsM.Unlock()
+
+	if n := len(c.freeChunkStreamIDs); n > 0 {
+		id := c.freeChunkStreamIDs[n-1]
+		c.freeChunkStreamIDs = c.freeChunkStreamIDs[:n-1]
+		return id, nil
+	}
+
+	if c.lastChunkStreamID >= maxChunkStreamID {
+		return 0, errors.Errorf("Chunk stream IDs are exhausted: Max = %d", maxChunkStreamID)
+	}
+	c.lastChunkStreamID++
+
+	return c.lastChunkStreamID, nil
+}
+
+// releaseChunkStreamID Removes the chunk stream after a message being written is finished, and makes the ID reusable.
+// The ID is not reused if the message is not finished until ctx is done.
+func (c *Conn) releaseChunkStreamID(ctx context.Context, chunkStreamID int) error {
+	if err := c.streamer.removeChunkWriter(ctx, chunkStreamID); err != nil {
+		return err
+	}
+
+	c.chunkStreamIDsM.Lock()
+	defer c.chunkStreamIDsM.Unlock()
+
+	c.freeChunkStreamIDs = append(c.freeChunkStreamIDs, chunkStreamID)
+
+	return nil
+}
+
+// AbortWrite Cancels a message which is being written to the chunk stream.
+func (c *Conn) AbortWrite(ctx context.Context, chunkStreamID int) error {
+	return c.streamer.AbortWrite(ctx, chunkStreamID)
+}
+
 func (c *Conn) handleMessageLoop() (err error) {
 	defer func() {
 		if r := recover(); r != nil {
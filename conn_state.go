@@ -22,6 +22,7 @@ type StreamControlState struct {
 	ackWindowSize       int32
 	bandwidthWindowSize int32
 	bandwidthLimitType  message.LimitType
+	bandwidthLimitSet   bool // True after a Hard or Soft limit is received
 
 	config *StreamControlStateConfig
 }
@@ -33,6 +34,7 @@ type StreamControlStateConfig struct {
 
 	DefaultAckWindowSize int32
 	MaxAckWindowSize     int32
+	AckPolicy            AckPolicy // Optional, WindowAckPolicy is used if nil
 
 	DefaultBandwidthWindowSize int32
 	DefaultBandwidthLimitType  message.LimitType
@@ -40,6 +42,10 @@ type StreamControlStateConfig struct {
 
 	MaxMessageSize    uint32
 	MaxMessageStreams int
+
+	// Small audio/video messages are batched into aggregate messages when they are written if AggregateMessageMaxSize > 0
+	AggregateMessageMaxSize     uint32
+	AggregateMessageMaxDuration uint32 // Milliseconds between first and last messages in an aggregate message
 }
 
 func (cb *StreamControlStateConfig) normalize() *StreamControlStateConfig {
@@ -69,6 +75,10 @@ func (cb *StreamControlStateConfig) normalize() *StreamControlStateConfig {
 		c.MaxAckWindowSize = math.MaxInt32
 	}
 
+	if c.AckPolicy == nil {
+		c.AckPolicy = defaultAckPolicy
+	}
+
 	// bandwidth
 
 	if c.DefaultBandwidthWindowSize == 0 {
@@ -89,6 +99,16 @@ func (cb *StreamControlStateConfig) normalize() *StreamControlStateConfig {
 		c.MaxMessageSize = MaxChunkSize // as same as chunk size
 	}
 
+	// aggregate
+
+	if c.AggregateMessageMaxSize > c.MaxMessageSize {
+		c.AggregateMessageMaxSize = c.MaxMessageSize
+	}
+
+	if c.AggregateMessageMaxSize > 0 && c.AggregateMessageMaxDuration == 0 {
+		c.AggregateMessageMaxDuration = 100 // 100ms
+	}
+
 	return &c
 }
 
@@ -148,3 +168,37 @@ func (s *StreamControlState) BandwidthWindowSize() int32 {
 func (s *StreamControlState) BandwidthLimitType() message.LimitType {
 	return s.bandwidthLimitType
 }
+
+// SetPeerBandwidth Updates the bandwidth window according to the limit type (5.4.5).
+func (s *StreamControlState) SetPeerBandwidth(size int32, limitType message.LimitType) error {
+	if size > s.config.MaxBandwidthWindowSize {
+		return errors.Errorf("Exceeded configured max bandwidth window size: Limit = %d, Value = %d", s.config.MaxBandwidthWindowSize, size)
+	}
+
+	switch limitType {
+	case message.LimitTypeHard:
+		s.bandwidthWindowSize = size
+		s.bandwidthLimitType = message.LimitTypeHard
+		s.bandwidthLimitSet = true
+
+	case message.LimitTypeSoft:
+		// Use the indicated window or the limit already in effect, whichever is smaller
+		if size < s.bandwidthWindowSize {
+			s.bandwidthWindowSize = size
+		}
+		s.bandwidthLimitType = message.LimitTypeSoft
+		s.bandwidthLimitSet = true
+
+	case message.LimitTypeDynamic:
+		// Treat as Hard if the previous limit type was Hard, otherwise ignore this.
+		// DefaultBandwidthLimitType is not a previous one because it is not received from the peer.
+		if s.bandwidthLimitSet && s.bandwidthLimitType == message.LimitTypeHard {
+			s.bandwidthWindowSize = size
+		}
+
+	default:
+		return errors.Errorf("Unexpected limit type: %d", limitType)
+	}
+
+	return nil
+}
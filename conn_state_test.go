@@ -0,0 +1,79 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+func TestStreamControlStateSetPeerBandwidth(t *testing.T) {
+	type request struct {
+		size      int32
+		limitType message.LimitType
+	}
+
+	testCases := []struct {
+		name              string
+		requests          []request
+		expectedSize      int32
+		expectedLimitType message.LimitType
+	}{
+		{
+			name:              "Hard",
+			requests:          []request{{1000, message.LimitTypeHard}, {2000, message.LimitTypeHard}},
+			expectedSize:      2000,
+			expectedLimitType: message.LimitTypeHard,
+		},
+		{
+			name:              "Soft uses the smaller one",
+			requests:          []request{{1000, message.LimitTypeHard}, {2000, message.LimitTypeSoft}},
+			expectedSize:      1000,
+			expectedLimitType: message.LimitTypeSoft,
+		},
+		{
+			name:              "Dynamic after Hard is treated as Hard",
+			requests:          []request{{1000, message.LimitTypeHard}, {2000, message.LimitTypeDynamic}},
+			expectedSize:      2000,
+			expectedLimitType: message.LimitTypeHard,
+		},
+		{
+			name:              "Dynamic after Soft is ignored",
+			requests:          []request{{1000, message.LimitTypeSoft}, {2000, message.LimitTypeDynamic}},
+			expectedSize:      1000,
+			expectedLimitType: message.LimitTypeSoft,
+		},
+		{
+			name:              "Dynamic before Hard is ignored",
+			requests:          []request{{2000, message.LimitTypeDynamic}},
+			expectedSize:      math.MaxInt32,
+			expectedLimitType: message.LimitTypeHard,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewStreamControlState(nil)
+			for _, req := range tc.requests {
+				err := s.SetPeerBandwidth(req.size, req.limitType)
+				require.Nil(t, err)
+			}
+
+			require.Equal(t, tc.expectedSize, s.BandwidthWindowSize())
+			require.Equal(t, tc.expectedLimitType, s.BandwidthLimitType())
+		})
+	}
+}
@@ -8,5 +8,6 @@
 package rtmp
 
 type StreamContext struct {
-	StreamID uint32
+	StreamID    uint32
+	PlaySession *PlaySession // Only set for OnPlay and commands while playing, used to push media to the player
 }
@@ -8,6 +8,7 @@
 package rtmp
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -42,3 +43,103 @@ func (err *CreateStreamRejectedError) Error() string {
 		err.Result,
 	)
 }
+
+type PublishRejectedError struct {
+	Result *message.NetStreamOnStatus
+}
+
+func (err *PublishRejectedError) Error() string {
+	return fmt.Sprintf(
+		"Publish is rejected: Result = %#v",
+		err.Result,
+	)
+}
+
+type PlayRejectedError struct {
+	Result *message.NetStreamOnStatus
+}
+
+func (err *PlayRejectedError) Error() string {
+	return fmt.Sprintf(
+		"Play is rejected: Result = %#v",
+		err.Result,
+	)
+}
+
+type PauseRejectedError struct {
+	Result *message.NetStreamOnStatus
+}
+
+func (err *PauseRejectedError) Error() string {
+	return fmt.Sprintf(
+		"Pause is rejected: Result = %#v",
+		err.Result,
+	)
+}
+
+type SeekRejectedError struct {
+	Result *message.NetStreamOnStatus
+}
+
+func (err *SeekRejectedError) Error() string {
+	return fmt.Sprintf(
+		"Seek is rejected: Result = %#v",
+		err.Result,
+	)
+}
+
+type CallRejectedError struct {
+	CommandName   string
+	TransactionID int64
+	Result        []interface{}
+}
+
+func (err *CallRejectedError) Error() string {
+	return fmt.Sprintf(
+		"Call is rejected: CommandName = %s, TransactionID = %d, Result = %#v",
+		err.CommandName,
+		err.TransactionID,
+		err.Result,
+	)
+}
+
+// TransactionTimeoutError An error which is returned when a context is done before a response of a command arrives.
+type TransactionTimeoutError struct {
+	CommandName   string
+	TransactionID int64
+	Err           error // context.DeadlineExceeded or context.Canceled
+}
+
+func (err *TransactionTimeoutError) Error() string {
+	return fmt.Sprintf(
+		"Response is not received: CommandName = %s, TransactionID = %d, Err = %+v",
+		err.CommandName,
+		err.TransactionID,
+		err.Err,
+	)
+}
+
+// Timeout Returns true if the deadline has been exceeded, false if it has been canceled.
+func (err *TransactionTimeoutError) Timeout() bool {
+	return err.Err == context.DeadlineExceeded
+}
+
+func (err *TransactionTimeoutError) Cause() error {
+	return err.Err
+}
+
+func (err *TransactionTimeoutError) Unwrap() error {
+	return err.Err
+}
+
+// ReconnectRequestedError An error which represents that a server requested to reconnect.
+type ReconnectRequestedError struct {
+	Request *ReconnectRequest
+}
+
+func (err *ReconnectRequestedError) Error() string {
+	return fmt.Sprintf(
+		"Reconnect is requested: Request = %#v",
+		err.Request,
+	)
+}
@@ -6,6 +6,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/yutopp/go-rtmp"
+	"github.com/yutopp/go-rtmp/relay"
 )
 
 func main() {
@@ -19,7 +20,9 @@ func main() {
 		log.Panicf("Failed: %+v", err)
 	}
 
-	relayService := NewRelayService()
+	broker := relay.NewBroker(&relay.BrokerConfig{
+		TakeoverPolicy: relay.TakeoverPolicyReplace,
+	})
 
 	srv := rtmp.NewServer(&rtmp.ServerConfig{
 		OnConnect: func(conn net.Conn) (io.ReadWriteCloser, *rtmp.ConnConfig) {
@@ -27,7 +30,7 @@ func main() {
 			//l.SetLevel(logrus.DebugLevel)
 
 			h := &Handler{
-				relayService: relayService,
+				Handler: relay.NewHandler(broker),
 			}
 
 			return conn, &rtmp.ConnConfig{
@@ -31,3 +31,27 @@ type Handler interface {
 	OnUnknownDataMessage(timestamp uint32, data *message.DataMessage) error
 	OnClose()
 }
+
+// MultitrackHandler An optional interface of Handler. If a Handler implements it, each track of multitrack audio/video
+// (Enhanced RTMP) is passed to it instead of OnAudio/OnVideo. Other media are passed to OnAudio/OnVideo as before.
+// header is the header of the message whose FourCC is replaced with the one of the track.
+type MultitrackHandler interface {
+	OnAudioTrack(timestamp uint32, trackID uint8, header *message.AudioTagHeader, payload io.Reader) error
+	OnVideoTrack(timestamp uint32, trackID uint8, header *message.VideoTagHeader, payload io.Reader) error
+}
+
+// SharedObjectHandler An optional interface of Handler. If a Handler implements it, shared object messages are passed to it.
+// On servers, messages are passed to it before they are handled by ConnConfig.SharedObjects.
+type SharedObjectHandler interface {
+	OnSharedObject(timestamp uint32, msg *message.SharedObjectMessage) error
+}
+
+// PlayControlHandler An optional interface of Handler. If a Handler implements it, requests to control playing
+// (pause, seek, receiveAudio and receiveVideo) are passed to it, and rejected if it returns an error.
+// Requests are accepted if a Handler does not implement it.
+type PlayControlHandler interface {
+	OnPause(ctx *StreamContext, timestamp uint32, cmd *message.NetStreamPause) error
+	OnSeek(ctx *StreamContext, timestamp uint32, cmd *message.NetStreamSeek) error
+	OnReceiveAudio(ctx *StreamContext, timestamp uint32, cmd *message.NetStreamReceiveAudio) error
+	OnReceiveVideo(ctx *StreamContext, timestamp uint32, cmd *message.NetStreamReceiveVideo) error
+}
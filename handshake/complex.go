@@ -0,0 +1,163 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package handshake
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+)
+
+const (
+	packetSize      = 1536 // Size of C1/S1 and C2/S2
+	digestSize      = 32   // HMAC-SHA256
+	digestOffsetMod = 728  // 764 - 32 - 4
+)
+
+var genuineKeySuffix = []byte{
+	0xf0, 0xee, 0xc2, 0x4a, 0x80, 0x68, 0xbe, 0xe8,
+	0x2e, 0x00, 0xd0, 0xd1, 0x02, 0x9e, 0x7e, 0x57,
+	0x6e, 0xec, 0x5d, 0x2d, 0x29, 0x80, 0x6f, 0xab,
+	0x93, 0xb8, 0xe6, 0x36, 0xcf, 0xeb, 0x31, 0xae,
+}
+
+// genuineFMSKey The first 36 bytes are used for S1 digests, and the whole is used for S2 digests.
+var genuineFMSKey = append([]byte("Genuine Adobe Flash Media Server 001"), genuineKeySuffix...)
+
+// genuineFPKey The first 30 bytes are used for C1 digests, and the whole is used for C2 digests.
+var genuineFPKey = append([]byte("Genuine Adobe Flash Player 001"), genuineKeySuffix...)
+
+const (
+	genuineFMSKeyPartialLen = 36
+	genuineFPKeyPartialLen  = 30
+)
+
+// complexSchema A layout of C1/S1 in the complex handshake.
+type complexSchema int
+
+const (
+	complexSchema0 complexSchema = iota // time, version, key block, digest block
+	complexSchema1                      // time, version, digest block, key block
+)
+
+// digestPos Returns the position of the digest in a C1/S1 packet.
+func (s complexSchema) digestPos(p []byte) int {
+	base := 8 + 764 // schema0: digest block follows the key block
+	if s == complexSchema1 {
+		base = 8
+	}
+
+	offset := int(p[base]) + int(p[base+1]) + int(p[base+2]) + int(p[base+3])
+	return base + 4 + offset%digestOffsetMod
+}
+
+func hmacSHA256(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		_, _ = mac.Write(d) // never returns an error
+	}
+	return mac.Sum(nil)
+}
+
+// calcDigest Calculates a digest of p excluding the digest itself placed at pos.
+func calcDigest(p []byte, pos int, key []byte) []byte {
+	return hmacSHA256(key, p[:pos], p[pos+digestSize:])
+}
+
+// imprintDigest Writes a digest into a C1/S1 packet and returns it.
+func imprintDigest(p []byte, schema complexSchema, key []byte) []byte {
+	pos := schema.digestPos(p)
+	digest := calcDigest(p, pos, key)
+	copy(p[pos:], digest)
+
+	return digest
+}
+
+// findDigest Finds a valid digest in a C1/S1 packet by trying each schema.
+func findDigest(p []byte, key []byte) ([]byte, complexSchema, bool) {
+	for _, schema := range []complexSchema{complexSchema0, complexSchema1} {
+		pos := schema.digestPos(p)
+		digest := calcDigest(p, pos, key)
+		if hmac.Equal(digest, p[pos:pos+digestSize]) {
+			return digest, schema, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// imprintResponseDigest Writes a digest of C2/S2 into its last 32 bytes.
+// The key is derived from the digest of the peer's C1/S1.
+func imprintResponseDigest(p []byte, genuineKey []byte, peerDigest []byte) {
+	tmpKey := hmacSHA256(genuineKey, peerDigest)
+	copy(p[packetSize-digestSize:], hmacSHA256(tmpKey, p[:packetSize-digestSize]))
+}
+
+// verifyResponseDigest Checks a digest of C2/S2 which is sent against own C1/S1.
+func verifyResponseDigest(p []byte, genuineKey []byte, selfDigest []byte) bool {
+	tmpKey := hmacSHA256(genuineKey, selfDigest)
+	expected := hmacSHA256(tmpKey, p[:packetSize-digestSize])
+	return hmac.Equal(expected, p[packetSize-digestSize:])
+}
+
+// newComplexS1C1 Makes a C1/S1 whose digest is imprinted with the schema.
+// dhPublicKey is placed in the key block if it is not nil (RTMPE).
+func newComplexS1C1(
+	time uint32,
+	version [4]byte,
+	schema complexSchema,
+	key []byte,
+	dhPublicKey []byte,
+) (*S1C1, []byte, error) {
+	h := &S1C1{
+		Time:    time,
+		Version: version,
+	}
+	if _, err := rand.Read(h.Random[:]); err != nil {
+		return nil, nil, err
+	}
+
+	p := marshalS1C1(h)
+	if dhPublicKey != nil {
+		copy(p[schema.dhKeyPos(p):], dhPublicKey)
+	}
+	digest := imprintDigest(p, schema, key)
+	copy(h.Random[:], p[8:])
+
+	return h, digest, nil
+}
+
+// newComplexS2C2 Makes a C2/S2 which responds to the peer's C1/S1 digest.
+func newComplexS2C2(time, time2 uint32, genuineKey []byte, peerDigest []byte) (*S2C2, error) {
+	h := &S2C2{
+		Time:  time,
+		Time2: time2,
+	}
+	if _, err := rand.Read(h.Random[:]); err != nil {
+		return nil, err
+	}
+
+	p := marshalS2C2(h)
+	imprintResponseDigest(p, genuineKey, peerDigest)
+	copy(h.Random[:], p[8:])
+
+	return h, nil
+}
+
+func marshalS1C1(h *S1C1) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, packetSize))
+	_ = NewEncoder(buf).EncodeS1C1(h) // never fails on bytes.Buffer
+	return buf.Bytes()
+}
+
+func marshalS2C2(h *S2C2) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, packetSize))
+	_ = NewEncoder(buf).EncodeS2C2(h) // never fails on bytes.Buffer
+	return buf.Bytes()
+}
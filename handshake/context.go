@@ -0,0 +1,113 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package handshake
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// deadlineSetter is implemented by net.Conn.
+type deadlineSetter interface {
+	SetDeadline(t time.Time) error
+}
+
+var aLongTimeAgo = time.Unix(1, 0)
+
+// HandshakeWithClientContext is the same as HandshakeWithClientResult, but it is aborted when ctx is done.
+// If r or w implements SetDeadline (e.g. net.Conn), a deadline of ctx is applied to them and is cleared after the handshake.
+func HandshakeWithClientContext(ctx context.Context, r io.Reader, w io.Writer, config *Config) (*Result, error) {
+	return handshakeWithContext(ctx, r, w, func() (*Result, error) {
+		return HandshakeWithClientResult(r, w, config)
+	})
+}
+
+// HandshakeWithServerContext is the same as HandshakeWithServerResult, but it is aborted when ctx is done.
+// If r or w implements SetDeadline (e.g. net.Conn), a deadline of ctx is applied to them and is cleared after the handshake.
+func HandshakeWithServerContext(ctx context.Context, r io.Reader, w io.Writer, config *Config) (*Result, error) {
+	return handshakeWithContext(ctx, r, w, func() (*Result, error) {
+		return HandshakeWithServerResult(r, w, config)
+	})
+}
+
+func handshakeWithContext(
+	ctx context.Context,
+	r io.Reader,
+	w io.Writer,
+	f func() (*Result, error),
+) (*Result, error) {
+	if ctx.Done() == nil { // never canceled
+		return f()
+	}
+
+	setters := make([]deadlineSetter, 0, 2)
+	for _, v := range []interface{}{r, w} {
+		if ds, ok := v.(deadlineSetter); ok {
+			setters = append(setters, ds)
+		}
+	}
+
+	if len(setters) == 0 {
+		// Deadlines cannot be applied. Leave the handshake in background, a caller must close r and w.
+		type res struct {
+			result *Result
+			err    error
+		}
+		ch := make(chan res, 1)
+		go func() {
+			result, err := f()
+			ch <- res{result: result, err: err}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case v := <-ch:
+			return v.result, v.err
+		}
+	}
+
+	setDeadline := func(t time.Time) {
+		for _, ds := range setters {
+			_ = ds.SetDeadline(t) // TODO: error handling
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		setDeadline(deadline)
+	}
+	defer setDeadline(time.Time{}) // clear
+
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		select {
+		case <-ctx.Done():
+			setDeadline(aLongTimeAgo) // interrupt blocking I/O
+		case <-stopCh:
+		}
+	}()
+
+	result, err := f()
+	close(stopCh)
+	<-doneCh
+
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			return nil, context.DeadlineExceeded // I/O timed out slightly before ctx
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
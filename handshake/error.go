@@ -0,0 +1,21 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package handshake
+
+import (
+	"fmt"
+)
+
+// UnsupportedVersionError is returned when the peer requests an RTMP version in C0/S0 which is not supported.
+type UnsupportedVersionError struct {
+	Version S0C0
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("Unsupported RTMP version: Version = %d", e.Version)
+}
@@ -9,6 +9,7 @@ package handshake
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"crypto/rand"
 	"io"
 	"time"
@@ -32,142 +33,407 @@ type S2C2 struct {
 
 var RTMPVersion = 3
 
-var Version = [4]byte{0, 0, 0, 0} // TODO: fix
+var RTMPEVersion = 6 // Encrypted RTMP
+
+var Version = [4]byte{0, 0, 0, 0} // Used in the simple handshake
+
+// Versions which are sent in C1/S1 when the complex handshake is used.
+// A version which is not zero notifies the peer that the C1/S1 has a digest.
+var (
+	ComplexServerVersion = [4]byte{3, 5, 1, 1}
+	ComplexClientVersion = [4]byte{9, 0, 124, 2}
+)
 
 var timeNow = time.Now // For mock
 
+// Mode is a kind of handshakes.
+type Mode int
+
+const (
+	// ModeSimple is the handshake which is described in the RTMP specification. S2/C2 echo C1/S1.
+	ModeSimple Mode = iota
+	// ModeComplex is the handshake which uses HMAC-SHA256 digests in C1/S1 and C2/S2.
+	ModeComplex
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeSimple:
+		return "Simple"
+	case ModeComplex:
+		return "Complex"
+	default:
+		return "<Unknown>"
+	}
+}
+
 type Config struct {
 	SkipHandshakeVerification bool
+
+	// DisableComplexHandshake forces the simple handshake unless RTMPE is used.
+	// Server side: C1 digests are ignored. Client side: C1 is sent without a digest.
+	DisableComplexHandshake bool
+
+	// EnableEncryption enables RTMPE.
+	// Server side: C0 = 6 is accepted. Client side: C0 = 6 is sent.
+	EnableEncryption bool
+}
+
+// Result is information which is negotiated by a handshake.
+type Result struct {
+	Mode      Mode
+	Encrypted bool // RTMPE
+
+	PeerRTMPVersion S0C0    // RTMP version in C0/S0 sent by the peer
+	PeerEpoch       uint32  // Time field in C1/S1 sent by the peer
+	PeerVersion     [4]byte // Version field in C1/S1 sent by the peer. e.g. [9 0 124 2]
+
+	readStream  cipher.Stream
+	writeStream cipher.Stream
+}
+
+// NewReader returns a reader which decrypts data read from r if RTMPE is negotiated, otherwise returns r.
+func (r *Result) NewReader(rd io.Reader) io.Reader {
+	if !r.Encrypted {
+		return rd
+	}
+	return &cipher.StreamReader{S: r.readStream, R: rd}
+}
+
+// NewWriter returns a writer which encrypts data written to w if RTMPE is negotiated, otherwise returns w.
+func (r *Result) NewWriter(w io.Writer) io.Writer {
+	if !r.Encrypted {
+		return w
+	}
+	return &cipher.StreamWriter{S: r.writeStream, W: w}
 }
 
 func HandshakeWithClient(r io.Reader, w io.Writer, config *Config) error {
+	_, err := HandshakeWithClientResult(r, w, config)
+	return err
+}
+
+// HandshakeWithClientResult is the same as HandshakeWithClient, but it returns the negotiated Result.
+func HandshakeWithClientResult(r io.Reader, w io.Writer, config *Config) (*Result, error) {
 	d := NewDecoder(r)
 	e := NewEncoder(w)
 
 	// Recv C0
 	var c0 S0C0
 	if err := d.DecodeS0C0(&c0); err != nil {
-		return err
+		return nil, err
+	}
+
+	encrypted := config.EnableEncryption && c0 == S0C0(RTMPEVersion)
+	if c0 != S0C0(RTMPVersion) && !encrypted {
+		return nil, &UnsupportedVersionError{Version: c0}
+	}
+
+	// Recv C1
+	var c1 S1C1
+	if err := d.DecodeS1C1(&c1); err != nil {
+		return nil, err
+	}
+	c1Bytes := marshalS1C1(&c1)
+
+	// Use the complex handshake only if C1 has a valid digest, otherwise fallback to the simple one
+	result := &Result{
+		Mode:            ModeSimple,
+		Encrypted:       encrypted,
+		PeerRTMPVersion: c0,
+		PeerEpoch:       c1.Time,
+		PeerVersion:     c1.Version,
+	}
+	var c1Digest []byte
+	var schema complexSchema
+	if (encrypted || !config.DisableComplexHandshake) && c1.Version != [4]byte{} {
+		digest, sc, ok := findDigest(c1Bytes, genuineFPKey[:genuineFPKeyPartialLen])
+		if ok {
+			result.Mode = ModeComplex
+			c1Digest = digest
+			schema = sc
+		}
 	}
 
-	// TODO: check c0 RTMP version
+	var dh *dhKey
+	if encrypted {
+		if result.Mode != ModeComplex {
+			return nil, errors.New("RTMPE requires a digest in C1")
+		}
+
+		key, err := newDHKey()
+		if err != nil {
+			return nil, err
+		}
+		dh = key
+	}
 
 	// Send S0
-	s0 := S0C0(RTMPVersion)
+	s0 := c0 // RTMPVersion or RTMPEVersion
 	if err := e.EncodeS0C0(&s0); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Send S1
-	s1 := S1C1{
-		Time: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+	var s1 *S1C1
+	var s1Digest []byte
+	switch result.Mode {
+	case ModeComplex:
+		var dhPublicKey []byte
+		if dh != nil {
+			dhPublicKey = dh.publicKeyBytes()
+		}
+
+		s, digest, err := newComplexS1C1(
+			uint32(timeNow().UnixNano()/int64(time.Millisecond)),
+			ComplexServerVersion,
+			schema,
+			genuineFMSKey[:genuineFMSKeyPartialLen],
+			dhPublicKey,
+		)
+		if err != nil {
+			return nil, err
+		}
+		s1, s1Digest = s, digest
+
+	default:
+		s1 = &S1C1{
+			Time: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+		}
+		copy(s1.Version[:], Version[:])
+		if _, err := rand.Read(s1.Random[:]); err != nil { // Random Seq
+			return nil, err
+		}
 	}
-	copy(s1.Version[:], Version[:])
-	if _, err := rand.Read(s1.Random[:]); err != nil { // Random Seq
-		return err
-	}
-	if err := e.EncodeS1C1(&s1); err != nil {
-		return err
+	if err := e.EncodeS1C1(s1); err != nil {
+		return nil, err
 	}
 
-	// Recv C1
-	var c1 S1C1
-	if err := d.DecodeS1C1(&c1); err != nil {
-		return err
+	if dh != nil {
+		if err := setupEncryption(result, dh, schema, c1Bytes); err != nil {
+			return nil, err
+		}
 	}
 
-	// TODO: check c1 Client version. e.g. [9 0 124 2]
-
 	// Send S2
-	s2 := S2C2{
-		Time:  c1.Time,
-		Time2: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+	var s2 *S2C2
+	switch result.Mode {
+	case ModeComplex:
+		s, err := newComplexS2C2(
+			c1.Time,
+			uint32(timeNow().UnixNano()/int64(time.Millisecond)),
+			genuineFMSKey,
+			c1Digest,
+		)
+		if err != nil {
+			return nil, err
+		}
+		s2 = s
+
+	default:
+		s2 = &S2C2{
+			Time:  c1.Time,
+			Time2: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+		}
+		copy(s2.Random[:], c1.Random[:]) // echo c1 random
 	}
-	copy(s2.Random[:], c1.Random[:]) // echo c1 random
-	if err := e.EncodeS2C2(&s2); err != nil {
-		return err
+	if err := e.EncodeS2C2(s2); err != nil {
+		return nil, err
 	}
 
 	// Recv C2
 	var c2 S2C2
 	if err := d.DecodeS2C2(&c2); err != nil {
-		return err
+		return nil, err
 	}
 
 	if config.SkipHandshakeVerification {
-		return nil
+		return result, nil
 	}
 
-	// Check random echo
-	if !bytes.Equal(c2.Random[:], s1.Random[:]) {
-		return errors.New("Random echo is not matched")
+	// Check random echo. Some clients echo S1 even if the complex handshake is used
+	if bytes.Equal(c2.Random[:], s1.Random[:]) {
+		return result, nil
 	}
 
-	return nil
+	if result.Mode == ModeComplex && verifyResponseDigest(marshalS2C2(&c2), genuineFPKey, s1Digest) {
+		return result, nil
+	}
+
+	return nil, errors.New("Random echo is not matched")
 }
 
 func HandshakeWithServer(r io.Reader, w io.Writer, config *Config) error {
+	_, err := HandshakeWithServerResult(r, w, config)
+	return err
+}
+
+// HandshakeWithServerResult is the same as HandshakeWithServer, but it returns the negotiated Result.
+func HandshakeWithServerResult(r io.Reader, w io.Writer, config *Config) (*Result, error) {
 	d := NewDecoder(r)
 	e := NewEncoder(w)
 
+	var dh *dhKey
+	if config.EnableEncryption {
+		key, err := newDHKey()
+		if err != nil {
+			return nil, err
+		}
+		dh = key
+	}
+
 	// Send C0
 	c0 := S0C0(RTMPVersion)
+	if dh != nil {
+		c0 = S0C0(RTMPEVersion)
+	}
 	if err := e.EncodeS0C0(&c0); err != nil {
-		return errors.Wrap(err, "Failed to encode c0")
+		return nil, errors.Wrap(err, "Failed to encode c0")
 	}
 
 	// Send C1
-	c1 := S1C1{
-		Time: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+	var c1 *S1C1
+	var c1Digest []byte
+	if dh != nil || !config.DisableComplexHandshake {
+		var dhPublicKey []byte
+		if dh != nil {
+			dhPublicKey = dh.publicKeyBytes()
+		}
+
+		c, digest, err := newComplexS1C1(
+			uint32(timeNow().UnixNano()/int64(time.Millisecond)),
+			ComplexClientVersion,
+			complexSchema1,
+			genuineFPKey[:genuineFPKeyPartialLen],
+			dhPublicKey,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c1, c1Digest = c, digest
+	} else {
+		c1 = &S1C1{
+			Time: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+		}
+		copy(c1.Version[:], Version[:])
+		if _, err := rand.Read(c1.Random[:]); err != nil { // Random Seq
+			return nil, err
+		}
 	}
-	copy(c1.Version[:], Version[:])
-	if _, err := rand.Read(c1.Random[:]); err != nil { // Random Seq
-		return err
-	}
-	if err := e.EncodeS1C1(&c1); err != nil {
-		return errors.Wrap(err, "Failed to encode c1")
+	if err := e.EncodeS1C1(c1); err != nil {
+		return nil, errors.Wrap(err, "Failed to encode c1")
 	}
 
 	// Recv S0
 	var s0 S0C0
 	if err := d.DecodeS0C0(&s0); err != nil {
-		return errors.Wrap(err, "Failed to decode s0")
+		return nil, errors.Wrap(err, "Failed to decode s0")
 	}
 
-	// TODO: check s0 RTMP version
+	if s0 != c0 {
+		return nil, &UnsupportedVersionError{Version: s0}
+	}
 
 	// Recv S1
 	var s1 S1C1
 	if err := d.DecodeS1C1(&s1); err != nil {
-		return errors.Wrap(err, "Failed to decode s1")
+		return nil, errors.Wrap(err, "Failed to decode s1")
+	}
+	s1Bytes := marshalS1C1(&s1)
+
+	// Use the complex handshake only if S1 has a valid digest, otherwise fallback to the simple one
+	result := &Result{
+		Mode:            ModeSimple,
+		Encrypted:       dh != nil,
+		PeerRTMPVersion: s0,
+		PeerEpoch:       s1.Time,
+		PeerVersion:     s1.Version,
+	}
+	var s1Digest []byte
+	var schema complexSchema
+	if c1Digest != nil && s1.Version != [4]byte{} {
+		digest, sc, ok := findDigest(s1Bytes, genuineFMSKey[:genuineFMSKeyPartialLen])
+		if ok {
+			result.Mode = ModeComplex
+			s1Digest = digest
+			schema = sc
+		}
 	}
 
-	// TODO: check s1 Server version. e.g. [9 0 124 2]
+	if dh != nil {
+		if result.Mode != ModeComplex {
+			return nil, errors.New("RTMPE requires a digest in S1")
+		}
+
+		if err := setupEncryption(result, dh, schema, s1Bytes); err != nil {
+			return nil, err
+		}
+	}
 
 	// Recv S2
 	var s2 S2C2
 	if err := d.DecodeS2C2(&s2); err != nil {
-		return errors.Wrap(err, "Failed to decode s2")
+		return nil, errors.Wrap(err, "Failed to decode s2")
 	}
 
 	// Send C2
-	c2 := S2C2{
-		Time:  c1.Time,
-		Time2: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+	var c2 *S2C2
+	switch result.Mode {
+	case ModeComplex:
+		c, err := newComplexS2C2(
+			s1.Time,
+			uint32(timeNow().UnixNano()/int64(time.Millisecond)),
+			genuineFPKey,
+			s1Digest,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c2 = c
+
+	default:
+		c2 = &S2C2{
+			Time:  c1.Time,
+			Time2: uint32(timeNow().UnixNano() / int64(time.Millisecond)),
+		}
+		copy(c2.Random[:], s1.Random[:]) // echo s1 random
 	}
-	copy(c2.Random[:], s1.Random[:]) // echo s1 random
-	if err := e.EncodeS2C2(&c2); err != nil {
-		return errors.Wrap(err, "Failed to encode c2")
+	if err := e.EncodeS2C2(c2); err != nil {
+		return nil, errors.Wrap(err, "Failed to encode c2")
 	}
 
 	if config.SkipHandshakeVerification {
-		return nil
+		return result, nil
+	}
+
+	// Check random echo. Some servers echo C1 even if the complex handshake is used
+	if bytes.Equal(s2.Random[:], c1.Random[:]) {
+		return result, nil
 	}
 
-	// Check random echo
-	if !bytes.Equal(s2.Random[:], c1.Random[:]) {
-		return errors.New("Random echo is not matched")
+	if result.Mode == ModeComplex && verifyResponseDigest(marshalS2C2(&s2), genuineFMSKey, c1Digest) {
+		return result, nil
+	}
+
+	return nil, errors.New("Random echo is not matched")
+}
+
+// setupEncryption Derives RC4 keystreams from own DH key and the peer's C1/S1.
+func setupEncryption(result *Result, dh *dhKey, schema complexSchema, peerS1C1 []byte) error {
+	pos := schema.dhKeyPos(peerS1C1)
+	peerPublicKey := peerS1C1[pos : pos+dhKeySize]
+
+	secret, err := dh.sharedSecret(peerPublicKey)
+	if err != nil {
+		return err
+	}
+
+	rs, ws, err := newRC4Streams(secret, dh.publicKeyBytes(), peerPublicKey)
+	if err != nil {
+		return err
 	}
+	result.readStream = rs
+	result.writeStream = ws
 
 	return nil
 }
@@ -0,0 +1,218 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package handshake
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshake(t *testing.T) {
+	type testCase struct {
+		name         string
+		serverConfig *Config
+		clientConfig *Config
+		expectedMode Mode
+	}
+	testCases := []testCase{
+		{
+			name:         "complex",
+			serverConfig: &Config{},
+			clientConfig: &Config{},
+			expectedMode: ModeComplex,
+		},
+		{
+			name:         "simple (client disabled complex)",
+			serverConfig: &Config{},
+			clientConfig: &Config{DisableComplexHandshake: true},
+			expectedMode: ModeSimple,
+		},
+		{
+			name:         "simple (server disabled complex)",
+			serverConfig: &Config{DisableComplexHandshake: true},
+			clientConfig: &Config{},
+			expectedMode: ModeSimple,
+		},
+		{
+			name:         "simple (both disabled complex)",
+			serverConfig: &Config{DisableComplexHandshake: true},
+			clientConfig: &Config{DisableComplexHandshake: true},
+			expectedMode: ModeSimple,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sConn, cConn := net.Pipe()
+			defer sConn.Close()
+			defer cConn.Close()
+
+			type res struct {
+				result *Result
+				err    error
+			}
+			serverCh := make(chan res, 1)
+			go func() {
+				result, err := HandshakeWithClientResult(sConn, sConn, tc.serverConfig)
+				serverCh <- res{result: result, err: err}
+			}()
+
+			clientResult, err := HandshakeWithServerResult(cConn, cConn, tc.clientConfig)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedMode, clientResult.Mode)
+			require.Equal(t, S0C0(RTMPVersion), clientResult.PeerRTMPVersion)
+
+			sr := <-serverCh
+			require.Nil(t, sr.err)
+			require.Equal(t, tc.expectedMode, sr.result.Mode)
+			require.Equal(t, S0C0(RTMPVersion), sr.result.PeerRTMPVersion)
+
+			if tc.clientConfig.DisableComplexHandshake {
+				require.Equal(t, Version, sr.result.PeerVersion)
+			} else {
+				require.Equal(t, ComplexClientVersion, sr.result.PeerVersion)
+			}
+		})
+	}
+}
+
+func TestHandshakeRejectsUnsupportedVersion(t *testing.T) {
+	sConn, cConn := net.Pipe()
+	defer sConn.Close()
+	defer cConn.Close()
+
+	go func() {
+		c0 := S0C0(6) // RTMPE
+		_ = NewEncoder(cConn).EncodeS0C0(&c0)
+	}()
+
+	err := HandshakeWithClient(sConn, sConn, &Config{})
+	require.Equal(t, &UnsupportedVersionError{Version: 6}, err)
+}
+
+func TestComplexDigest(t *testing.T) {
+	for _, schema := range []complexSchema{complexSchema0, complexSchema1} {
+		c1, digest, err := newComplexS1C1(0, ComplexClientVersion, schema, genuineFPKey[:genuineFPKeyPartialLen], nil)
+		require.Nil(t, err)
+
+		found, foundSchema, ok := findDigest(marshalS1C1(c1), genuineFPKey[:genuineFPKeyPartialLen])
+		require.True(t, ok)
+		require.Equal(t, schema, foundSchema)
+		require.Equal(t, digest, found)
+
+		// Digests are not found by a wrong key
+		_, _, ok = findDigest(marshalS1C1(c1), genuineFMSKey[:genuineFMSKeyPartialLen])
+		require.False(t, ok)
+
+		s2, err := newComplexS2C2(0, 0, genuineFMSKey, digest)
+		require.Nil(t, err)
+		require.True(t, verifyResponseDigest(marshalS2C2(s2), genuineFMSKey, digest))
+		require.False(t, verifyResponseDigest(marshalS2C2(s2), genuineFPKey, digest))
+	}
+}
+
+func TestHandshakeContext(t *testing.T) {
+	t.Run("Deadline of net.Conn", func(t *testing.T) {
+		sConn, cConn := net.Pipe()
+		defer sConn.Close()
+		defer cConn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// The peer never sends C0
+		_, err := HandshakeWithClientContext(ctx, sConn, sConn, &Config{})
+		require.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("Cancel without deadlines", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		defer pw.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		// The peer never sends S0
+		_, err := HandshakeWithServerContext(ctx, pr, ioutil.Discard, &Config{})
+		require.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestHandshakeEncrypted(t *testing.T) {
+	sConn, cConn := net.Pipe()
+	defer sConn.Close()
+	defer cConn.Close()
+
+	type res struct {
+		result *Result
+		err    error
+	}
+	serverCh := make(chan res, 1)
+	go func() {
+		result, err := HandshakeWithClientResult(sConn, sConn, &Config{EnableEncryption: true})
+		serverCh <- res{result: result, err: err}
+	}()
+
+	clientResult, err := HandshakeWithServerResult(cConn, cConn, &Config{EnableEncryption: true})
+	require.Nil(t, err)
+	require.True(t, clientResult.Encrypted)
+	require.Equal(t, ModeComplex, clientResult.Mode)
+	require.Equal(t, S0C0(RTMPEVersion), clientResult.PeerRTMPVersion)
+
+	sr := <-serverCh
+	require.Nil(t, sr.err)
+	require.True(t, sr.result.Encrypted)
+	require.Equal(t, S0C0(RTMPEVersion), sr.result.PeerRTMPVersion)
+
+	// client -> server, and server -> client
+	for _, dir := range []struct {
+		w io.Writer
+		r io.Reader
+	}{
+		{w: clientResult.NewWriter(cConn), r: sr.result.NewReader(sConn)},
+		{w: sr.result.NewWriter(sConn), r: clientResult.NewReader(cConn)},
+	} {
+		plain := []byte("RTMPE payload")
+
+		go func(w io.Writer) {
+			_, _ = w.Write(plain)
+		}(dir.w)
+
+		actual := make([]byte, len(plain))
+		_, err := io.ReadFull(dir.r, actual)
+		require.Nil(t, err)
+		require.Equal(t, plain, actual)
+	}
+}
+
+func TestHandshakeRejectsEncryptionIfDisabled(t *testing.T) {
+	sConn, cConn := net.Pipe()
+	defer sConn.Close()
+	defer cConn.Close()
+
+	go func() {
+		_ = HandshakeWithServer(cConn, cConn, &Config{EnableEncryption: true})
+	}()
+
+	err := HandshakeWithClient(sConn, sConn, &Config{})
+	require.Equal(t, &UnsupportedVersionError{Version: S0C0(RTMPEVersion)}, err)
+}
@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package handshake
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rc4"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dhKeySize      = 128 // 1024 bits
+	dhKeyOffsetMod = 632 // 764 - 128 - 4
+	rc4KeySize     = 16
+)
+
+// dhPrime 1024-bit MODP Group (RFC 2409, 6.2)
+var dhPrime, _ = new(big.Int).SetString(
+	"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
+		"29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245"+
+		"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE65381"+
+		"FFFFFFFFFFFFFFFF",
+	16,
+)
+
+var dhGenerator = big.NewInt(2)
+
+// dhKeyPos Returns the position of the Diffie-Hellman public key in a C1/S1 packet.
+// The key is placed in the key block, and its offset is stored at the last 4 bytes of the block.
+func (s complexSchema) dhKeyPos(p []byte) int {
+	base := 8 // schema0: the key block is the first one
+	if s == complexSchema1 {
+		base = 8 + 764
+	}
+
+	o := base + 764 - 4
+	offset := int(p[o]) + int(p[o+1]) + int(p[o+2]) + int(p[o+3])
+	return base + offset%dhKeyOffsetMod
+}
+
+type dhKey struct {
+	private *big.Int
+	public  *big.Int
+}
+
+func newDHKey() (*dhKey, error) {
+	max := new(big.Int).Sub(dhPrime, big.NewInt(2))
+	priv, err := rand.Int(rand.Reader, max) // [0, p-2)
+	if err != nil {
+		return nil, err
+	}
+	priv.Add(priv, big.NewInt(1)) // [1, p-1)
+
+	return &dhKey{
+		private: priv,
+		public:  new(big.Int).Exp(dhGenerator, priv, dhPrime),
+	}, nil
+}
+
+func (k *dhKey) publicKeyBytes() []byte {
+	buf := make([]byte, dhKeySize)
+	return k.public.FillBytes(buf)
+}
+
+func (k *dhKey) sharedSecret(peerPublicKey []byte) ([]byte, error) {
+	peer := new(big.Int).SetBytes(peerPublicKey)
+
+	// Reject keys which reveal the secret: 0, 1 and p-1 or greater
+	if peer.Cmp(big.NewInt(1)) <= 0 || peer.Cmp(new(big.Int).Sub(dhPrime, big.NewInt(1))) >= 0 {
+		return nil, errors.New("Invalid Diffie-Hellman public key of the peer")
+	}
+
+	secret := new(big.Int).Exp(peer, k.private, dhPrime)
+
+	buf := make([]byte, dhKeySize)
+	return secret.FillBytes(buf), nil
+}
+
+// newRC4Streams Derives keystreams to decrypt received data (r) and encrypt sent data (w).
+func newRC4Streams(secret, selfPublicKey, peerPublicKey []byte) (r cipher.Stream, w cipher.Stream, err error) {
+	wc, err := rc4.NewCipher(hmacSHA256(secret, peerPublicKey)[:rc4KeySize])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc, err := rc4.NewCipher(hmacSHA256(secret, selfPublicKey)[:rc4KeySize])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Both keystreams are advanced by the size of a handshake packet before use
+	buf := make([]byte, packetSize)
+	rc.XORKeyStream(buf, buf)
+	wc.XORKeyStream(buf, buf)
+
+	return rc, wc, nil
+}
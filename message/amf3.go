@@ -0,0 +1,112 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+
+	"github.com/yutopp/go-amf0"
+
+	"github.com/yutopp/go-rtmp/amf3"
+)
+
+// avmplusObjectMarker A marker of AMF0 which switches the encoding of the following value to AMF3
+const avmplusObjectMarker = 0x11
+
+// amf3Decoder Decodes values of messages negotiated as AMF3 (objectEncoding = 3).
+// These values are AMF0 values, and a value which follows the avmplus-object-marker is an AMF3 value.
+type amf3Decoder struct {
+	r      io.Reader
+	amf0   *amf0.Decoder
+	amf3   *amf3.Decoder
+	marker [1]byte
+}
+
+func newAMF3Decoder(r io.Reader) *amf3Decoder {
+	return &amf3Decoder{
+		r:    r,
+		amf0: amf0.NewDecoder(r),
+		amf3: amf3.NewDecoder(r),
+	}
+}
+
+func (d *amf3Decoder) Decode(v interface{}) error {
+	if _, err := io.ReadFull(d.r, d.marker[:]); err != nil {
+		return err
+	}
+
+	if d.marker[0] == avmplusObjectMarker {
+		d.amf3.Reset(d.r) // Each AMF3 value has own reference tables
+		return d.amf3.Decode(v)
+	}
+
+	// Give back the marker to the AMF0 decoder
+	d.amf0.Reset(io.MultiReader(bytes.NewReader(d.marker[:]), d.r))
+	return d.amf0.Decode(v)
+}
+
+func (d *amf3Decoder) Reset(r io.Reader) {
+	d.r = r
+}
+
+// amf3Encoder Encodes values of messages negotiated as AMF3 (objectEncoding = 3).
+// Primitive values are encoded as AMF0 values for compatibility, and others are encoded as AMF3 values
+// following the avmplus-object-marker.
+type amf3Encoder struct {
+	w    io.Writer
+	amf0 *amf0.Encoder
+	amf3 *amf3.Encoder
+}
+
+func newAMF3Encoder(w io.Writer) *amf3Encoder {
+	return &amf3Encoder{
+		w:    w,
+		amf0: amf0.NewEncoder(w),
+		amf3: amf3.NewEncoder(w),
+	}
+}
+
+func (e *amf3Encoder) Encode(v interface{}) error {
+	if isAMF0Primitive(v) {
+		e.amf0.Reset(e.w)
+		return e.amf0.Encode(v)
+	}
+
+	if _, err := e.w.Write([]byte{avmplusObjectMarker}); err != nil {
+		return err
+	}
+
+	e.amf3.Reset(e.w) // Each AMF3 value has own reference tables
+	return e.amf3.Encode(v)
+}
+
+func (e *amf3Encoder) Reset(w io.Writer) {
+	e.w = w
+}
+
+func isAMF0Primitive(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+
+	default:
+		return false
+	}
+}
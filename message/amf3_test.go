@@ -0,0 +1,83 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+func TestAMF3DecoderSwitchesEncoding(t *testing.T) {
+	bin := []byte{
+		// AMF0 / string: abc
+		0x02, 0x00, 0x03, 0x61, 0x62, 0x63,
+		// avmplus-object-marker, AMF3 / anonymous object {a: 1}
+		0x11, 0x0a, 0x0b, 0x01, 0x03, 0x61, 0x04, 0x01, 0x01,
+		// AMF0 / null
+		0x05,
+	}
+	d := NewAMFDecoder(bytes.NewReader(bin), EncodingTypeAMF3)
+
+	var s string
+	require.Nil(t, d.Decode(&s))
+	require.Equal(t, "abc", s)
+
+	var obj map[string]interface{}
+	require.Nil(t, d.Decode(&obj))
+	require.Equal(t, map[string]interface{}{"a": int32(1)}, obj)
+
+	var null interface{}
+	require.Nil(t, d.Decode(&null))
+	require.Nil(t, null)
+}
+
+func TestAMF3EncoderSwitchesEncoding(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewAMFEncoder(buf, EncodingTypeAMF3)
+
+	require.Nil(t, e.Encode("abc"))
+	require.Nil(t, e.Encode(map[string]interface{}{"a": 1}))
+	require.Nil(t, e.Encode(nil))
+
+	require.Equal(t, []byte{
+		0x02, 0x00, 0x03, 0x61, 0x62, 0x63,
+		0x11, 0x0a, 0x0b, 0x01, 0x03, 0x61, 0x04, 0x01, 0x01,
+		0x05,
+	}, buf.Bytes())
+}
+
+func TestAMF3ConnectResultRoundTrip(t *testing.T) {
+	result := &NetConnectionConnectResult{
+		Properties: NetConnectionConnectResultProperties{
+			FMSVer:       "GO-RTMP/0,0,0,0",
+			Capabilities: 31,
+			Mode:         1,
+		},
+		Information: NetConnectionConnectResultInformation{
+			Level:       "status",
+			Code:        NetConnectionConnectCodeSuccess,
+			Description: "Connection succeeded.",
+			Data: amf0.ECMAArray{
+				"version": "3,5,3,888",
+			},
+			ObjectEncoding: EncodingTypeAMF3,
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	err := EncodeBodyAnyValues(NewAMFEncoder(buf, EncodingTypeAMF3), result)
+	require.Nil(t, err)
+
+	var v AMFConvertible
+	err = DecodeBodyConnectResult(buf, NewAMFDecoder(buf, EncodingTypeAMF3), &v)
+	require.Nil(t, err)
+	require.Equal(t, result, v)
+}
@@ -33,7 +33,7 @@ type AMFDecoder interface {
 func NewAMFDecoder(r io.Reader, encTy EncodingType) AMFDecoder {
 	switch encTy {
 	case EncodingTypeAMF3:
-		panic("Unsupported encoding: AMF3")
+		return newAMF3Decoder(r)
 	case EncodingTypeAMF0:
 		return amf0.NewDecoder(r)
 	default:
@@ -49,7 +49,7 @@ type AMFEncoder interface {
 func NewAMFEncoder(w io.Writer, encTy EncodingType) AMFEncoder {
 	switch encTy {
 	case EncodingTypeAMF3:
-		panic("Unsupported encoding: AMF3")
+		return newAMF3Encoder(w)
 	case EncodingTypeAMF0:
 		return amf0.NewEncoder(w)
 	default:
@@ -0,0 +1,182 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// SoundFormat A legacy codec ID of FLV audio tags.
+type SoundFormat uint8
+
+const (
+	SoundFormatLinearPCMPlatformEndian SoundFormat = 0
+	SoundFormatADPCM                   SoundFormat = 1
+	SoundFormatMP3                     SoundFormat = 2
+	SoundFormatLinearPCMLittleEndian   SoundFormat = 3
+	SoundFormatNellymoser16kHzMono     SoundFormat = 4
+	SoundFormatNellymoser8kHzMono      SoundFormat = 5
+	SoundFormatNellymoser              SoundFormat = 6
+	SoundFormatG711ALaw                SoundFormat = 7
+	SoundFormatG711MuLaw               SoundFormat = 8
+	SoundFormatExHeader                SoundFormat = 9 // Enhanced RTMP
+	SoundFormatAAC                     SoundFormat = 10
+	SoundFormatSpeex                   SoundFormat = 11
+	SoundFormatMP38kHz                 SoundFormat = 14
+	SoundFormatDeviceSpecific          SoundFormat = 15
+)
+
+type AACPacketType uint8
+
+const (
+	AACPacketTypeSequenceHeader AACPacketType = 0
+	AACPacketTypeRaw            AACPacketType = 1
+)
+
+// AudioPacketType A packet type of ExAudioTagHeader (Enhanced RTMP).
+type AudioPacketType uint8
+
+const (
+	AudioPacketTypeSequenceStart      AudioPacketType = 0
+	AudioPacketTypeCodedFrames        AudioPacketType = 1
+	AudioPacketTypeSequenceEnd        AudioPacketType = 2
+	AudioPacketTypeMultichannelConfig AudioPacketType = 4
+	AudioPacketTypeMultitrack         AudioPacketType = 5
+)
+
+// AudioTagHeader A header of payloads of AudioMessage. It is either a legacy AudioTagHeader or an ExAudioTagHeader.
+type AudioTagHeader struct {
+	SoundFormat SoundFormat // SoundFormatExHeader for Enhanced RTMP
+
+	// Legacy
+	SoundRate     uint8
+	SoundSize     uint8
+	SoundType     uint8
+	AACPacketType AACPacketType // Only for AAC
+
+	// Enhanced RTMP
+	PacketType     AudioPacketType // A packet type of tracks if IsMultitrack
+	FourCC         FourCC          // Empty if MultitrackType is ManyTracksManyCodecs
+	IsMultitrack   bool
+	MultitrackType MultitrackType
+}
+
+func (h *AudioTagHeader) IsExHeader() bool {
+	return h.SoundFormat == SoundFormatExHeader
+}
+
+// IsSequenceHeader Returns true if the body is a decoder configuration record.
+func (h *AudioTagHeader) IsSequenceHeader() bool {
+	if h.IsExHeader() {
+		return h.PacketType == AudioPacketTypeSequenceStart
+	}
+	return h.SoundFormat == SoundFormatAAC && h.AACPacketType == AACPacketTypeSequenceHeader
+}
+
+// DecodeAudioTagHeader Reads a header from a payload of AudioMessage. Subsequent data of r is a body of the audio,
+// or tracks which can be read by DecodeMultitrackTracks if IsMultitrack.
+func DecodeAudioTagHeader(r io.Reader, h *AudioTagHeader) error {
+	buf := make([]byte, 4)
+	if _, err := io.ReadAtLeast(r, buf[:1], 1); err != nil {
+		return err
+	}
+
+	*h = AudioTagHeader{
+		SoundFormat: SoundFormat(buf[0] >> 4),
+	}
+	if !h.IsExHeader() {
+		h.SoundRate = (buf[0] >> 2) & 0x03
+		h.SoundSize = (buf[0] >> 1) & 0x01
+		h.SoundType = buf[0] & 0x01
+
+		if h.SoundFormat == SoundFormatAAC {
+			if _, err := io.ReadAtLeast(r, buf[:1], 1); err != nil {
+				return err
+			}
+			h.AACPacketType = AACPacketType(buf[0])
+		}
+
+		return nil
+	}
+
+	h.PacketType = AudioPacketType(buf[0] & 0x0f)
+	if h.PacketType == AudioPacketTypeMultitrack {
+		if _, err := io.ReadAtLeast(r, buf[:1], 1); err != nil {
+			return err
+		}
+		h.IsMultitrack = true
+		h.MultitrackType = MultitrackType(buf[0] >> 4)
+		h.PacketType = AudioPacketType(buf[0] & 0x0f)
+
+		if h.MultitrackType > MultitrackTypeManyTracksManyCodecs {
+			return errors.Errorf("Unsupported multitrack type: MultitrackType = %d", h.MultitrackType)
+		}
+		if h.PacketType == AudioPacketTypeMultitrack {
+			return errors.New("Nested multitrack is not allowed")
+		}
+	}
+	if !isSupportedAudioPacketType(h.PacketType) {
+		return errors.Errorf("Unsupported packet type of ExAudioTagHeader: PacketType = %d", h.PacketType)
+	}
+
+	if h.IsMultitrack && h.MultitrackType == MultitrackTypeManyTracksManyCodecs {
+		return nil // FourCC is in each tracks
+	}
+
+	if _, err := io.ReadAtLeast(r, buf[:4], 4); err != nil {
+		return err
+	}
+	h.FourCC = FourCC(buf[:4])
+
+	return nil
+}
+
+// EncodeAudioTagHeader Writes a header of a payload of AudioMessage. A body of the audio should be written after this.
+func EncodeAudioTagHeader(w io.Writer, h *AudioTagHeader) error {
+	buf := make([]byte, 0, 6)
+	if !h.IsExHeader() {
+		buf = append(buf, byte(h.SoundFormat)<<4|(h.SoundRate&0x03)<<2|(h.SoundSize&0x01)<<1|h.SoundType&0x01)
+		if h.SoundFormat == SoundFormatAAC {
+			buf = append(buf, byte(h.AACPacketType))
+		}
+
+		_, err := w.Write(buf)
+		return err
+	}
+
+	if h.IsMultitrack {
+		buf = append(buf, byte(SoundFormatExHeader)<<4|byte(AudioPacketTypeMultitrack))
+		buf = append(buf, byte(h.MultitrackType)<<4|byte(h.PacketType&0x0f))
+	} else {
+		buf = append(buf, byte(SoundFormatExHeader)<<4|byte(h.PacketType&0x0f))
+	}
+
+	if !h.IsMultitrack || h.MultitrackType != MultitrackTypeManyTracksManyCodecs {
+		if len(h.FourCC) != 4 {
+			return errors.Errorf("FourCC must be 4 characters: FourCC = %s", h.FourCC)
+		}
+		buf = append(buf, h.FourCC...)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func isSupportedAudioPacketType(ty AudioPacketType) bool {
+	switch ty {
+	case AudioPacketTypeSequenceStart,
+		AudioPacketTypeCodedFrames,
+		AudioPacketTypeSequenceEnd,
+		AudioPacketTypeMultichannelConfig:
+		return true
+	default:
+		return false
+	}
+}
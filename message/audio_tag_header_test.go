@@ -0,0 +1,148 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type audioTagHeaderTestCase struct {
+	Name   string
+	Header AudioTagHeader
+	Binary []byte
+}
+
+var audioTagHeaderTestCases = []audioTagHeaderTestCase{
+	{
+		Name: "AAC sequence header",
+		Header: AudioTagHeader{
+			SoundFormat:   SoundFormatAAC,
+			SoundRate:     3,
+			SoundSize:     1,
+			SoundType:     1,
+			AACPacketType: AACPacketTypeSequenceHeader,
+		},
+		Binary: []byte{0xaf, 0x00},
+	},
+	{
+		Name: "MP3",
+		Header: AudioTagHeader{
+			SoundFormat: SoundFormatMP3,
+			SoundRate:   2,
+			SoundSize:   1,
+			SoundType:   0,
+		},
+		Binary: []byte{0x2a},
+	},
+	{
+		Name: "Opus SequenceStart",
+		Header: AudioTagHeader{
+			SoundFormat: SoundFormatExHeader,
+			PacketType:  AudioPacketTypeSequenceStart,
+			FourCC:      FourCCOpus,
+		},
+		Binary: []byte{0x90, 'O', 'p', 'u', 's'},
+	},
+	{
+		Name: "FLAC CodedFrames",
+		Header: AudioTagHeader{
+			SoundFormat: SoundFormatExHeader,
+			PacketType:  AudioPacketTypeCodedFrames,
+			FourCC:      FourCCFLAC,
+		},
+		Binary: []byte{0x91, 'f', 'L', 'a', 'C'},
+	},
+	{
+		Name: "AC-3 SequenceEnd",
+		Header: AudioTagHeader{
+			SoundFormat: SoundFormatExHeader,
+			PacketType:  AudioPacketTypeSequenceEnd,
+			FourCC:      FourCCAC3,
+		},
+		Binary: []byte{0x92, 'a', 'c', '-', '3'},
+	},
+	{
+		Name: "E-AC-3 MultichannelConfig",
+		Header: AudioTagHeader{
+			SoundFormat: SoundFormatExHeader,
+			PacketType:  AudioPacketTypeMultichannelConfig,
+			FourCC:      FourCCEAC3,
+		},
+		Binary: []byte{0x94, 'e', 'c', '-', '3'},
+	},
+	{
+		Name: "Multitrack OneTrack",
+		Header: AudioTagHeader{
+			SoundFormat:    SoundFormatExHeader,
+			PacketType:     AudioPacketTypeCodedFrames,
+			FourCC:         FourCCOpus,
+			IsMultitrack:   true,
+			MultitrackType: MultitrackTypeOneTrack,
+		},
+		Binary: []byte{0x95, 0x01, 'O', 'p', 'u', 's'},
+	},
+	{
+		Name: "Multitrack ManyTracksManyCodecs",
+		Header: AudioTagHeader{
+			SoundFormat:    SoundFormatExHeader,
+			PacketType:     AudioPacketTypeCodedFrames,
+			IsMultitrack:   true,
+			MultitrackType: MultitrackTypeManyTracksManyCodecs,
+		},
+		Binary: []byte{0x95, 0x21},
+	},
+}
+
+func TestDecodeAudioTagHeader(t *testing.T) {
+	for _, tc := range audioTagHeaderTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			r := bytes.NewReader(append(tc.Binary, "body"...))
+
+			var h AudioTagHeader
+			err := DecodeAudioTagHeader(r, &h)
+			require.Nil(t, err)
+			require.Equal(t, tc.Header, h)
+
+			body, err := ioutil.ReadAll(r)
+			require.Nil(t, err)
+			require.Equal(t, []byte("body"), body)
+		})
+	}
+}
+
+func TestEncodeAudioTagHeader(t *testing.T) {
+	for _, tc := range audioTagHeaderTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			err := EncodeAudioTagHeader(buf, &tc.Header)
+			require.Nil(t, err)
+			require.Equal(t, tc.Binary, buf.Bytes())
+		})
+	}
+}
+
+func TestDecodeAudioTagHeaderWithUnsupportedPacketType(t *testing.T) {
+	var h AudioTagHeader
+	err := DecodeAudioTagHeader(bytes.NewReader([]byte{0x97, 'O', 'p', 'u', 's'}), &h)
+	require.EqualError(t, err, "Unsupported packet type of ExAudioTagHeader: PacketType = 7")
+
+	err = DecodeAudioTagHeader(bytes.NewReader([]byte{0x95, 0x05, 'O', 'p', 'u', 's'}), &h)
+	require.EqualError(t, err, "Nested multitrack is not allowed")
+}
@@ -10,6 +10,7 @@ package message
 import (
 	"bytes"
 	"io"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -17,7 +18,9 @@ import (
 type BodyDecoderFunc func(r io.Reader, e AMFDecoder, v *AMFConvertible) error
 
 var DataBodyDecoders = map[string]BodyDecoderFunc{
-	"@setDataFrame": DecodeBodyAtSetDataFrame,
+	"@setDataFrame":     DecodeBodyAtSetDataFrame,
+	"onMetaData":        DecodeBodyOnMetaData,
+	"|RtmpSampleAccess": DecodeBodyRtmpSampleAccess,
 }
 
 func DataBodyDecoderFor(name string) BodyDecoderFunc {
@@ -59,6 +62,42 @@ func DecodeBodyAtSetDataFrame(r io.Reader, _ AMFDecoder, v *AMFConvertible) erro
 	return nil
 }
 
+func DecodeBodyOnMetaData(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
+	var metaData map[string]interface{}
+	if err := d.Decode(&metaData); err != nil {
+		return errors.Wrap(err, "Failed to decode 'onMetaData' args[0]")
+	}
+
+	var data NetStreamOnMetaData
+	if err := data.FromArgs(metaData); err != nil {
+		return errors.Wrap(err, "Failed to reconstruct 'onMetaData'")
+	}
+
+	*v = &data
+
+	return nil
+}
+
+func DecodeBodyRtmpSampleAccess(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
+	var audio interface{}
+	if err := d.Decode(&audio); err != nil {
+		return errors.Wrap(err, "Failed to decode '|RtmpSampleAccess' args[0]")
+	}
+	var video interface{}
+	if err := d.Decode(&video); err != nil {
+		return errors.Wrap(err, "Failed to decode '|RtmpSampleAccess' args[1]")
+	}
+
+	var data NetStreamRtmpSampleAccess
+	if err := data.FromArgs(audio, video); err != nil {
+		return errors.Wrap(err, "Failed to reconstruct '|RtmpSampleAccess'")
+	}
+
+	*v = &data
+
+	return nil
+}
+
 var CmdBodyDecoders = map[string]BodyDecoderFunc{
 	"connect":         DecodeBodyConnect,
 	"createStream":    DecodeBodyCreateStream,
@@ -71,6 +110,11 @@ var CmdBodyDecoders = map[string]BodyDecoderFunc{
 	"getStreamLength": DecodeBodyGetStreamLength,
 	"ping":            DecodeBodyPing,
 	"closeStream":     DecodeBodyCloseStream,
+	"onStatus":        DecodeBodyOnStatus,
+	"pause":           DecodeBodyPause,
+	"seek":            DecodeBodySeek,
+	"receiveAudio":    DecodeBodyReceiveAudio,
+	"receiveVideo":    DecodeBodyReceiveVideo,
 }
 
 func CmdBodyDecoderFor(name string, transactionID int64) BodyDecoderFunc {
@@ -226,21 +270,24 @@ func DecodeBodyPlay(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
 	if err := d.Decode(&streamName); err != nil {
 		return errors.Wrap(err, "Failed to decode 'play' args[1]")
 	}
-	var start int64
-	if err := d.Decode(&start); err != nil {
-		//
-		// io.EOF occurs when the start position is not specified.
-		//  'NetStream.play(streamName,null)'
-		// set start to 0 to avoid it.
-		//
-		if err != io.EOF {
-			return errors.Wrap(err, "Failed to decode 'play' args[2]")
+
+	args := []interface{}{commandObject, streamName}
+
+	// start, duration and reset are optional.
+	//  e.g. 'NetStream.play(streamName)', 'NetStream.play(streamName, null)'
+	for i, name := range []string{"start", "duration", "reset"} {
+		var arg interface{}
+		if err := d.Decode(&arg); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrapf(err, "Failed to decode 'play' args[%d] (%s)", i+2, name)
 		}
-		start = 0
+		args = append(args, arg)
 	}
 
 	var cmd NetStreamPlay
-	if err := cmd.FromArgs(commandObject, streamName, start); err != nil {
+	if err := cmd.FromArgs(args...); err != nil {
 		return errors.Wrap(err, "Failed to reconstruct 'play'")
 	}
 
@@ -248,6 +295,86 @@ func DecodeBodyPlay(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
 	return nil
 }
 
+func DecodeBodyPause(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
+	var commandObject interface{} // maybe nil
+	if err := d.Decode(&commandObject); err != nil {
+		return errors.Wrap(err, "Failed to decode 'pause' args[0]")
+	}
+	var pause interface{}
+	if err := d.Decode(&pause); err != nil {
+		return errors.Wrap(err, "Failed to decode 'pause' args[1]")
+	}
+	var milliseconds int64
+	if err := d.Decode(&milliseconds); err != nil {
+		return errors.Wrap(err, "Failed to decode 'pause' args[2]")
+	}
+
+	var cmd NetStreamPause
+	if err := cmd.FromArgs(commandObject, pause, milliseconds); err != nil {
+		return errors.Wrap(err, "Failed to reconstruct 'pause'")
+	}
+
+	*v = &cmd
+	return nil
+}
+
+func DecodeBodySeek(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
+	var commandObject interface{} // maybe nil
+	if err := d.Decode(&commandObject); err != nil {
+		return errors.Wrap(err, "Failed to decode 'seek' args[0]")
+	}
+	var milliseconds int64
+	if err := d.Decode(&milliseconds); err != nil {
+		return errors.Wrap(err, "Failed to decode 'seek' args[1]")
+	}
+
+	var cmd NetStreamSeek
+	if err := cmd.FromArgs(commandObject, milliseconds); err != nil {
+		return errors.Wrap(err, "Failed to reconstruct 'seek'")
+	}
+
+	*v = &cmd
+	return nil
+}
+
+func DecodeBodyReceiveAudio(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
+	var commandObject interface{} // maybe nil
+	if err := d.Decode(&commandObject); err != nil {
+		return errors.Wrap(err, "Failed to decode 'receiveAudio' args[0]")
+	}
+	var receive interface{}
+	if err := d.Decode(&receive); err != nil {
+		return errors.Wrap(err, "Failed to decode 'receiveAudio' args[1]")
+	}
+
+	var cmd NetStreamReceiveAudio
+	if err := cmd.FromArgs(commandObject, receive); err != nil {
+		return errors.Wrap(err, "Failed to reconstruct 'receiveAudio'")
+	}
+
+	*v = &cmd
+	return nil
+}
+
+func DecodeBodyReceiveVideo(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
+	var commandObject interface{} // maybe nil
+	if err := d.Decode(&commandObject); err != nil {
+		return errors.Wrap(err, "Failed to decode 'receiveVideo' args[0]")
+	}
+	var receive interface{}
+	if err := d.Decode(&receive); err != nil {
+		return errors.Wrap(err, "Failed to decode 'receiveVideo' args[1]")
+	}
+
+	var cmd NetStreamReceiveVideo
+	if err := cmd.FromArgs(commandObject, receive); err != nil {
+		return errors.Wrap(err, "Failed to reconstruct 'receiveVideo'")
+	}
+
+	*v = &cmd
+	return nil
+}
+
 func DecodeBodyReleaseStream(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
 	var commandObject interface{} // maybe nil
 	if err := d.Decode(&commandObject); err != nil {
@@ -354,3 +481,33 @@ func DecodeBodyCloseStream(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
 
 	return nil
 }
+
+func DecodeBodyOnStatus(_ io.Reader, d AMFDecoder, v *AMFConvertible) error {
+	var commandObject interface{} // maybe nil
+	if err := d.Decode(&commandObject); err != nil {
+		return errors.Wrap(err, "Failed to decode 'onStatus' args[0]")
+	}
+	var infoObject map[string]interface{}
+	if err := d.Decode(&infoObject); err != nil {
+		return errors.Wrap(err, "Failed to decode 'onStatus' args[1]")
+	}
+
+	// Statuses of NetConnection are also sent as "onStatus" (e.g. Enhanced RTMP ReconnectRequest)
+	if code, ok := infoObject["code"].(string); ok && strings.HasPrefix(code, "NetConnection.") {
+		var cmd NetConnectionOnStatus
+		if err := cmd.FromArgs(commandObject, infoObject); err != nil {
+			return errors.Wrap(err, "Failed to reconstruct 'onStatus'")
+		}
+
+		*v = &cmd
+		return nil
+	}
+
+	var cmd NetStreamOnStatus
+	if err := cmd.FromArgs(commandObject, infoObject); err != nil {
+		return errors.Wrap(err, "Failed to reconstruct 'onStatus'")
+	}
+
+	*v = &cmd
+	return nil
+}
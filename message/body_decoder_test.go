@@ -28,6 +28,45 @@ func TestDecodeDataMessageAtsetDataFrame(t *testing.T) {
 	}, v)
 }
 
+func TestDecodeDataMessageOnMetaData(t *testing.T) {
+	bin := []byte{
+		// ecma array: { width: 1280 }
+		0x08, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x05, 0x77, 0x69, 0x64, 0x74, 0x68,
+		0x00, 0x40, 0x94, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x09,
+	}
+	r := bytes.NewReader(bin)
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := DataBodyDecoderFor("onMetaData")(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamOnMetaData{
+		MetaData: map[string]interface{}{
+			"width": float64(1280),
+		},
+	}, v)
+}
+
+func TestDecodeDataMessageRtmpSampleAccess(t *testing.T) {
+	bin := []byte{
+		// true, false
+		0x01, 0x01,
+		0x01, 0x00,
+	}
+	r := bytes.NewReader(bin)
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := DataBodyDecoderFor("|RtmpSampleAccess")(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamRtmpSampleAccess{
+		AudioSampleAccess: true,
+		VideoSampleAccess: false,
+	}, v)
+}
+
 func TestDecodeDataMessageUnknown(t *testing.T) {
 	bin := []byte{
 		// nil
@@ -148,10 +187,133 @@ func TestDecodeCmdMessagePlay(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, &NetStreamPlay{
 		StreamName: "abc",
+		StartMode:  NetStreamPlayStartModeRecorded,
 		Start:      42,
 	}, v)
 }
 
+func TestDecodeCmdMessagePlayWithOptionalArgs(t *testing.T) {
+	bin := []byte{
+		// nil
+		0x05,
+		// string: abc
+		0x02, 0x00, 0x03, 0x61, 0x62, 0x63,
+		// number: 1.5
+		0x00, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// number: -1
+		0x00, 0xbf, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// boolean: false
+		0x01, 0x00,
+	}
+	r := bytes.NewReader(bin)
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := CmdBodyDecoderFor("play", 42)(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamPlay{
+		StreamName:  "abc",
+		StartMode:   NetStreamPlayStartModeRecorded,
+		Start:       1.5,
+		Duration:    -1,
+		HasDuration: true,
+		Reset:       false,
+		HasReset:    true,
+	}, v)
+
+	play := v.(*NetStreamPlay)
+	require.True(t, play.PlaysUntilEnd())
+	require.False(t, play.ShouldReset())
+}
+
+func TestDecodeCmdMessagePlayWithoutStart(t *testing.T) {
+	bin := []byte{
+		// nil
+		0x05,
+		// string: abc
+		0x02, 0x00, 0x03, 0x61, 0x62, 0x63,
+		// null
+		0x05,
+	}
+	r := bytes.NewReader(bin)
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := CmdBodyDecoderFor("play", 42)(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamPlay{
+		StreamName: "abc",
+		StartMode:  NetStreamPlayStartModeLiveOrRecorded,
+	}, v)
+
+	play := v.(*NetStreamPlay)
+	require.True(t, play.PlaysUntilEnd())
+	require.True(t, play.ShouldReset())
+}
+
+func TestDecodeCmdMessagePause(t *testing.T) {
+	bin := []byte{
+		// nil
+		0x05,
+		// boolean: true
+		0x01, 0x01,
+		// number: 42
+		0x00, 0x40, 0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	r := bytes.NewReader(bin)
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := CmdBodyDecoderFor("pause", 0)(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamPause{
+		Pause:        true,
+		Milliseconds: 42,
+	}, v)
+}
+
+func TestDecodeCmdMessageSeek(t *testing.T) {
+	bin := []byte{
+		// nil
+		0x05,
+		// number: 42
+		0x00, 0x40, 0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	r := bytes.NewReader(bin)
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := CmdBodyDecoderFor("seek", 0)(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamSeek{
+		Milliseconds: 42,
+	}, v)
+}
+
+func TestDecodeCmdMessageReceiveAudioAndVideo(t *testing.T) {
+	bin := []byte{
+		// nil
+		0x05,
+		// boolean: false
+		0x01, 0x00,
+	}
+
+	var v AMFConvertible
+	r := bytes.NewReader(bin)
+	err := CmdBodyDecoderFor("receiveAudio", 0)(r, amf0.NewDecoder(r), &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamReceiveAudio{
+		Receive: false,
+	}, v)
+
+	r = bytes.NewReader(bin)
+	err = CmdBodyDecoderFor("receiveVideo", 0)(r, amf0.NewDecoder(r), &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamReceiveVideo{
+		Receive: false,
+	}, v)
+}
+
 func TestDecodeCmdMessageReleaseStream(t *testing.T) {
 	bin := []byte{
 		// nil
@@ -252,6 +414,58 @@ func TestDecodeCmdMessageCloseStream(t *testing.T) {
 	require.Equal(t, &NetStreamCloseStream{}, v)
 }
 
+func TestDecodeCmdMessageOnStatus(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := amf0.NewEncoder(buf)
+	require.Nil(t, e.Encode(nil))
+	require.Nil(t, e.Encode(map[string]interface{}{
+		"level":       "status",
+		"code":        "NetStream.Play.Start",
+		"description": "Play succeeded.",
+	}))
+
+	r := bytes.NewReader(buf.Bytes())
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := CmdBodyDecoderFor("onStatus", 0)(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetStreamOnStatus{
+		InfoObject: NetStreamOnStatusInfoObject{
+			Level:       NetStreamOnStatusLevelStatus,
+			Code:        NetStreamOnStatusCodePlayStart,
+			Description: "Play succeeded.",
+		},
+	}, v)
+}
+
+func TestDecodeCmdMessageOnStatusOfNetConnection(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := amf0.NewEncoder(buf)
+	require.Nil(t, e.Encode(nil))
+	require.Nil(t, e.Encode(map[string]interface{}{
+		"level":       "status",
+		"code":        "NetConnection.Connect.ReconnectRequest",
+		"description": "Maintenance",
+		"tcUrl":       "rtmp://other.example.com/live",
+	}))
+
+	r := bytes.NewReader(buf.Bytes())
+	d := amf0.NewDecoder(r)
+
+	var v AMFConvertible
+	err := CmdBodyDecoderFor("onStatus", 0)(r, d, &v)
+	require.Nil(t, err)
+	require.Equal(t, &NetConnectionOnStatus{
+		InfoObject: NetConnectionOnStatusInfoObject{
+			Level:       NetConnectionOnStatusLevelStatus,
+			Code:        NetConnectionConnectCodeReconnectRequest,
+			Description: "Maintenance",
+			TCURL:       "rtmp://other.example.com/live",
+		},
+	}, v)
+}
+
 func TestDecodeCmdMessageUnknown(t *testing.T) {
 	bin := []byte{
 		// nil
@@ -21,6 +21,8 @@ func EncodeBodyAnyValues(e AMFEncoder, v AMFConvertible) error {
 	switch e.(type) {
 	case *amf0.Encoder:
 		amfTy = EncodingTypeAMF0
+	case *amf3Encoder:
+		amfTy = EncodingTypeAMF3
 	default:
 		return errors.Errorf("Unsupported AMF Encoder: Type = %T", e)
 	}
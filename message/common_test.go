@@ -94,7 +94,27 @@ var testCases = []testCase{
 		},
 		Binary: []byte("video data"),
 	},
-	// TODO: DataMessageAMF3
+	{
+		Name:   "DataMessageAMF3",
+		TypeID: TypeIDDataMessageAMF3,
+		Value: &DataMessage{
+			Name:     "test",
+			Encoding: EncodingTypeAMF3,
+			Body:     bytes.NewReader([]byte("test")),
+		},
+		Binary: []byte{
+			// Format selector: AMF0
+			0x00,
+			// Name: AMF0 / string marker
+			0x02,
+			// Name: AMF0 / string Length 4
+			0x00, 0x04,
+			// Name: AMF0 / "test" string
+			0x74, 0x65, 0x73, 0x74,
+			// RAW Binary: test
+			0x74, 0x65, 0x73, 0x74,
+		},
+	},
 	{
 		Name:   "DataMessageAMF0",
 		TypeID: TypeIDDataMessageAMF0,
@@ -114,9 +134,97 @@ var testCases = []testCase{
 			0x74, 0x65, 0x73, 0x74,
 		},
 	},
-	// TODO: TypeIDSharedObjectMessageAMF3
-	// TODO: TypeIDCommandMessageAMF3
-	// TODO: TypeIDSharedObjectMessageAMF0
+	{
+		Name:   "SharedObjectMessageAMF3",
+		TypeID: TypeIDSharedObjectMessageAMF3,
+		Value: &SharedObjectMessage{
+			ObjectName: "so",
+			Encoding:   EncodingTypeAMF3,
+			Events: []SharedObjectEvent{
+				&SharedObjectEventSendMessage{
+					Method: "m",
+					Args: []interface{}{
+						map[string]interface{}{"a": int32(1)},
+					},
+				},
+			},
+		},
+		Binary: []byte{
+			// Format selector: AMF0
+			0x00,
+			// Name: Length 2, "so"
+			0x00, 0x02, 0x73, 0x6f,
+			// Version: 0 (32bit, BigEndian)
+			0x00, 0x00, 0x00, 0x00,
+			// Flags: Not persistent
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			// Event: SendMessage, Length 13 (32bit, BigEndian)
+			0x06, 0x00, 0x00, 0x00, 0x0d,
+			// Method: AMF0 / "m" string
+			0x02, 0x00, 0x01, 0x6d,
+			// Args[0]: avmplus-object-marker, AMF3 / {a: 1}
+			0x11, 0x0a, 0x0b, 0x01, 0x03, 0x61, 0x04, 0x01, 0x01,
+		},
+	},
+	{
+		Name:   "CommandMessageAMF3",
+		TypeID: TypeIDCommandMessageAMF3,
+		Value: &CommandMessage{
+			CommandName:   "_result",
+			TransactionID: 10,
+			Encoding:      EncodingTypeAMF3,
+			Body:          bytes.NewReader([]byte("test")),
+		},
+		Binary: []byte{
+			// Format selector: AMF0
+			0x00,
+			// CommandName: AMF0 / string marker
+			0x02,
+			// CommandName: AMF0 / string Length
+			0x00, 0x07,
+			// CommandName: AMF0 / "_result" string
+			0x5f, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74,
+			// TransactionID: AMF0 / number marker
+			0x00,
+			// TransactionID: AMF0 / 10 number
+			0x40, 0x24, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			// RAW Binary: test
+			0x74, 0x65, 0x73, 0x74,
+		},
+	},
+	{
+		Name:   "SharedObjectMessageAMF0",
+		TypeID: TypeIDSharedObjectMessageAMF0,
+		Value: &SharedObjectMessage{
+			ObjectName: "so",
+			Version:    1,
+			Persistent: true,
+			Encoding:   EncodingTypeAMF0,
+			Events: []SharedObjectEvent{
+				&SharedObjectEventUse{},
+				&SharedObjectEventRequestChange{
+					Name:  "a",
+					Value: float64(1),
+				},
+			},
+		},
+		Binary: []byte{
+			// Name: Length 2, "so"
+			0x00, 0x02, 0x73, 0x6f,
+			// Version: 1 (32bit, BigEndian)
+			0x00, 0x00, 0x00, 0x01,
+			// Flags: Persistent
+			0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00,
+			// Event: Use, Length 0 (32bit, BigEndian)
+			0x01, 0x00, 0x00, 0x00, 0x00,
+			// Event: RequestChange, Length 12 (32bit, BigEndian)
+			0x03, 0x00, 0x00, 0x00, 0x0c,
+			// Name: Length 1, "a"
+			0x00, 0x01, 0x61,
+			// Value: AMF0 / 1 number
+			0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	},
 	{
 		Name:   "CommandMessageAMF0",
 		TypeID: TypeIDCommandMessageAMF0,
@@ -141,5 +249,50 @@ var testCases = []testCase{
 			0x74, 0x65, 0x73, 0x74,
 		},
 	},
-	// TODO: TypeIDAggregateMessage
+	{
+		Name:   "AggregateMessage",
+		TypeID: TypeIDAggregateMessage,
+		Value: &AggregateMessage{
+			Messages: []*AggregateSubMessage{
+				{
+					Timestamp: 0x01000010,
+					Message: &AudioMessage{
+						Payload: bytes.NewReader([]byte("audio")),
+					},
+				},
+				{
+					Timestamp: 0x01000020,
+					Message: &VideoMessage{
+						Payload: bytes.NewReader([]byte("video")),
+					},
+				},
+			},
+		},
+		Binary: []byte{
+			// Type 8(AudioMessage)
+			0x08,
+			// DataSize 5 (24bit, BigEndian)
+			0x00, 0x00, 0x05,
+			// Timestamp 0x000010 (24bit, BigEndian), Extended 0x01 (8bit)
+			0x00, 0x00, 0x10, 0x01,
+			// StreamID 0 (24bit)
+			0x00, 0x00, 0x00,
+			// Data: "audio"
+			0x61, 0x75, 0x64, 0x69, 0x6f,
+			// BackPointer 16 (32bit, BigEndian)
+			0x00, 0x00, 0x00, 0x10,
+			// Type 9(VideoMessage)
+			0x09,
+			// DataSize 5 (24bit, BigEndian)
+			0x00, 0x00, 0x05,
+			// Timestamp 0x000020 (24bit, BigEndian), Extended 0x01 (8bit)
+			0x00, 0x00, 0x20, 0x01,
+			// StreamID 0 (24bit)
+			0x00, 0x00, 0x00,
+			// Data: "video"
+			0x76, 0x69, 0x64, 0x65, 0x6f,
+			// BackPointer 16 (32bit, BigEndian)
+			0x00, 0x00, 0x00, 0x10,
+		},
+	},
 }
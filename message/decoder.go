@@ -8,6 +8,7 @@
 package message
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -188,15 +189,39 @@ func (dec *Decoder) decodeVideoMessage(msg *Message) error {
 }
 
 func (dec *Decoder) decodeDataMessageAMF3(msg *Message) error {
-	return fmt.Errorf("Not implemented: DataMessageAMF3")
+	if err := dec.skipAMF3FormatSelector(); err != nil {
+		return err
+	}
+
+	if err := dec.decodeDataMessage(msg, func(r io.Reader) (AMFDecoder, EncodingType) {
+		return newAMF3Decoder(r), EncodingTypeAMF3
+	}); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (dec *Decoder) decodeSharedObjectMessageAMF3(msg *Message) error {
-	return fmt.Errorf("Not implemented: SharedObjectMessageAMF3")
+	if err := dec.skipAMF3FormatSelector(); err != nil {
+		return err
+	}
+
+	return dec.decodeSharedObjectMessage(msg, EncodingTypeAMF3)
 }
 
 func (dec *Decoder) decodeCommandMessageAMF3(msg *Message) error {
-	return fmt.Errorf("Not implemented: CommandMessageAMF3")
+	if err := dec.skipAMF3FormatSelector(); err != nil {
+		return err
+	}
+
+	if err := dec.decodeCommandMessage(msg, func(r io.Reader) (AMFDecoder, EncodingType) {
+		return newAMF3Decoder(r), EncodingTypeAMF3
+	}); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (dec *Decoder) decodeDataMessageAMF0(msg *Message) error {
@@ -210,7 +235,7 @@ func (dec *Decoder) decodeDataMessageAMF0(msg *Message) error {
 }
 
 func (dec *Decoder) decodeSharedObjectMessageAMF0(msg *Message) error {
-	return fmt.Errorf("Not implemented: SharedObjectMessageAMF0")
+	return dec.decodeSharedObjectMessage(msg, EncodingTypeAMF0)
 }
 
 func (dec *Decoder) decodeCommandMessageAMF0(msg *Message) error {
@@ -224,7 +249,120 @@ func (dec *Decoder) decodeCommandMessageAMF0(msg *Message) error {
 }
 
 func (dec *Decoder) decodeAggregateMessage(msg *Message) error {
-	return fmt.Errorf("Not implemented: AggregateMessage")
+	var subMsgs []*AggregateSubMessage
+
+	buf := make([]byte, aggregateSubMessageHeaderSize)
+	for {
+		if _, err := io.ReadFull(dec.r, buf[:1]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrap(err, "Failed to decode type of sub message")
+		}
+		if _, err := io.ReadFull(dec.r, buf[1:aggregateSubMessageHeaderSize]); err != nil {
+			return errors.Wrap(err, "Failed to decode header of sub message")
+		}
+
+		typeID := TypeID(buf[0])
+		dataSize := uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])
+		timestamp := uint32(buf[7])<<24 | uint32(buf[4])<<16 | uint32(buf[5])<<8 | uint32(buf[6]) // [7] is an extended part
+		// [8:11] is a stream id, but it is ignored(always same as the aggregate message)
+
+		if typeID == TypeIDAggregateMessage {
+			return errors.New("Nested aggregate messages are not supported")
+		}
+
+		data := make([]byte, dataSize)
+		if _, err := io.ReadFull(dec.r, data); err != nil {
+			return errors.Wrap(err, "Failed to decode data of sub message")
+		}
+
+		if _, err := io.ReadFull(dec.r, buf[:4]); err != nil {
+			return errors.Wrap(err, "Failed to decode back pointer of sub message")
+		}
+		backPointer := binary.BigEndian.Uint32(buf[:4])
+		if backPointer != aggregateSubMessageHeaderSize+dataSize {
+			return errors.Errorf(
+				"Invalid back pointer of sub message: Expected = %d, Actual = %d",
+				aggregateSubMessageHeaderSize+dataSize,
+				backPointer,
+			)
+		}
+
+		var subMsg Message
+		if err := NewDecoder(bytes.NewReader(data)).Decode(typeID, &subMsg); err != nil {
+			return errors.Wrap(err, "Failed to decode sub message")
+		}
+
+		subMsgs = append(subMsgs, &AggregateSubMessage{
+			Timestamp: timestamp,
+			Message:   subMsg,
+		})
+	}
+
+	*msg = &AggregateMessage{
+		Messages: subMsgs,
+	}
+
+	return nil
+}
+
+func (dec *Decoder) decodeSharedObjectMessage(msg *Message, encTy EncodingType) error {
+	buf := make([]byte, 8)
+	if _, err := io.ReadAtLeast(dec.r, buf[:2], 2); err != nil {
+		return errors.Wrap(err, "Failed to decode name length")
+	}
+	name := make([]byte, binary.BigEndian.Uint16(buf[:2]))
+	if _, err := io.ReadAtLeast(dec.r, name, len(name)); err != nil {
+		return errors.Wrap(err, "Failed to decode name")
+	}
+
+	if _, err := io.ReadAtLeast(dec.r, buf[:4], 4); err != nil {
+		return errors.Wrap(err, "Failed to decode version")
+	}
+	version := binary.BigEndian.Uint32(buf[:4])
+
+	if _, err := io.ReadAtLeast(dec.r, buf[:8], 8); err != nil {
+		return errors.Wrap(err, "Failed to decode flags")
+	}
+	persistent := binary.BigEndian.Uint32(buf[:4]) == sharedObjectPersistentFlag // buf[4:8] is reserved
+
+	d := NewSharedObjectEventDecoder(dec.r, encTy)
+
+	var events []SharedObjectEvent
+	for {
+		var event SharedObjectEvent
+		if err := d.Decode(&event); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrap(err, "Failed to decode shared object event")
+		}
+		events = append(events, event)
+	}
+
+	*msg = &SharedObjectMessage{
+		ObjectName: string(name),
+		Version:    version,
+		Persistent: persistent,
+		Encoding:   encTy,
+		Events:     events,
+	}
+
+	return nil
+}
+
+// skipAMF3FormatSelector Skips the first byte of AMF3 data/command messages. It must be 0 (AMF0 values follow).
+func (dec *Decoder) skipAMF3FormatSelector() error {
+	buf := make([]byte, 1)
+	if _, err := io.ReadAtLeast(dec.r, buf, 1); err != nil {
+		return errors.Wrap(err, "Failed to decode format selector")
+	}
+	if buf[0] != 0 {
+		return fmt.Errorf("Unexpected format selector of AMF3 messages: %d", buf[0])
+	}
+
+	return nil
 }
 
 func (dec *Decoder) decodeDataMessage(msg *Message, f func(r io.Reader) (AMFDecoder, EncodingType)) error {
@@ -32,6 +32,19 @@ func TestDecodeCommon(t *testing.T) {
 	}
 }
 
+func TestDecodeAggregateMessageWithInvalidBackPointer(t *testing.T) {
+	buf := bytes.NewReader([]byte{
+		0x08, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Header
+		0x61,                   // Data
+		0x00, 0x00, 0x00, 0x0b, // BackPointer 11 (must be 12)
+	})
+	dec := NewDecoder(buf)
+
+	var msg Message
+	err := dec.Decode(TypeIDAggregateMessage, &msg)
+	require.EqualError(t, err, "Invalid back pointer of sub message: Expected = 12, Actual = 11")
+}
+
 func BenchmarkDecode5KBVideoMessage(b *testing.B) {
 	sizes := []struct {
 		name string
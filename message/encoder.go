@@ -8,9 +8,12 @@
 package message
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+
+	"github.com/pkg/errors"
 )
 
 type Encoder struct {
@@ -48,12 +51,18 @@ func (enc *Encoder) Encode(msg Message) error {
 		return enc.encodeVideoMessage(msg)
 	case *DataMessage:
 		return enc.encodeDataMessage(msg)
+	case *SharedObjectMessage:
+		return enc.encodeSharedObjectMessage(msg)
 	case *SharedObjectMessageAMF3:
-		return enc.encodeSharedObjectMessageAMF3(msg)
+		m := msg.SharedObjectMessage
+		m.Encoding = EncodingTypeAMF3
+		return enc.encodeSharedObjectMessage(&m)
+	case *SharedObjectMessageAMF0:
+		m := msg.SharedObjectMessage
+		m.Encoding = EncodingTypeAMF0
+		return enc.encodeSharedObjectMessage(&m)
 	case *CommandMessage:
 		return enc.encodeCommandMessage(msg)
-	case *SharedObjectMessageAMF0:
-		return enc.encodeSharedObjectMessageAMF0(msg)
 	case *AggregateMessage:
 		return enc.encodeAggregateMessage(msg)
 	default:
@@ -142,11 +151,11 @@ func (enc *Encoder) encodeVideoMessage(m *VideoMessage) error {
 	return nil
 }
 
-func (enc *Encoder) encodeSharedObjectMessageAMF3(m *SharedObjectMessageAMF3) error {
-	return fmt.Errorf("Not implemented: SharedObjectMessageAMF3")
-}
-
 func (enc *Encoder) encodeDataMessage(m *DataMessage) error {
+	if err := enc.writeAMF3FormatSelector(m.Encoding); err != nil {
+		return err
+	}
+
 	e := NewAMFEncoder(enc.w, m.Encoding)
 
 	if err := e.Encode(m.Name); err != nil {
@@ -160,11 +169,39 @@ func (enc *Encoder) encodeDataMessage(m *DataMessage) error {
 	return nil
 }
 
-func (enc *Encoder) encodeSharedObjectMessageAMF0(m *SharedObjectMessageAMF0) error {
-	return fmt.Errorf("Not implemented: SharedObjectMessageAMF0")
+func (enc *Encoder) encodeSharedObjectMessage(m *SharedObjectMessage) error {
+	if err := enc.writeAMF3FormatSelector(m.Encoding); err != nil {
+		return err
+	}
+
+	if err := writeShortString(enc.w, m.ObjectName); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+8)
+	binary.BigEndian.PutUint32(buf[0:4], m.Version) // [0:4]: version
+	if m.Persistent {
+		binary.BigEndian.PutUint32(buf[4:8], sharedObjectPersistentFlag) // [4:12]: flags ([8:12] is reserved)
+	}
+	if _, err := enc.w.Write(buf); err != nil {
+		return err
+	}
+
+	e := NewSharedObjectEventEncoder(enc.w, m.Encoding)
+	for _, event := range m.Events {
+		if err := e.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (enc *Encoder) encodeCommandMessage(m *CommandMessage) error {
+	if err := enc.writeAMF3FormatSelector(m.Encoding); err != nil {
+		return err
+	}
+
 	e := NewAMFEncoder(enc.w, m.Encoding)
 
 	if err := e.Encode(m.CommandName); err != nil {
@@ -181,6 +218,51 @@ func (enc *Encoder) encodeCommandMessage(m *CommandMessage) error {
 	return nil
 }
 
+// writeAMF3FormatSelector Writes the first byte of AMF3 data/command messages, which means AMF0 values follow.
+func (enc *Encoder) writeAMF3FormatSelector(encTy EncodingType) error {
+	if encTy != EncodingTypeAMF3 {
+		return nil
+	}
+
+	_, err := enc.w.Write([]byte{0x00})
+	return err
+}
+
 func (enc *Encoder) encodeAggregateMessage(m *AggregateMessage) error {
-	return fmt.Errorf("Not implemented: AggregateMessage")
+	buf := make([]byte, aggregateSubMessageHeaderSize)
+	var data bytes.Buffer
+	for _, subMsg := range m.Messages {
+		if subMsg.Message.TypeID() == TypeIDAggregateMessage {
+			return errors.New("Nested aggregate messages are not supported")
+		}
+
+		data.Reset()
+		if err := NewEncoder(&data).Encode(subMsg.Message); err != nil {
+			return errors.Wrap(err, "Failed to encode sub message")
+		}
+		dataSize := uint32(data.Len())
+		if dataSize > 0xffffff {
+			return errors.Errorf("Too large sub message: Size = %d", dataSize)
+		}
+
+		buf[0] = byte(subMsg.Message.TypeID())
+		buf[1], buf[2], buf[3] = byte(dataSize>>16), byte(dataSize>>8), byte(dataSize)
+		ts := subMsg.Timestamp
+		buf[4], buf[5], buf[6], buf[7] = byte(ts>>16), byte(ts>>8), byte(ts), byte(ts>>24) // [7] is an extended part
+		buf[8], buf[9], buf[10] = 0, 0, 0                                                  // stream id is always 0
+		if _, err := enc.w.Write(buf); err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(enc.w, &data); err != nil {
+			return err
+		}
+
+		binary.BigEndian.PutUint32(buf[:4], aggregateSubMessageHeaderSize+dataSize)
+		if _, err := enc.w.Write(buf[:4]); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -30,3 +30,24 @@ func TestEncodeCommon(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeSharedObjectMessageOfFixedEncoding(t *testing.T) {
+	so := SharedObjectMessage{
+		ObjectName: "so",
+		Version:    1,
+		Events: []SharedObjectEvent{
+			&SharedObjectEventChange{Name: "a", Value: "b"},
+		},
+	}
+
+	expected := new(bytes.Buffer)
+	msg := so
+	msg.Encoding = EncodingTypeAMF0
+	err := NewEncoder(expected).Encode(&msg)
+	require.Nil(t, err)
+
+	actual := new(bytes.Buffer)
+	err = NewEncoder(actual).Encode(&SharedObjectMessageAMF0{SharedObjectMessage: so})
+	require.Nil(t, err)
+	require.Equal(t, expected.Bytes(), actual.Bytes())
+}
@@ -0,0 +1,26 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+// FourCC A codec identifier which is used by Enhanced RTMP instead of legacy codec IDs.
+type FourCC string
+
+const (
+	// Video
+	FourCCAV1  FourCC = "av01"
+	FourCCVP9  FourCC = "vp09"
+	FourCCHEVC FourCC = "hvc1"
+
+	// Audio
+	FourCCAC3  FourCC = "ac-3"
+	FourCCEAC3 FourCC = "ec-3"
+	FourCCOpus FourCC = "Opus"
+	FourCCMP3  FourCC = ".mp3"
+	FourCCFLAC FourCC = "fLaC"
+	FourCCAAC  FourCC = "mp4a"
+)
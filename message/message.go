@@ -137,8 +137,27 @@ func (m *DataMessage) TypeID() TypeID {
 
 // SharedObjectMessage (16, 19)
 type SharedObjectMessage struct {
+	ObjectName string
+	Version    uint32
+	Persistent bool
+	Encoding   EncodingType
+	Events     []SharedObjectEvent
 }
 
+func (m *SharedObjectMessage) TypeID() TypeID {
+	switch m.Encoding {
+	case EncodingTypeAMF3:
+		return TypeIDSharedObjectMessageAMF3
+	case EncodingTypeAMF0:
+		return TypeIDSharedObjectMessageAMF0
+	default:
+		panic("Unreachable")
+	}
+}
+
+// SharedObjectMessageAMF3 A shared object message which is always encoded in AMF3.
+//
+// Deprecated: Use SharedObjectMessage with EncodingTypeAMF3 instead.
 type SharedObjectMessageAMF3 struct {
 	SharedObjectMessage
 }
@@ -147,6 +166,9 @@ func (m *SharedObjectMessageAMF3) TypeID() TypeID {
 	return TypeIDSharedObjectMessageAMF3
 }
 
+// SharedObjectMessageAMF0 A shared object message which is always encoded in AMF0.
+//
+// Deprecated: Use SharedObjectMessage with EncodingTypeAMF0 instead.
 type SharedObjectMessageAMF0 struct {
 	SharedObjectMessage
 }
@@ -174,8 +196,19 @@ func (m *CommandMessage) TypeID() TypeID {
 	}
 }
 
+// aggregateSubMessageHeaderSize A size of a header of sub messages, which is the same as the FLV tag header
+const aggregateSubMessageHeaderSize = 11
+
 // AggregateMessage (22)
 type AggregateMessage struct {
+	Messages []*AggregateSubMessage
+}
+
+// AggregateSubMessage A message in an aggregate message.
+// Timestamp is a raw value in the aggregate message, thus it must be rebased on the timestamp of the aggregate message.
+type AggregateSubMessage struct {
+	Timestamp uint32
+	Message   Message
 }
 
 func (m *AggregateMessage) TypeID() TypeID {
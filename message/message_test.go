@@ -49,6 +49,16 @@ func assertEqualMessage(t *testing.T, expected, actual Message) {
 		require.Equal(t, expected.Encoding, actual.Encoding)
 		assertEqualPayload(t, expected.Body, actual.Body)
 
+	case *AggregateMessage:
+		actual, ok := actual.(*AggregateMessage)
+		require.True(t, ok)
+
+		require.Equal(t, len(expected.Messages), len(actual.Messages))
+		for i, expectedSubMsg := range expected.Messages {
+			require.Equal(t, expectedSubMsg.Timestamp, actual.Messages[i].Timestamp)
+			assertEqualMessage(t, expectedSubMsg.Message, actual.Messages[i].Message)
+		}
+
 	default:
 		require.Equal(t, expected, actual)
 	}
@@ -0,0 +1,122 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// MultitrackType A layout of tracks in a multitrack audio/video body (Enhanced RTMP).
+type MultitrackType uint8
+
+const (
+	MultitrackTypeOneTrack             MultitrackType = 0
+	MultitrackTypeManyTracks           MultitrackType = 1
+	MultitrackTypeManyTracksManyCodecs MultitrackType = 2
+)
+
+// MultitrackTrack A track in a multitrack audio/video body.
+type MultitrackTrack struct {
+	TrackID uint8
+	FourCC  FourCC
+	Body    []byte
+}
+
+// DecodeMultitrackTracks Reads tracks from a body which follows a multitrack header.
+// fourCC in the header is used as codecs of tracks unless ty is ManyTracksManyCodecs.
+func DecodeMultitrackTracks(r io.Reader, ty MultitrackType, fourCC FourCC) ([]*MultitrackTrack, error) {
+	if ty > MultitrackTypeManyTracksManyCodecs {
+		return nil, errors.Errorf("Unsupported multitrack type: MultitrackType = %d", ty)
+	}
+
+	var tracks []*MultitrackTrack
+	buf := make([]byte, 4)
+	for {
+		track := &MultitrackTrack{
+			FourCC: fourCC,
+		}
+
+		if ty == MultitrackTypeManyTracksManyCodecs {
+			if _, err := io.ReadFull(r, buf[:4]); err != nil {
+				if err == io.EOF {
+					return tracks, nil
+				}
+				return nil, err
+			}
+			track.FourCC = FourCC(buf[:4])
+		}
+
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			if err == io.EOF && ty == MultitrackTypeManyTracks {
+				return tracks, nil
+			}
+			return nil, err
+		}
+		track.TrackID = buf[0]
+
+		if ty == MultitrackTypeOneTrack {
+			body, err := ioutil.ReadAll(r)
+			if err != nil {
+				return nil, err
+			}
+			track.Body = body
+
+			return append(tracks, track), nil
+		}
+
+		if _, err := io.ReadFull(r, buf[:3]); err != nil {
+			return nil, err
+		}
+		size := uint32(buf[0])<<16 | uint32(buf[1])<<8 | uint32(buf[2])
+
+		track.Body = make([]byte, size)
+		if _, err := io.ReadFull(r, track.Body); err != nil {
+			return nil, err
+		}
+
+		tracks = append(tracks, track)
+	}
+}
+
+// EncodeMultitrackTracks Writes tracks as a body which follows a multitrack header.
+func EncodeMultitrackTracks(w io.Writer, ty MultitrackType, tracks []*MultitrackTrack) error {
+	if ty == MultitrackTypeOneTrack && len(tracks) != 1 {
+		return errors.Errorf("OneTrack must have exactly 1 track: Tracks = %d", len(tracks))
+	}
+
+	for _, track := range tracks {
+		buf := make([]byte, 0, 8)
+		if ty == MultitrackTypeManyTracksManyCodecs {
+			if len(track.FourCC) != 4 {
+				return errors.Errorf("FourCC must be 4 characters: FourCC = %s", track.FourCC)
+			}
+			buf = append(buf, track.FourCC...)
+		}
+		buf = append(buf, track.TrackID)
+
+		if ty != MultitrackTypeOneTrack {
+			size := len(track.Body)
+			if size > 0xffffff {
+				return errors.Errorf("Track is too large: TrackID = %d, Size = %d", track.TrackID, size)
+			}
+			buf = append(buf, byte(size>>16), byte(size>>8), byte(size))
+		}
+
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		if _, err := w.Write(track.Body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
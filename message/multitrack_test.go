@@ -0,0 +1,94 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type multitrackTestCase struct {
+	Name   string
+	Type   MultitrackType
+	FourCC FourCC
+	Tracks []*MultitrackTrack
+	Binary []byte
+}
+
+var multitrackTestCases = []multitrackTestCase{
+	{
+		Name:   "OneTrack",
+		Type:   MultitrackTypeOneTrack,
+		FourCC: FourCCOpus,
+		Tracks: []*MultitrackTrack{
+			{TrackID: 1, FourCC: FourCCOpus, Body: []byte("en")},
+		},
+		Binary: []byte{0x01, 'e', 'n'},
+	},
+	{
+		Name:   "ManyTracks",
+		Type:   MultitrackTypeManyTracks,
+		FourCC: FourCCOpus,
+		Tracks: []*MultitrackTrack{
+			{TrackID: 0, FourCC: FourCCOpus, Body: []byte("en")},
+			{TrackID: 1, FourCC: FourCCOpus, Body: []byte("ja")},
+		},
+		Binary: []byte{
+			0x00, 0x00, 0x00, 0x02, 'e', 'n',
+			0x01, 0x00, 0x00, 0x02, 'j', 'a',
+		},
+	},
+	{
+		Name: "ManyTracksManyCodecs",
+		Type: MultitrackTypeManyTracksManyCodecs,
+		Tracks: []*MultitrackTrack{
+			{TrackID: 0, FourCC: FourCCOpus, Body: []byte("en")},
+			{TrackID: 1, FourCC: FourCCAAC, Body: []byte("ja!")},
+		},
+		Binary: []byte{
+			'O', 'p', 'u', 's', 0x00, 0x00, 0x00, 0x02, 'e', 'n',
+			'm', 'p', '4', 'a', 0x01, 0x00, 0x00, 0x03, 'j', 'a', '!',
+		},
+	},
+}
+
+func TestDecodeMultitrackTracks(t *testing.T) {
+	for _, tc := range multitrackTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			tracks, err := DecodeMultitrackTracks(bytes.NewReader(tc.Binary), tc.Type, tc.FourCC)
+			require.Nil(t, err)
+			require.Equal(t, tc.Tracks, tracks)
+		})
+	}
+}
+
+func TestEncodeMultitrackTracks(t *testing.T) {
+	for _, tc := range multitrackTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			err := EncodeMultitrackTracks(buf, tc.Type, tc.Tracks)
+			require.Nil(t, err)
+			require.Equal(t, tc.Binary, buf.Bytes())
+		})
+	}
+}
+
+func TestDecodeMultitrackTracksWithTruncatedBody(t *testing.T) {
+	_, err := DecodeMultitrackTracks(bytes.NewReader([]byte{0x00, 0x00, 0x00, 0x05, 'e'}), MultitrackTypeManyTracks, FourCCOpus)
+	require.NotNil(t, err)
+}
@@ -19,6 +19,16 @@ const (
 	NetConnectionConnectCodeSuccess NetConnectionConnectCode = "NetConnection.Connect.Success"
 	NetConnectionConnectCodeFailed  NetConnectionConnectCode = "NetConnection.Connect.Failed"
 	NetConnectionConnectCodeClosed  NetConnectionConnectCode = "NetConnection.Connect.Closed"
+	// Enhanced RTMP: Sent by a server to request a client to reconnect to the server or another one
+	NetConnectionConnectCodeReconnectRequest NetConnectionConnectCode = "NetConnection.Connect.ReconnectRequest"
+)
+
+type NetConnectionOnStatusLevel string
+
+const (
+	NetConnectionOnStatusLevelStatus  NetConnectionOnStatusLevel = "status"
+	NetConnectionOnStatusLevelWarning NetConnectionOnStatusLevel = "warning"
+	NetConnectionOnStatusLevelError   NetConnectionOnStatusLevel = "error"
 )
 
 type NetConnectionConnect struct {
@@ -26,16 +36,16 @@ type NetConnectionConnect struct {
 }
 
 type NetConnectionConnectCommand struct {
-	App            string       `mapstructure:"app" amf0:"app"`
-	Type           string       `mapstructure:"type" amf0:"type"`
-	FlashVer       string       `mapstructure:"flashVer" amf0:"flashVer"`
-	TCURL          string       `mapstructure:"tcUrl" amf0:"tcUrl"`
-	Fpad           bool         `mapstructure:"fpad" amf0:"fpad"`
-	Capabilities   int          `mapstructure:"capabilities" amf0:"capabilities"`
-	AudioCodecs    int          `mapstructure:"audioCodecs" amf0:"audioCodecs"`
-	VideoCodecs    int          `mapstructure:"videoCodecs" amf0:"videoCodecs"`
-	VideoFunction  int          `mapstructure:"videoFunction" amf0:"videoFunction"`
-	ObjectEncoding EncodingType `mapstructure:"objectEncoding" amf0:"objectEncoding"`
+	App            string       `mapstructure:"app" amf0:"app" amf3:"app"`
+	Type           string       `mapstructure:"type" amf0:"type" amf3:"type"`
+	FlashVer       string       `mapstructure:"flashVer" amf0:"flashVer" amf3:"flashVer"`
+	TCURL          string       `mapstructure:"tcUrl" amf0:"tcUrl" amf3:"tcUrl"`
+	Fpad           bool         `mapstructure:"fpad" amf0:"fpad" amf3:"fpad"`
+	Capabilities   int          `mapstructure:"capabilities" amf0:"capabilities" amf3:"capabilities"`
+	AudioCodecs    int          `mapstructure:"audioCodecs" amf0:"audioCodecs" amf3:"audioCodecs"`
+	VideoCodecs    int          `mapstructure:"videoCodecs" amf0:"videoCodecs" amf3:"videoCodecs"`
+	VideoFunction  int          `mapstructure:"videoFunction" amf0:"videoFunction" amf3:"videoFunction"`
+	ObjectEncoding EncodingType `mapstructure:"objectEncoding" amf0:"objectEncoding" amf3:"objectEncoding"`
 }
 
 func (t *NetConnectionConnect) FromArgs(args ...interface{}) error {
@@ -59,16 +69,27 @@ type NetConnectionConnectResult struct {
 }
 
 type NetConnectionConnectResultProperties struct {
-	FMSVer       string `mapstructure:"fmsVer" amf0:"fmsVer"`             // TODO: fix
-	Capabilities int    `mapstructure:"capabilities" amf0:"capabilities"` // TODO: fix
-	Mode         int    `mapstructure:"mode" amf0:"mode"`                 // TODO: fix
+	FMSVer       string `mapstructure:"fmsVer" amf0:"fmsVer" amf3:"fmsVer"`                   // TODO: fix
+	Capabilities int    `mapstructure:"capabilities" amf0:"capabilities" amf3:"capabilities"` // TODO: fix
+	Mode         int    `mapstructure:"mode" amf0:"mode" amf3:"mode"`                         // TODO: fix
+	// Enhanced RTMP: Codecs which are supported by the server. Omitted if empty
+	FourCCList []FourCC `mapstructure:"fourCcList" amf0:"fourCcList" amf3:"fourCcList"`
+}
+
+// netConnectionConnectResultLegacyProperties Properties which are sent if FourCCList is empty.
+type netConnectionConnectResultLegacyProperties struct {
+	FMSVer       string `amf0:"fmsVer" amf3:"fmsVer"`
+	Capabilities int    `amf0:"capabilities" amf3:"capabilities"`
+	Mode         int    `amf0:"mode" amf3:"mode"`
 }
 
 type NetConnectionConnectResultInformation struct {
-	Level       string                   `mapstructure:"level" amf0:"level"` // TODO: fix
-	Code        NetConnectionConnectCode `mapstructure:"code" amf0:"code"`
-	Description string                   `mapstructure:"description" amf0:"description"`
-	Data        amf0.ECMAArray           `mapstructure:"data" amf0:"data"`
+	Level       string                   `mapstructure:"level" amf0:"level" amf3:"level"` // TODO: fix
+	Code        NetConnectionConnectCode `mapstructure:"code" amf0:"code" amf3:"code"`
+	Description string                   `mapstructure:"description" amf0:"description" amf3:"description"`
+	Data        amf0.ECMAArray           `mapstructure:"data" amf0:"data" amf3:"data"`
+	// Encoding which is used after the connection is established
+	ObjectEncoding EncodingType `mapstructure:"objectEncoding" amf0:"objectEncoding" amf3:"objectEncoding"`
 }
 
 func (t *NetConnectionConnectResult) FromArgs(args ...interface{}) error {
@@ -86,12 +107,61 @@ func (t *NetConnectionConnectResult) FromArgs(args ...interface{}) error {
 }
 
 func (t *NetConnectionConnectResult) ToArgs(ty EncodingType) ([]interface{}, error) {
+	var properties interface{} = t.Properties
+	if len(t.Properties.FourCCList) == 0 {
+		properties = netConnectionConnectResultLegacyProperties{
+			FMSVer:       t.Properties.FMSVer,
+			Capabilities: t.Properties.Capabilities,
+			Mode:         t.Properties.Mode,
+		}
+	}
+
 	return []interface{}{
-		t.Properties,
+		properties,
 		t.Information,
 	}, nil
 }
 
+// NetConnectionOnStatus "onStatus" which is sent on the NetConnection, e.g. NetConnection.Connect.ReconnectRequest.
+type NetConnectionOnStatus struct {
+	InfoObject NetConnectionOnStatusInfoObject
+}
+
+type NetConnectionOnStatusInfoObject struct {
+	Level       NetConnectionOnStatusLevel `mapstructure:"level"`
+	Code        NetConnectionConnectCode   `mapstructure:"code"`
+	Description string                     `mapstructure:"description"`
+	TCURL       string                     `mapstructure:"tcUrl"` // Optional, a destination of ReconnectRequest
+}
+
+func (t *NetConnectionOnStatus) FromArgs(args ...interface{}) error {
+	// args[0] is nil
+	info, ok := args[1].(map[string]interface{})
+	if !ok {
+		return errors.Errorf("Info object is not an object: Value = %#v", args[1])
+	}
+	if err := mapstructure.Decode(info, &t.InfoObject); err != nil {
+		return errors.Wrapf(err, "Failed to mapping NetConnectionOnStatusInfoObject")
+	}
+
+	return nil
+}
+
+func (t *NetConnectionOnStatus) ToArgs(ty EncodingType) ([]interface{}, error) {
+	info := make(map[string]interface{})
+	info["level"] = t.InfoObject.Level
+	info["code"] = t.InfoObject.Code
+	info["description"] = t.InfoObject.Description
+	if t.InfoObject.TCURL != "" {
+		info["tcUrl"] = t.InfoObject.TCURL
+	}
+
+	return []interface{}{
+		nil, // Always nil
+		info,
+	}, nil
+}
+
 type NetConnectionCreateStream struct {
 }
 
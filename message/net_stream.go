@@ -7,6 +7,12 @@
 
 package message
 
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/yutopp/go-amf0"
+)
+
 type NetStreamPublish struct {
 	CommandObject  interface{}
 	PublishingName string
@@ -29,44 +35,287 @@ func (t *NetStreamPublish) ToArgs(ty EncodingType) ([]interface{}, error) {
 	}, nil
 }
 
+// NetStreamPlayStartMode How a stream is looked up by "play" (7.2.2.1).
+type NetStreamPlayStartMode int
+
+const (
+	// NetStreamPlayStartModeLiveOrRecorded Plays a live stream, or a recorded stream if it is not found (start = -2, default)
+	NetStreamPlayStartModeLiveOrRecorded NetStreamPlayStartMode = iota
+	// NetStreamPlayStartModeLive Plays only a live stream (start = -1)
+	NetStreamPlayStartModeLive
+	// NetStreamPlayStartModeRecorded Plays a recorded stream from NetStreamPlay.Start (start >= 0)
+	NetStreamPlayStartModeRecorded
+)
+
+func (m NetStreamPlayStartMode) String() string {
+	switch m {
+	case NetStreamPlayStartModeLiveOrRecorded:
+		return "LiveOrRecorded"
+	case NetStreamPlayStartModeLive:
+		return "Live"
+	case NetStreamPlayStartModeRecorded:
+		return "Recorded"
+	default:
+		return "<Unknown>"
+	}
+}
+
+const (
+	netStreamPlayStartLiveOrRecorded = -2
+	netStreamPlayStartLive           = -1
+	netStreamPlayDurationUntilEnd    = -1
+)
+
+// NetStreamPlay "play". Duration and Reset are optional arguments, and they are used only if HasDuration and HasReset
+// are true respectively, thus arguments are reconstructed by ToArgs as they were.
 type NetStreamPlay struct {
 	CommandObject interface{}
 	StreamName    string
-	Start         int64
+	StartMode     NetStreamPlayStartMode
+	Start         float64 // An offset in seconds of a recorded stream. Used if StartMode is NetStreamPlayStartModeRecorded
+	Duration      float64 // Seconds to play. Negative values (-1) play until the end, and 0 plays a single frame
+	HasDuration   bool
+	Reset         bool // Whether to flush a previous playlist
+	HasReset      bool
 }
 
 func (t *NetStreamPlay) FromArgs(args ...interface{}) error {
 	//command := args[0] // will be nil
 	t.StreamName = args[1].(string)
-	t.Start = args[2].(int64)
+
+	// Optional arguments may be omitted or null
+	if len(args) > 2 && args[2] != nil {
+		start, err := netStreamPlayNumber(args[2])
+		if err != nil {
+			return errors.Wrap(err, "Start is invalid")
+		}
+
+		switch {
+		case start == netStreamPlayStartLive:
+			t.StartMode = NetStreamPlayStartModeLive
+		case start < 0:
+			t.StartMode = NetStreamPlayStartModeLiveOrRecorded
+		default:
+			t.StartMode = NetStreamPlayStartModeRecorded
+			t.Start = start
+		}
+	}
+
+	if len(args) > 3 && args[3] != nil {
+		duration, err := netStreamPlayNumber(args[3])
+		if err != nil {
+			return errors.Wrap(err, "Duration is invalid")
+		}
+		t.Duration = duration
+		t.HasDuration = true
+	}
+
+	if len(args) > 4 && args[4] != nil {
+		switch reset := args[4].(type) {
+		case bool:
+			t.Reset = reset
+		default:
+			// Numbers are also used: 0 and 2 do not flush, 1 and 3 flush a playlist
+			n, err := netStreamPlayNumber(reset)
+			if err != nil {
+				return errors.Wrap(err, "Reset is invalid")
+			}
+			t.Reset = n == 1 || n == 3
+		}
+		t.HasReset = true
+	}
 
 	return nil
 }
 
 func (t *NetStreamPlay) ToArgs(ty EncodingType) ([]interface{}, error) {
-	panic("Not implemented")
+	var start float64
+	switch t.StartMode {
+	case NetStreamPlayStartModeLiveOrRecorded:
+		start = netStreamPlayStartLiveOrRecorded
+	case NetStreamPlayStartModeLive:
+		start = netStreamPlayStartLive
+	case NetStreamPlayStartModeRecorded:
+		start = t.Start
+	default:
+		return nil, errors.Errorf("Unknown start mode: %d", t.StartMode)
+	}
+
+	args := []interface{}{
+		nil, // Always nil
+		t.StreamName,
+		start,
+	}
+	if !t.HasDuration && !t.HasReset {
+		return args, nil // Optional arguments are omitted
+	}
+
+	duration := float64(netStreamPlayDurationUntilEnd)
+	if t.HasDuration {
+		duration = t.Duration
+	}
+	args = append(args, duration)
+
+	if !t.HasReset {
+		return args, nil
+	}
+
+	return append(args, t.Reset), nil
+}
+
+// PlaysUntilEnd Returns true if the stream should be played until the end.
+func (t *NetStreamPlay) PlaysUntilEnd() bool {
+	return !t.HasDuration || t.Duration < 0
+}
+
+// ShouldReset Returns true if a previous playlist should be flushed. It is true if reset is not specified.
+func (t *NetStreamPlay) ShouldReset() bool {
+	return !t.HasReset || t.Reset
+}
+
+// netStreamPlayNumber Converts a number of AMF to float64. Integers are also accepted for values made by users.
+func netStreamPlayNumber(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int32: // AMF3 integer
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	default:
+		return 0, errors.Errorf("Not a number: Value = %#v", v)
+	}
+}
+
+// NetStreamPause "pause" which pauses or resumes playing. Milliseconds is the position where the stream is paused or resumed.
+type NetStreamPause struct {
+	CommandObject interface{}
+	Pause         bool
+	Milliseconds  int64
+}
+
+func (t *NetStreamPause) FromArgs(args ...interface{}) error {
+	//command := args[0] // will be nil
+	pause, ok := args[1].(bool)
+	if !ok {
+		return errors.Errorf("Pause flag is not a boolean: Value = %#v", args[1])
+	}
+	t.Pause = pause
+	t.Milliseconds = args[2].(int64)
+
+	return nil
+}
+
+func (t *NetStreamPause) ToArgs(ty EncodingType) ([]interface{}, error) {
+	return []interface{}{
+		nil, // Always nil
+		t.Pause,
+		t.Milliseconds,
+	}, nil
+}
+
+// NetStreamSeek "seek" which seeks to the position in milliseconds.
+type NetStreamSeek struct {
+	CommandObject interface{}
+	Milliseconds  int64
+}
+
+func (t *NetStreamSeek) FromArgs(args ...interface{}) error {
+	//command := args[0] // will be nil
+	t.Milliseconds = args[1].(int64)
+
+	return nil
+}
+
+func (t *NetStreamSeek) ToArgs(ty EncodingType) ([]interface{}, error) {
+	return []interface{}{
+		nil, // Always nil
+		t.Milliseconds,
+	}, nil
+}
+
+// NetStreamReceiveAudio "receiveAudio" which tells whether the player receives audio or not.
+type NetStreamReceiveAudio struct {
+	CommandObject interface{}
+	Receive       bool
+}
+
+func (t *NetStreamReceiveAudio) FromArgs(args ...interface{}) error {
+	//command := args[0] // will be nil
+	receive, ok := args[1].(bool)
+	if !ok {
+		return errors.Errorf("Receive flag is not a boolean: Value = %#v", args[1])
+	}
+	t.Receive = receive
+
+	return nil
+}
+
+func (t *NetStreamReceiveAudio) ToArgs(ty EncodingType) ([]interface{}, error) {
+	return []interface{}{
+		nil, // Always nil
+		t.Receive,
+	}, nil
+}
+
+// NetStreamReceiveVideo "receiveVideo" which tells whether the player receives video or not.
+type NetStreamReceiveVideo struct {
+	CommandObject interface{}
+	Receive       bool
+}
+
+func (t *NetStreamReceiveVideo) FromArgs(args ...interface{}) error {
+	//command := args[0] // will be nil
+	receive, ok := args[1].(bool)
+	if !ok {
+		return errors.Errorf("Receive flag is not a boolean: Value = %#v", args[1])
+	}
+	t.Receive = receive
+
+	return nil
+}
+
+func (t *NetStreamReceiveVideo) ToArgs(ty EncodingType) ([]interface{}, error) {
+	return []interface{}{
+		nil, // Always nil
+		t.Receive,
+	}, nil
 }
 
 type NetStreamOnStatusLevel string
 
 const (
-	NetStreamOnStatusLevelStatus NetStreamOnStatusLevel = "status"
-	NetStreamOnStatusLevelError  NetStreamOnStatusLevel = "error"
+	NetStreamOnStatusLevelStatus  NetStreamOnStatusLevel = "status"
+	NetStreamOnStatusLevelWarning NetStreamOnStatusLevel = "warning"
+	NetStreamOnStatusLevelError   NetStreamOnStatusLevel = "error"
 )
 
 type NetStreamOnStatusCode string
 
 const (
+	NetStreamOnStatusCodeFailed              NetStreamOnStatusCode = "NetStream.Failed"
 	NetStreamOnStatusCodeConnectSuccess      NetStreamOnStatusCode = "NetStream.Connect.Success"
 	NetStreamOnStatusCodeConnectFailed       NetStreamOnStatusCode = "NetStream.Connect.Failed"
 	NetStreamOnStatusCodeMuticastStreamReset NetStreamOnStatusCode = "NetStream.MulticastStream.Reset"
+	NetStreamOnStatusCodePlayReset           NetStreamOnStatusCode = "NetStream.Play.Reset"
 	NetStreamOnStatusCodePlayStart           NetStreamOnStatusCode = "NetStream.Play.Start"
+	NetStreamOnStatusCodePlayStop            NetStreamOnStatusCode = "NetStream.Play.Stop"
 	NetStreamOnStatusCodePlayFailed          NetStreamOnStatusCode = "NetStream.Play.Failed"
 	NetStreamOnStatusCodePlayComplete        NetStreamOnStatusCode = "NetStream.Play.Complete"
+	NetStreamOnStatusCodePlayStreamNotFound  NetStreamOnStatusCode = "NetStream.Play.StreamNotFound"
+	NetStreamOnStatusCodePlayInsufficientBW  NetStreamOnStatusCode = "NetStream.Play.InsufficientBW"
+	NetStreamOnStatusCodePlayPublishNotify   NetStreamOnStatusCode = "NetStream.Play.PublishNotify"
+	NetStreamOnStatusCodePlayUnpublishNotify NetStreamOnStatusCode = "NetStream.Play.UnpublishNotify"
 	NetStreamOnStatusCodePublishBadName      NetStreamOnStatusCode = "NetStream.Publish.BadName"
 	NetStreamOnStatusCodePublishFailed       NetStreamOnStatusCode = "NetStream.Publish.Failed"
 	NetStreamOnStatusCodePublishStart        NetStreamOnStatusCode = "NetStream.Publish.Start"
 	NetStreamOnStatusCodeUnpublishSuccess    NetStreamOnStatusCode = "NetStream.Unpublish.Success"
+	NetStreamOnStatusCodePauseNotify         NetStreamOnStatusCode = "NetStream.Pause.Notify"
+	NetStreamOnStatusCodeUnpauseNotify       NetStreamOnStatusCode = "NetStream.Unpause.Notify"
+	NetStreamOnStatusCodeSeekNotify          NetStreamOnStatusCode = "NetStream.Seek.Notify"
+	NetStreamOnStatusCodeSeekFailed          NetStreamOnStatusCode = "NetStream.Seek.Failed"
 )
 
 type NetStreamOnStatus struct {
@@ -80,7 +329,16 @@ type NetStreamOnStatusInfoObject struct {
 }
 
 func (t *NetStreamOnStatus) FromArgs(args ...interface{}) error {
-	panic("Not implemented")
+	// args[0] is nil
+	info, ok := args[1].(map[string]interface{})
+	if !ok {
+		return errors.Errorf("Info object is not an object: Value = %#v", args[1])
+	}
+	if err := mapstructure.Decode(info, &t.InfoObject); err != nil {
+		return errors.Wrapf(err, "Failed to mapping NetStreamOnStatusInfoObject")
+	}
+
+	return nil
 }
 
 func (t *NetStreamOnStatus) ToArgs(ty EncodingType) ([]interface{}, error) {
@@ -185,6 +443,61 @@ func (t *NetStreamSetDataFrame) ToArgs(ty EncodingType) ([]interface{}, error) {
 	}, nil
 }
 
+// NetStreamOnMetaData Metadata of a stream which is sent to players.
+type NetStreamOnMetaData struct {
+	MetaData map[string]interface{}
+}
+
+func (t *NetStreamOnMetaData) FromArgs(args ...interface{}) error {
+	metaData, ok := args[0].(map[string]interface{})
+	if !ok {
+		return errors.Errorf("MetaData is not an object: Value = %#v", args[0])
+	}
+	t.MetaData = metaData
+
+	return nil
+}
+
+func (t *NetStreamOnMetaData) ToArgs(ty EncodingType) ([]interface{}, error) {
+	if ty == EncodingTypeAMF0 {
+		return []interface{}{
+			amf0.ECMAArray(t.MetaData),
+		}, nil
+	}
+
+	return []interface{}{
+		t.MetaData,
+	}, nil
+}
+
+// NetStreamRtmpSampleAccess "|RtmpSampleAccess" which allows players to access raw audio and video data.
+type NetStreamRtmpSampleAccess struct {
+	AudioSampleAccess bool
+	VideoSampleAccess bool
+}
+
+func (t *NetStreamRtmpSampleAccess) FromArgs(args ...interface{}) error {
+	audio, ok := args[0].(bool)
+	if !ok {
+		return errors.Errorf("AudioSampleAccess is not a boolean: Value = %#v", args[0])
+	}
+	video, ok := args[1].(bool)
+	if !ok {
+		return errors.Errorf("VideoSampleAccess is not a boolean: Value = %#v", args[1])
+	}
+	t.AudioSampleAccess = audio
+	t.VideoSampleAccess = video
+
+	return nil
+}
+
+func (t *NetStreamRtmpSampleAccess) ToArgs(ty EncodingType) ([]interface{}, error) {
+	return []interface{}{
+		t.AudioSampleAccess,
+		t.VideoSampleAccess,
+	}, nil
+}
+
 type NetStreamGetStreamLength struct {
 	StreamName string
 }
@@ -35,6 +35,83 @@ var netStreamTestCases = []netStreamTestCase{
 			PublishingType: "bbb",
 		},
 	},
+	{
+		Name: "NetStreamPlay OK",
+		Box:  &NetStreamPlay{},
+		Args: []interface{}{nil, "theStream", float64(-2)},
+		ExpectedMsg: &NetStreamPlay{
+			StreamName: "theStream",
+			StartMode:  NetStreamPlayStartModeLiveOrRecorded,
+		},
+	},
+	{
+		Name: "NetStreamPlay live only OK",
+		Box:  &NetStreamPlay{},
+		Args: []interface{}{nil, "theStream", float64(-1)},
+		ExpectedMsg: &NetStreamPlay{
+			StreamName: "theStream",
+			StartMode:  NetStreamPlayStartModeLive,
+		},
+	},
+	{
+		Name: "NetStreamPlay with duration and reset OK",
+		Box:  &NetStreamPlay{},
+		Args: []interface{}{nil, "theStream", float64(0), float64(10), true},
+		ExpectedMsg: &NetStreamPlay{
+			StreamName:  "theStream",
+			StartMode:   NetStreamPlayStartModeRecorded,
+			Start:       0,
+			Duration:    10,
+			HasDuration: true,
+			Reset:       true,
+			HasReset:    true,
+		},
+	},
+	{
+		Name: "NetStreamPlay with duration OK",
+		Box:  &NetStreamPlay{},
+		Args: []interface{}{nil, "theStream", float64(2.5), float64(0)},
+		ExpectedMsg: &NetStreamPlay{
+			StreamName:  "theStream",
+			StartMode:   NetStreamPlayStartModeRecorded,
+			Start:       2.5,
+			Duration:    0,
+			HasDuration: true,
+		},
+	},
+	{
+		Name: "NetStreamPause OK",
+		Box:  &NetStreamPause{},
+		Args: []interface{}{nil, true, int64(1000)},
+		ExpectedMsg: &NetStreamPause{
+			Pause:        true,
+			Milliseconds: 1000,
+		},
+	},
+	{
+		Name: "NetStreamSeek OK",
+		Box:  &NetStreamSeek{},
+		Args: []interface{}{nil, int64(1000)},
+		ExpectedMsg: &NetStreamSeek{
+			Milliseconds: 1000,
+		},
+	},
+	{
+		Name: "NetStreamReceiveAudio OK",
+		Box:  &NetStreamReceiveAudio{},
+		Args: []interface{}{nil, true},
+		ExpectedMsg: &NetStreamReceiveAudio{
+			Receive: true,
+		},
+	},
+	{
+		Name: "NetStreamReceiveVideo OK",
+		Box:  &NetStreamReceiveVideo{},
+		Args: []interface{}{nil, false},
+		ExpectedMsg: &NetStreamReceiveVideo{
+			Receive: false,
+		},
+	},
 	{
 		Name: "NetStreamReleaseStream OK",
 		Box:  &NetStreamReleaseStream{},
@@ -80,3 +157,16 @@ func TestConvertNetStreamMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestNetStreamPlayWithInvalidStart(t *testing.T) {
+	var play NetStreamPlay
+	err := play.FromArgs(nil, "theStream", "0")
+	require.EqualError(t, err, `Start is invalid: Not a number: Value = "0"`)
+}
+
+func TestNetStreamPlayStartModeString(t *testing.T) {
+	require.Equal(t, "LiveOrRecorded", NetStreamPlayStartModeLiveOrRecorded.String())
+	require.Equal(t, "Live", NetStreamPlayStartModeLive.String())
+	require.Equal(t, "Recorded", NetStreamPlayStartModeRecorded.String())
+	require.Equal(t, "<Unknown>", NetStreamPlayStartMode(42).String())
+}
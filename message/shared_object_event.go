@@ -0,0 +1,84 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+// sharedObjectPersistentFlag A value of flags which means the shared object is persistent
+const sharedObjectPersistentFlag = 2
+
+type SharedObjectEventType uint8
+
+const (
+	SharedObjectEventTypeUse           SharedObjectEventType = 1
+	SharedObjectEventTypeRelease       SharedObjectEventType = 2
+	SharedObjectEventTypeRequestChange SharedObjectEventType = 3
+	SharedObjectEventTypeChange        SharedObjectEventType = 4
+	SharedObjectEventTypeSuccess       SharedObjectEventType = 5
+	SharedObjectEventTypeSendMessage   SharedObjectEventType = 6
+	SharedObjectEventTypeStatus        SharedObjectEventType = 7
+	SharedObjectEventTypeClear         SharedObjectEventType = 8
+	SharedObjectEventTypeRemove        SharedObjectEventType = 9
+	SharedObjectEventTypeRequestRemove SharedObjectEventType = 10
+	SharedObjectEventTypeUseSuccess    SharedObjectEventType = 11
+)
+
+type SharedObjectEvent interface{}
+
+// SharedObjectEventUse (1) client -> server
+type SharedObjectEventUse struct {
+}
+
+// SharedObjectEventRelease (2) client -> server
+type SharedObjectEventRelease struct {
+}
+
+// SharedObjectEventRequestChange (3) client -> server
+type SharedObjectEventRequestChange struct {
+	Name  string
+	Value interface{}
+}
+
+// SharedObjectEventChange (4) server -> client
+type SharedObjectEventChange struct {
+	Name  string
+	Value interface{}
+}
+
+// SharedObjectEventSuccess (5) server -> client
+type SharedObjectEventSuccess struct {
+	Name string
+}
+
+// SharedObjectEventSendMessage (6) client <-> server
+type SharedObjectEventSendMessage struct {
+	Method string
+	Args   []interface{}
+}
+
+// SharedObjectEventStatus (7) server -> client
+type SharedObjectEventStatus struct {
+	Code  string
+	Level string
+}
+
+// SharedObjectEventClear (8) server -> client
+type SharedObjectEventClear struct {
+}
+
+// SharedObjectEventRemove (9) server -> client
+type SharedObjectEventRemove struct {
+	Name string
+}
+
+// SharedObjectEventRequestRemove (10) client -> server
+type SharedObjectEventRequestRemove struct {
+	Name string
+}
+
+// SharedObjectEventUseSuccess (11) server -> client
+type SharedObjectEventUseSuccess struct {
+}
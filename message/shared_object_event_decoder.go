@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+type SharedObjectEventDecoder struct {
+	r     io.Reader
+	encTy EncodingType
+
+	// An event data of Change/RequestChange may have multiple properties. They are decoded as separated events.
+	pending []SharedObjectEvent
+}
+
+func NewSharedObjectEventDecoder(r io.Reader, encTy EncodingType) *SharedObjectEventDecoder {
+	return &SharedObjectEventDecoder{
+		r:     r,
+		encTy: encTy,
+	}
+}
+
+// Decode Decodes an event. Returns io.EOF if there are no more events.
+func (dec *SharedObjectEventDecoder) Decode(event *SharedObjectEvent) error {
+	if len(dec.pending) > 0 {
+		*event = dec.pending[0]
+		dec.pending = dec.pending[1:]
+		return nil
+	}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(dec.r, buf[:1]); err != nil {
+		return err // io.EOF if there are no more events
+	}
+	eventType := SharedObjectEventType(buf[0])
+
+	if _, err := io.ReadFull(dec.r, buf); err != nil {
+		return wrapEOF(err)
+	}
+	length := binary.BigEndian.Uint32(buf)
+
+	data, err := ioutil.ReadAll(io.LimitReader(dec.r, int64(length)))
+	if err != nil {
+		return err
+	}
+	if len(data) != int(length) {
+		return io.ErrUnexpectedEOF
+	}
+	r := bytes.NewReader(data)
+
+	switch eventType {
+	case SharedObjectEventTypeUse:
+		*event = &SharedObjectEventUse{}
+	case SharedObjectEventTypeRelease:
+		*event = &SharedObjectEventRelease{}
+	case SharedObjectEventTypeRequestChange, SharedObjectEventTypeChange:
+		return dec.decodeChanges(r, eventType, event)
+	case SharedObjectEventTypeSuccess:
+		name, err := readShortString(r)
+		if err != nil {
+			return err
+		}
+		*event = &SharedObjectEventSuccess{Name: name}
+	case SharedObjectEventTypeSendMessage:
+		return dec.decodeSendMessage(r, event)
+	case SharedObjectEventTypeStatus:
+		code, err := readShortString(r)
+		if err != nil {
+			return err
+		}
+		level, err := readShortString(r)
+		if err != nil {
+			return err
+		}
+		*event = &SharedObjectEventStatus{Code: code, Level: level}
+	case SharedObjectEventTypeClear:
+		*event = &SharedObjectEventClear{}
+	case SharedObjectEventTypeRemove:
+		name, err := readShortString(r)
+		if err != nil {
+			return err
+		}
+		*event = &SharedObjectEventRemove{Name: name}
+	case SharedObjectEventTypeRequestRemove:
+		name, err := readShortString(r)
+		if err != nil {
+			return err
+		}
+		*event = &SharedObjectEventRequestRemove{Name: name}
+	case SharedObjectEventTypeUseSuccess:
+		*event = &SharedObjectEventUseSuccess{}
+	default:
+		return errors.Errorf("Unsupported type for SharedObjectEvent: TypeID = %d", eventType)
+	}
+
+	return nil
+}
+
+func (dec *SharedObjectEventDecoder) decodeChanges(
+	r *bytes.Reader,
+	eventType SharedObjectEventType,
+	event *SharedObjectEvent,
+) error {
+	d := NewAMFDecoder(r, dec.encTy)
+
+	var events []SharedObjectEvent
+	for r.Len() > 0 {
+		name, err := readShortString(r)
+		if err != nil {
+			return err
+		}
+
+		var value interface{}
+		if err := d.Decode(&value); err != nil {
+			return errors.Wrapf(err, "Failed to decode a value of the property: Name = %s", name)
+		}
+
+		if eventType == SharedObjectEventTypeRequestChange {
+			events = append(events, &SharedObjectEventRequestChange{Name: name, Value: value})
+		} else {
+			events = append(events, &SharedObjectEventChange{Name: name, Value: value})
+		}
+	}
+	if len(events) == 0 {
+		return errors.Errorf("No properties in the event: TypeID = %d", eventType)
+	}
+
+	*event = events[0]
+	dec.pending = append(dec.pending, events[1:]...)
+
+	return nil
+}
+
+func (dec *SharedObjectEventDecoder) decodeSendMessage(r *bytes.Reader, event *SharedObjectEvent) error {
+	d := NewAMFDecoder(r, dec.encTy)
+
+	var method string
+	if err := d.Decode(&method); err != nil {
+		return errors.Wrap(err, "Failed to decode a method name")
+	}
+
+	var args []interface{}
+	for r.Len() > 0 {
+		var arg interface{}
+		if err := d.Decode(&arg); err != nil {
+			return errors.Wrapf(err, "Failed to decode args[%d]", len(args))
+		}
+		args = append(args, arg)
+	}
+
+	*event = &SharedObjectEventSendMessage{
+		Method: method,
+		Args:   args,
+	}
+
+	return nil
+}
+
+// readShortString Reads a string which has a 16bits length prefix (without AMF markers)
+func readShortString(r io.Reader) (string, error) {
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", wrapEOF(err)
+	}
+
+	str := make([]byte, binary.BigEndian.Uint16(buf))
+	if _, err := io.ReadFull(r, str); err != nil {
+		return "", wrapEOF(err)
+	}
+
+	return string(str), nil
+}
+
+func wrapEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
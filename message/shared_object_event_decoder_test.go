@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedObjectEventRoundTrip(t *testing.T) {
+	events := []SharedObjectEvent{
+		&SharedObjectEventUse{},
+		&SharedObjectEventRelease{},
+		&SharedObjectEventRequestChange{Name: "a", Value: "v"},
+		&SharedObjectEventChange{Name: "b", Value: true},
+		&SharedObjectEventSuccess{Name: "a"},
+		&SharedObjectEventSendMessage{Method: "m", Args: []interface{}{float64(1), nil}},
+		&SharedObjectEventStatus{Code: "SharedObject.BadPersistence", Level: "error"},
+		&SharedObjectEventClear{},
+		&SharedObjectEventRemove{Name: "a"},
+		&SharedObjectEventRequestRemove{Name: "b"},
+		&SharedObjectEventUseSuccess{},
+	}
+
+	buf := new(bytes.Buffer)
+	enc := NewSharedObjectEventEncoder(buf, EncodingTypeAMF0)
+	for _, event := range events {
+		require.Nil(t, enc.Encode(event))
+	}
+
+	dec := NewSharedObjectEventDecoder(buf, EncodingTypeAMF0)
+	for _, expected := range events {
+		var event SharedObjectEvent
+		require.Nil(t, dec.Decode(&event))
+		require.Equal(t, expected, event)
+	}
+
+	var event SharedObjectEvent
+	require.Equal(t, io.EOF, dec.Decode(&event))
+}
+
+func TestSharedObjectEventDecodeMultipleChanges(t *testing.T) {
+	bin := []byte{
+		// Event: Change, Length 12
+		0x04, 0x00, 0x00, 0x00, 0x0c,
+		// a: AMF0 / true
+		0x00, 0x01, 0x61, 0x01, 0x01,
+		// b: AMF0 / "c" string
+		0x00, 0x01, 0x62, 0x02, 0x00, 0x01, 0x63,
+	}
+	dec := NewSharedObjectEventDecoder(bytes.NewReader(bin), EncodingTypeAMF0)
+
+	var event SharedObjectEvent
+	require.Nil(t, dec.Decode(&event))
+	require.Equal(t, &SharedObjectEventChange{Name: "a", Value: true}, event)
+	require.Nil(t, dec.Decode(&event))
+	require.Equal(t, &SharedObjectEventChange{Name: "b", Value: "c"}, event)
+	require.Equal(t, io.EOF, dec.Decode(&event))
+}
@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type SharedObjectEventEncoder struct {
+	w     io.Writer
+	encTy EncodingType
+}
+
+func NewSharedObjectEventEncoder(w io.Writer, encTy EncodingType) *SharedObjectEventEncoder {
+	return &SharedObjectEventEncoder{
+		w:     w,
+		encTy: encTy,
+	}
+}
+
+func (enc *SharedObjectEventEncoder) Encode(event SharedObjectEvent) error {
+	data := new(bytes.Buffer)
+
+	var eventType SharedObjectEventType
+	switch event := event.(type) {
+	case *SharedObjectEventUse:
+		eventType = SharedObjectEventTypeUse
+	case *SharedObjectEventRelease:
+		eventType = SharedObjectEventTypeRelease
+	case *SharedObjectEventRequestChange:
+		eventType = SharedObjectEventTypeRequestChange
+		if err := enc.encodeProperty(data, event.Name, event.Value); err != nil {
+			return err
+		}
+	case *SharedObjectEventChange:
+		eventType = SharedObjectEventTypeChange
+		if err := enc.encodeProperty(data, event.Name, event.Value); err != nil {
+			return err
+		}
+	case *SharedObjectEventSuccess:
+		eventType = SharedObjectEventTypeSuccess
+		if err := writeShortString(data, event.Name); err != nil {
+			return err
+		}
+	case *SharedObjectEventSendMessage:
+		eventType = SharedObjectEventTypeSendMessage
+		if err := enc.encodeSendMessage(data, event); err != nil {
+			return err
+		}
+	case *SharedObjectEventStatus:
+		eventType = SharedObjectEventTypeStatus
+		if err := writeShortString(data, event.Code); err != nil {
+			return err
+		}
+		if err := writeShortString(data, event.Level); err != nil {
+			return err
+		}
+	case *SharedObjectEventClear:
+		eventType = SharedObjectEventTypeClear
+	case *SharedObjectEventRemove:
+		eventType = SharedObjectEventTypeRemove
+		if err := writeShortString(data, event.Name); err != nil {
+			return err
+		}
+	case *SharedObjectEventRequestRemove:
+		eventType = SharedObjectEventTypeRequestRemove
+		if err := writeShortString(data, event.Name); err != nil {
+			return err
+		}
+	case *SharedObjectEventUseSuccess:
+		eventType = SharedObjectEventTypeUseSuccess
+	default:
+		return errors.Errorf("Unsupported type for SharedObjectEvent: Type = %T", event)
+	}
+
+	buf := make([]byte, 1+4)
+	buf[0] = byte(eventType)                                 // [0:1]: type
+	binary.BigEndian.PutUint32(buf[1:5], uint32(data.Len())) // [1:5]: length of data
+	if _, err := enc.w.Write(buf); err != nil {
+		return err
+	}
+
+	_, err := data.WriteTo(enc.w)
+	return err
+}
+
+func (enc *SharedObjectEventEncoder) encodeProperty(w io.Writer, name string, value interface{}) error {
+	if err := writeShortString(w, name); err != nil {
+		return err
+	}
+
+	return NewAMFEncoder(w, enc.encTy).Encode(value)
+}
+
+func (enc *SharedObjectEventEncoder) encodeSendMessage(w io.Writer, event *SharedObjectEventSendMessage) error {
+	e := NewAMFEncoder(w, enc.encTy)
+	if err := e.Encode(event.Method); err != nil {
+		return err
+	}
+
+	for _, arg := range event.Args {
+		if err := e.Encode(arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeShortString Writes a string which has a 16bits length prefix (without AMF markers)
+func writeShortString(w io.Writer, str string) error {
+	if len(str) > 0xffff {
+		return errors.Errorf("String is too long: Length = %d", len(str))
+	}
+
+	buf := make([]byte, 2+len(str))
+	binary.BigEndian.PutUint16(buf[0:2], uint16(len(str)))
+	copy(buf[2:], str)
+
+	_, err := w.Write(buf)
+	return err
+}
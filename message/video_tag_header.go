@@ -0,0 +1,190 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type VideoFrameType uint8
+
+const (
+	VideoFrameTypeKeyFrame             VideoFrameType = 1
+	VideoFrameTypeInterFrame           VideoFrameType = 2
+	VideoFrameTypeDisposableInterFrame VideoFrameType = 3
+	VideoFrameTypeGeneratedKeyFrame    VideoFrameType = 4
+	VideoFrameTypeCommandFrame         VideoFrameType = 5
+)
+
+// VideoCodecID A legacy codec ID of FLV video tags.
+type VideoCodecID uint8
+
+const (
+	VideoCodecIDSorensonH263  VideoCodecID = 2
+	VideoCodecIDScreenVideo   VideoCodecID = 3
+	VideoCodecIDOn2VP6        VideoCodecID = 4
+	VideoCodecIDOn2VP6Alpha   VideoCodecID = 5
+	VideoCodecIDScreenVideoV2 VideoCodecID = 6
+	VideoCodecIDAVC           VideoCodecID = 7
+)
+
+type AVCPacketType uint8
+
+const (
+	AVCPacketTypeSequenceHeader AVCPacketType = 0
+	AVCPacketTypeNALU           AVCPacketType = 1
+	AVCPacketTypeEndOfSequence  AVCPacketType = 2
+)
+
+// VideoPacketType A packet type of ExVideoTagHeader (Enhanced RTMP).
+type VideoPacketType uint8
+
+const (
+	VideoPacketTypeSequenceStart        VideoPacketType = 0
+	VideoPacketTypeCodedFrames          VideoPacketType = 1
+	VideoPacketTypeSequenceEnd          VideoPacketType = 2
+	VideoPacketTypeCodedFramesX         VideoPacketType = 3 // CodedFrames without CompositionTime
+	VideoPacketTypeMetadata             VideoPacketType = 4
+	VideoPacketTypeMPEG2TSSequenceStart VideoPacketType = 5
+	VideoPacketTypeMultitrack           VideoPacketType = 6
+)
+
+// VideoTagHeader A header of payloads of VideoMessage. It is either a legacy VideoTagHeader or an ExVideoTagHeader.
+type VideoTagHeader struct {
+	FrameType  VideoFrameType
+	IsExHeader bool
+
+	// Legacy
+	CodecID       VideoCodecID
+	AVCPacketType AVCPacketType // Only for AVC
+
+	// Enhanced RTMP
+	PacketType     VideoPacketType // A packet type of tracks if IsMultitrack
+	FourCC         FourCC          // Empty if MultitrackType is ManyTracksManyCodecs
+	IsMultitrack   bool
+	MultitrackType MultitrackType
+
+	CompositionTime int32 // Only for AVC NALU and HEVC CodedFrames. It is in bodies of each tracks if IsMultitrack
+}
+
+// IsSequenceHeader Returns true if the body is a decoder configuration record.
+func (h *VideoTagHeader) IsSequenceHeader() bool {
+	if h.IsExHeader {
+		return h.PacketType == VideoPacketTypeSequenceStart
+	}
+	return h.CodecID == VideoCodecIDAVC && h.AVCPacketType == AVCPacketTypeSequenceHeader
+}
+
+// IsKeyFrame Returns true if the body is a key frame.
+func (h *VideoTagHeader) IsKeyFrame() bool {
+	return h.FrameType == VideoFrameTypeKeyFrame || h.FrameType == VideoFrameTypeGeneratedKeyFrame
+}
+
+func (h *VideoTagHeader) hasCompositionTime() bool {
+	if h.IsExHeader {
+		return !h.IsMultitrack && h.FourCC == FourCCHEVC && h.PacketType == VideoPacketTypeCodedFrames
+	}
+	return h.CodecID == VideoCodecIDAVC
+}
+
+// DecodeVideoTagHeader Reads a header from a payload of VideoMessage. Subsequent data of r is a body of the video,
+// or tracks which can be read by DecodeMultitrackTracks if IsMultitrack.
+func DecodeVideoTagHeader(r io.Reader, h *VideoTagHeader) error {
+	buf := make([]byte, 5)
+	if _, err := io.ReadAtLeast(r, buf[:1], 1); err != nil {
+		return err
+	}
+
+	*h = VideoTagHeader{
+		IsExHeader: buf[0]&0x80 != 0,
+	}
+	if h.IsExHeader {
+		h.FrameType = VideoFrameType((buf[0] >> 4) & 0x07)
+		h.PacketType = VideoPacketType(buf[0] & 0x0f)
+		if h.PacketType == VideoPacketTypeMultitrack {
+			if _, err := io.ReadAtLeast(r, buf[:1], 1); err != nil {
+				return err
+			}
+			h.IsMultitrack = true
+			h.MultitrackType = MultitrackType(buf[0] >> 4)
+			h.PacketType = VideoPacketType(buf[0] & 0x0f)
+
+			if h.MultitrackType > MultitrackTypeManyTracksManyCodecs {
+				return errors.Errorf("Unsupported multitrack type: MultitrackType = %d", h.MultitrackType)
+			}
+			if h.PacketType == VideoPacketTypeMultitrack {
+				return errors.New("Nested multitrack is not allowed")
+			}
+		}
+		if h.PacketType > VideoPacketTypeMultitrack {
+			return errors.Errorf("Unsupported packet type of ExVideoTagHeader: PacketType = %d", h.PacketType)
+		}
+
+		if !h.IsMultitrack || h.MultitrackType != MultitrackTypeManyTracksManyCodecs {
+			if _, err := io.ReadAtLeast(r, buf[:4], 4); err != nil {
+				return err
+			}
+			h.FourCC = FourCC(buf[:4])
+		}
+	} else {
+		h.FrameType = VideoFrameType(buf[0] >> 4)
+		h.CodecID = VideoCodecID(buf[0] & 0x0f)
+
+		if h.CodecID == VideoCodecIDAVC {
+			if _, err := io.ReadAtLeast(r, buf[:1], 1); err != nil {
+				return err
+			}
+			h.AVCPacketType = AVCPacketType(buf[0])
+		}
+	}
+
+	if h.hasCompositionTime() {
+		if _, err := io.ReadAtLeast(r, buf[:3], 3); err != nil {
+			return err
+		}
+		// SI24
+		h.CompositionTime = int32(uint32(buf[0])<<24|uint32(buf[1])<<16|uint32(buf[2])<<8) >> 8
+	}
+
+	return nil
+}
+
+// EncodeVideoTagHeader Writes a header of a payload of VideoMessage. A body of the video should be written after this.
+func EncodeVideoTagHeader(w io.Writer, h *VideoTagHeader) error {
+	buf := make([]byte, 0, 8)
+	if h.IsExHeader {
+		if h.IsMultitrack {
+			buf = append(buf, 0x80|byte(h.FrameType&0x07)<<4|byte(VideoPacketTypeMultitrack))
+			buf = append(buf, byte(h.MultitrackType)<<4|byte(h.PacketType&0x0f))
+		} else {
+			buf = append(buf, 0x80|byte(h.FrameType&0x07)<<4|byte(h.PacketType&0x0f))
+		}
+
+		if !h.IsMultitrack || h.MultitrackType != MultitrackTypeManyTracksManyCodecs {
+			if len(h.FourCC) != 4 {
+				return errors.Errorf("FourCC must be 4 characters: FourCC = %s", h.FourCC)
+			}
+			buf = append(buf, h.FourCC...)
+		}
+	} else {
+		buf = append(buf, byte(h.FrameType)<<4|byte(h.CodecID&0x0f))
+		if h.CodecID == VideoCodecIDAVC {
+			buf = append(buf, byte(h.AVCPacketType))
+		}
+	}
+
+	if h.hasCompositionTime() {
+		ct := uint32(h.CompositionTime)
+		buf = append(buf, byte(ct>>16), byte(ct>>8), byte(ct))
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,203 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package message
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type videoTagHeaderTestCase struct {
+	Name   string
+	Header VideoTagHeader
+	Binary []byte
+}
+
+var videoTagHeaderTestCases = []videoTagHeaderTestCase{
+	{
+		Name: "AVC sequence header",
+		Header: VideoTagHeader{
+			FrameType:     VideoFrameTypeKeyFrame,
+			CodecID:       VideoCodecIDAVC,
+			AVCPacketType: AVCPacketTypeSequenceHeader,
+		},
+		Binary: []byte{0x17, 0x00, 0x00, 0x00, 0x00},
+	},
+	{
+		Name: "AVC NALU with negative composition time",
+		Header: VideoTagHeader{
+			FrameType:       VideoFrameTypeInterFrame,
+			CodecID:         VideoCodecIDAVC,
+			AVCPacketType:   AVCPacketTypeNALU,
+			CompositionTime: -2,
+		},
+		Binary: []byte{0x27, 0x01, 0xff, 0xff, 0xfe},
+	},
+	{
+		Name: "Sorenson H.263",
+		Header: VideoTagHeader{
+			FrameType: VideoFrameTypeInterFrame,
+			CodecID:   VideoCodecIDSorensonH263,
+		},
+		Binary: []byte{0x22},
+	},
+	{
+		Name: "HEVC SequenceStart",
+		Header: VideoTagHeader{
+			FrameType:  VideoFrameTypeKeyFrame,
+			IsExHeader: true,
+			PacketType: VideoPacketTypeSequenceStart,
+			FourCC:     FourCCHEVC,
+		},
+		Binary: []byte{0x90, 'h', 'v', 'c', '1'},
+	},
+	{
+		Name: "HEVC CodedFrames",
+		Header: VideoTagHeader{
+			FrameType:       VideoFrameTypeKeyFrame,
+			IsExHeader:      true,
+			PacketType:      VideoPacketTypeCodedFrames,
+			FourCC:          FourCCHEVC,
+			CompositionTime: 0x10203,
+		},
+		Binary: []byte{0x91, 'h', 'v', 'c', '1', 0x01, 0x02, 0x03},
+	},
+	{
+		Name: "HEVC CodedFramesX",
+		Header: VideoTagHeader{
+			FrameType:  VideoFrameTypeInterFrame,
+			IsExHeader: true,
+			PacketType: VideoPacketTypeCodedFramesX,
+			FourCC:     FourCCHEVC,
+		},
+		Binary: []byte{0xa3, 'h', 'v', 'c', '1'},
+	},
+	{
+		Name: "AV1 CodedFrames",
+		Header: VideoTagHeader{
+			FrameType:  VideoFrameTypeInterFrame,
+			IsExHeader: true,
+			PacketType: VideoPacketTypeCodedFrames,
+			FourCC:     FourCCAV1,
+		},
+		Binary: []byte{0xa1, 'a', 'v', '0', '1'},
+	},
+	{
+		Name: "AV1 MPEG2TSSequenceStart",
+		Header: VideoTagHeader{
+			FrameType:  VideoFrameTypeKeyFrame,
+			IsExHeader: true,
+			PacketType: VideoPacketTypeMPEG2TSSequenceStart,
+			FourCC:     FourCCAV1,
+		},
+		Binary: []byte{0x95, 'a', 'v', '0', '1'},
+	},
+	{
+		Name: "VP9 SequenceEnd",
+		Header: VideoTagHeader{
+			FrameType:  VideoFrameTypeKeyFrame,
+			IsExHeader: true,
+			PacketType: VideoPacketTypeSequenceEnd,
+			FourCC:     FourCCVP9,
+		},
+		Binary: []byte{0x92, 'v', 'p', '0', '9'},
+	},
+	{
+		Name: "Metadata",
+		Header: VideoTagHeader{
+			FrameType:  VideoFrameTypeCommandFrame,
+			IsExHeader: true,
+			PacketType: VideoPacketTypeMetadata,
+			FourCC:     FourCCHEVC,
+		},
+		Binary: []byte{0xd4, 'h', 'v', 'c', '1'},
+	},
+	{
+		Name: "Multitrack HEVC CodedFrames",
+		Header: VideoTagHeader{
+			FrameType:      VideoFrameTypeKeyFrame,
+			IsExHeader:     true,
+			PacketType:     VideoPacketTypeCodedFrames,
+			FourCC:         FourCCHEVC,
+			IsMultitrack:   true,
+			MultitrackType: MultitrackTypeManyTracks,
+		},
+		Binary: []byte{0x96, 0x11, 'h', 'v', 'c', '1'},
+	},
+	{
+		Name: "Multitrack ManyTracksManyCodecs SequenceStart",
+		Header: VideoTagHeader{
+			FrameType:      VideoFrameTypeKeyFrame,
+			IsExHeader:     true,
+			PacketType:     VideoPacketTypeSequenceStart,
+			IsMultitrack:   true,
+			MultitrackType: MultitrackTypeManyTracksManyCodecs,
+		},
+		Binary: []byte{0x96, 0x20},
+	},
+}
+
+func TestDecodeVideoTagHeader(t *testing.T) {
+	for _, tc := range videoTagHeaderTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			r := bytes.NewReader(append(tc.Binary, "body"...))
+
+			var h VideoTagHeader
+			err := DecodeVideoTagHeader(r, &h)
+			require.Nil(t, err)
+			require.Equal(t, tc.Header, h)
+
+			body, err := ioutil.ReadAll(r)
+			require.Nil(t, err)
+			require.Equal(t, []byte("body"), body)
+		})
+	}
+}
+
+func TestEncodeVideoTagHeader(t *testing.T) {
+	for _, tc := range videoTagHeaderTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			err := EncodeVideoTagHeader(buf, &tc.Header)
+			require.Nil(t, err)
+			require.Equal(t, tc.Binary, buf.Bytes())
+		})
+	}
+}
+
+func TestVideoTagHeaderPredicates(t *testing.T) {
+	avcSeq := &VideoTagHeader{FrameType: VideoFrameTypeKeyFrame, CodecID: VideoCodecIDAVC}
+	require.True(t, avcSeq.IsSequenceHeader())
+	require.True(t, avcSeq.IsKeyFrame())
+
+	hevcFrame := &VideoTagHeader{
+		FrameType:  VideoFrameTypeInterFrame,
+		IsExHeader: true,
+		PacketType: VideoPacketTypeCodedFramesX,
+		FourCC:     FourCCHEVC,
+	}
+	require.False(t, hevcFrame.IsSequenceHeader())
+	require.False(t, hevcFrame.IsKeyFrame())
+}
+
+func TestDecodeVideoTagHeaderWithUnsupportedPacketType(t *testing.T) {
+	var h VideoTagHeader
+	err := DecodeVideoTagHeader(bytes.NewReader([]byte{0x97, 'h', 'v', 'c', '1'}), &h)
+	require.EqualError(t, err, "Unsupported packet type of ExVideoTagHeader: PacketType = 7")
+}
@@ -0,0 +1,48 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"io"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+// PlayHandler A handler of a stream played by a client. It receives media delivered from a server.
+type PlayHandler interface {
+	OnStreamBegin(timestamp uint32) error
+	OnStreamEOF(timestamp uint32) error
+	OnAudio(timestamp uint32, payload io.Reader) error
+	OnVideo(timestamp uint32, payload io.Reader) error
+	OnMetaData(timestamp uint32, data *message.NetStreamOnMetaData) error
+}
+
+var _ PlayHandler = (*DefaultPlayHandler)(nil)
+
+type DefaultPlayHandler struct {
+}
+
+func (h *DefaultPlayHandler) OnStreamBegin(timestamp uint32) error {
+	return nil
+}
+
+func (h *DefaultPlayHandler) OnStreamEOF(timestamp uint32) error {
+	return nil
+}
+
+func (h *DefaultPlayHandler) OnAudio(timestamp uint32, payload io.Reader) error {
+	return nil
+}
+
+func (h *DefaultPlayHandler) OnVideo(timestamp uint32, payload io.Reader) error {
+	return nil
+}
+
+func (h *DefaultPlayHandler) OnMetaData(timestamp uint32, data *message.NetStreamOnMetaData) error {
+	return nil
+}
@@ -0,0 +1,300 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+// PlaySession A session to push media to a player at server side. It is passed to Handler.OnPlay via StreamContext.
+// Methods can be called from any goroutines, even in OnPlay. Media written before the session started are queued,
+// and sent after the sequence of the start (e.g. NetStream.Play.Start) when OnPlay accepted the request.
+type PlaySession struct {
+	stream *Stream
+
+	// Used by notifications after the session started
+	chunkStreamID int
+
+	m             sync.Mutex
+	isStarted     bool
+	isStopped     bool
+	pendingWrites []func(ctx context.Context) error
+}
+
+func newPlaySession(stream *Stream) *PlaySession {
+	return &PlaySession{
+		stream: stream,
+	}
+}
+
+func (ps *PlaySession) StreamID() uint32 {
+	return ps.stream.streamID
+}
+
+func (ps *PlaySession) WriteAudio(timestamp uint32, payload io.Reader) error {
+	ctx, cancel := ps.stream.conn.newWriteContext()
+	defer cancel()
+
+	return ps.WriteAudioContext(ctx, timestamp, payload)
+}
+
+func (ps *PlaySession) WriteAudioContext(ctx context.Context, timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload) // Read it because the write may be queued
+	if err != nil {
+		return err
+	}
+
+	return ps.write(ctx, func(ctx context.Context) error {
+		return ps.stream.WriteAudioContext(ctx, timestamp, bytes.NewReader(data))
+	})
+}
+
+func (ps *PlaySession) WriteVideo(timestamp uint32, payload io.Reader) error {
+	ctx, cancel := ps.stream.conn.newWriteContext()
+	defer cancel()
+
+	return ps.WriteVideoContext(ctx, timestamp, payload)
+}
+
+func (ps *PlaySession) WriteVideoContext(ctx context.Context, timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload) // Read it because the write may be queued
+	if err != nil {
+		return err
+	}
+
+	return ps.write(ctx, func(ctx context.Context) error {
+		return ps.stream.WriteVideoContext(ctx, timestamp, bytes.NewReader(data))
+	})
+}
+
+func (ps *PlaySession) WriteMetadata(metadata map[string]interface{}) error {
+	ctx, cancel := ps.stream.conn.newWriteContext()
+	defer cancel()
+
+	return ps.WriteMetadataContext(ctx, metadata)
+}
+
+// WriteMetadataContext Writes metadata as "onMetaData", {...} which players expect.
+func (ps *PlaySession) WriteMetadataContext(ctx context.Context, metadata map[string]interface{}) error {
+	buf := new(bytes.Buffer)
+	amfEnc := message.NewAMFEncoder(buf, message.EncodingTypeAMF0)
+	if err := message.EncodeBodyAnyValues(amfEnc, &message.NetStreamOnMetaData{
+		MetaData: metadata,
+	}); err != nil {
+		return err
+	}
+
+	return ps.write(ctx, func(ctx context.Context) error {
+		return ps.stream.writeMediaContext(ctx, &ps.stream.dataTrack, 0, &message.DataMessage{
+			Name:     "onMetaData",
+			Encoding: message.EncodingTypeAMF0,
+			Body:     buf,
+		})
+	})
+}
+
+// SendEOF Notifies the player that the source of the stream has gone, e.g. the publisher is unpublished.
+// The session is still available and media can be written again, e.g. when the publisher comes back.
+func (ps *PlaySession) SendEOF() error {
+	return ps.write(context.Background(), func(_ context.Context) error {
+		if err := ps.stream.NotifyStatus(ps.chunkStreamID, 0, newPlayOnStatus(
+			message.NetStreamOnStatusCodePlayUnpublishNotify,
+			"Stream is unpublished.",
+		)); err != nil {
+			return err
+		}
+
+		return ps.writeStreamEvent(&message.UserCtrlEventStreamEOF{
+			StreamID: ps.stream.streamID,
+		})
+	})
+}
+
+// Stop Stops playing. The stream returns to the inactive state, and the session cannot be used anymore.
+// If it is called before the session started (e.g. in OnPlay), the session never starts and the player is notified of nothing.
+func (ps *PlaySession) Stop() error {
+	ps.m.Lock()
+	if ps.isStopped {
+		ps.m.Unlock()
+		return nil
+	}
+	ps.isStopped = true
+	ps.pendingWrites = nil
+	isStarted := ps.isStarted
+	ps.m.Unlock()
+
+	if !isStarted {
+		// If the session is starting, the player is notified after the start by flushPendingWrites
+		return nil
+	}
+
+	return ps.notifyStopped()
+}
+
+// stopped Returns true if the session is stopped.
+func (ps *PlaySession) stopped() bool {
+	ps.m.Lock()
+	defer ps.m.Unlock()
+
+	return ps.isStopped
+}
+
+// notifyStopped Notifies the player that playing is stopped, and then returns the stream to the inactive state.
+func (ps *PlaySession) notifyStopped() error {
+	if err := ps.stream.NotifyStatus(ps.chunkStreamID, 0, newPlayOnStatus(
+		message.NetStreamOnStatusCodePlayStop,
+		"Play stopped.",
+	)); err != nil {
+		return err
+	}
+
+	if err := ps.writeStreamEvent(&message.UserCtrlEventStreamEOF{
+		StreamID: ps.stream.streamID,
+	}); err != nil {
+		return err
+	}
+
+	ps.stream.setPlaySession(nil)
+	ps.stream.handler.ChangeState(streamStateServerInactive)
+
+	return nil
+}
+
+// start Sends the sequence which players expect before media:
+// StreamBegin, NetStream.Play.Reset (if the playlist is reset), NetStream.Play.Start and |RtmpSampleAccess.
+func (ps *PlaySession) start(chunkStreamID int, timestamp uint32, cmd *message.NetStreamPlay) error {
+	ps.chunkStreamID = chunkStreamID
+
+	if err := ps.writeStreamEvent(&message.UserCtrlEventStreamBegin{
+		StreamID: ps.stream.streamID,
+	}); err != nil {
+		return err
+	}
+
+	if cmd.ShouldReset() {
+		if err := ps.stream.NotifyStatus(chunkStreamID, timestamp, newPlayOnStatus(
+			message.NetStreamOnStatusCodePlayReset,
+			"Playing and resetting.",
+		)); err != nil {
+			return err
+		}
+	}
+
+	if err := ps.stream.NotifyStatus(chunkStreamID, timestamp, newPlayOnStatus(
+		message.NetStreamOnStatusCodePlayStart,
+		"Play succeeded.",
+	)); err != nil {
+		return err
+	}
+
+	if err := ps.stream.WriteDataMessage(chunkStreamID, timestamp, "|RtmpSampleAccess", &message.NetStreamRtmpSampleAccess{
+		AudioSampleAccess: true,
+		VideoSampleAccess: true,
+	}); err != nil {
+		return err
+	}
+
+	return ps.flushPendingWrites()
+}
+
+// cancel Marks the session as stopped without notifications. It is used when OnPlay rejected the request.
+func (ps *PlaySession) cancel() {
+	ps.m.Lock()
+	defer ps.m.Unlock()
+
+	ps.isStopped = true
+	ps.pendingWrites = nil
+}
+
+// write Calls f to write messages, or queues it if the session is not started yet.
+func (ps *PlaySession) write(ctx context.Context, f func(ctx context.Context) error) error {
+	ps.m.Lock()
+	if ps.isStopped {
+		ps.m.Unlock()
+		return errors.New("Play session is stopped")
+	}
+
+	if !ps.isStarted {
+		ps.pendingWrites = append(ps.pendingWrites, f)
+		ps.m.Unlock()
+		return nil
+	}
+	ps.m.Unlock()
+
+	return f(ctx)
+}
+
+// flushPendingWrites Writes queued messages in order, and then marks the session as started.
+// ps.m is not locked while writing, thus messages queued during flushing are also written by the loop.
+func (ps *PlaySession) flushPendingWrites() error {
+	for {
+		ps.m.Lock()
+		if ps.isStopped {
+			// Stopped while starting, thus notify the player after the start
+			ps.m.Unlock()
+			return ps.notifyStopped()
+		}
+		writes := ps.pendingWrites
+		ps.pendingWrites = nil
+		if len(writes) == 0 {
+			ps.isStarted = true
+			ps.m.Unlock()
+			return nil
+		}
+		ps.m.Unlock()
+
+		for _, f := range writes {
+			if err := ps.writePending(f); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (ps *PlaySession) writePending(f func(ctx context.Context) error) error {
+	ctx, cancel := ps.stream.conn.newWriteContext()
+	defer cancel()
+
+	return f(ctx)
+}
+
+// writeStreamEvent Writes a user control event which must be sent on the control stream.
+func (ps *PlaySession) writeStreamEvent(event message.UserCtrlEvent) error {
+	ctrlStream, err := ps.stream.streams().At(ControlStreamID)
+	if err != nil {
+		return err
+	}
+
+	return ctrlStream.WriteUserCtrl(ctrlMsgChunkStreamID, 0, &message.UserCtrl{
+		Event: event,
+	})
+}
+
+func newPlayOnStatus(code message.NetStreamOnStatusCode, description string) *message.NetStreamOnStatus {
+	level := message.NetStreamOnStatusLevelStatus
+	switch code {
+	case message.NetStreamOnStatusCodeFailed, message.NetStreamOnStatusCodeSeekFailed:
+		level = message.NetStreamOnStatusLevelError
+	}
+
+	return &message.NetStreamOnStatus{
+		InfoObject: message.NetStreamOnStatusInfoObject{
+			Level:       level,
+			Code:        code,
+			Description: description,
+		},
+	}
+}
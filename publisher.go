@@ -0,0 +1,481 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+// PublisherConfig A configuration of Publisher.
+type PublisherConfig struct {
+	ConnConfig *ConnConfig
+
+	MinReconnectBackoff time.Duration // 500ms if 0
+	MaxReconnectBackoff time.Duration // 30s if 0
+	MaxReconnectRetries int           // Unlimited if 0
+	ReconnectTimeout    time.Duration // A timeout of each attempts, 10s if 0
+
+	OnGap func(gap *PublisherGap) // Optional, called when the publisher resumed after reconnected
+}
+
+func (cb *PublisherConfig) normalize() *PublisherConfig {
+	c := PublisherConfig(*cb)
+
+	if c.MinReconnectBackoff == 0 {
+		c.MinReconnectBackoff = 500 * time.Millisecond
+	}
+
+	if c.MaxReconnectBackoff == 0 {
+		c.MaxReconnectBackoff = 30 * time.Second
+	}
+
+	if c.ReconnectTimeout == 0 {
+		c.ReconnectTimeout = 10 * time.Second
+	}
+
+	return &c
+}
+
+// PublisherGap A gap of media which were not sent while the publisher was reconnecting.
+type PublisherGap struct {
+	Err             error         // An error which caused the reconnection
+	Retries         int           // A number of failed attempts before reconnected
+	DroppedMessages int           // A number of messages which were written while reconnecting
+	Duration        time.Duration // A duration from disconnected to reconnected
+}
+
+// Publisher A publishing client which reconnects with backoff when the connection is lost.
+// After reconnected, it replays the last metadata and sequence headers, and rebases timestamps of media to start from 0.
+// Media written while reconnecting are dropped and reported as a gap.
+// It also follows reconnect requests from the server (Enhanced RTMP) by reconnecting to the requested URL.
+type Publisher struct {
+	rawURL string // Updated by reconnect requests
+	config *PublisherConfig
+
+	cc        *ClientConn
+	stream    *Stream
+	connected bool  // false while reconnecting
+	lastErr   error // Set if reconnecting is given up
+
+	// Replayed after reconnected
+	metadata            map[string]interface{}
+	audioSequenceHeader []byte
+	videoSequenceHeader []byte
+	replayVersion       int // Incremented when replayed data are updated
+
+	baseTimestamp    uint32 // A timestamp of the first media in the current connection
+	baseTimestampSet bool
+
+	disconnectedAt  time.Time
+	disconnectedErr error
+	droppedMessages int
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	m        sync.Mutex
+	isClosed bool
+}
+
+func DialPublisher(rawURL string, config *PublisherConfig) (*Publisher, error) {
+	return DialPublisherContext(context.Background(), rawURL, config)
+}
+
+// DialPublisherContext Dials to the server of the URL and publishes the stream. Reconnection is not tried for this
+// first attempt, thus an error is returned if it failed.
+func DialPublisherContext(ctx context.Context, rawURL string, config *PublisherConfig) (*Publisher, error) {
+	if config == nil {
+		config = &PublisherConfig{}
+	}
+	config = config.normalize()
+
+	cc, stream, err := PublishURLContext(ctx, rawURL, config.ConnConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	pubCtx, cancel := context.WithCancel(context.Background())
+	p := &Publisher{
+		rawURL: rawURL,
+		config: config,
+
+		cc:        cc,
+		stream:    stream,
+		connected: true,
+
+		ctx:    pubCtx,
+		cancel: cancel,
+	}
+	p.watchReconnectRequest(cc)
+
+	return p, nil
+}
+
+// WriteAudio Writes an audio message. It returns nil even if the message is dropped while reconnecting.
+func (p *Publisher) WriteAudio(timestamp uint32, payload io.Reader) error {
+	return p.writeMedia(timestamp, payload, true)
+}
+
+// WriteVideo Writes a video message. It returns nil even if the message is dropped while reconnecting.
+func (p *Publisher) WriteVideo(timestamp uint32, payload io.Reader) error {
+	return p.writeMedia(timestamp, payload, false)
+}
+
+// WriteMetadata Writes metadata. It is replayed after reconnected.
+func (p *Publisher) WriteMetadata(metadata map[string]interface{}) error {
+	p.m.Lock()
+	if err := p.writable(); err != nil {
+		p.m.Unlock()
+		return err
+	}
+	p.metadata = metadata
+	p.replayVersion++
+
+	if !p.available() {
+		p.droppedMessages++
+		p.m.Unlock()
+		return nil
+	}
+	cc, stream := p.cc, p.stream
+	p.m.Unlock()
+
+	// Do not lock p.m while writing because writing may wait for acknowledgements from the server
+	if err := stream.WriteMetadata(metadata); err != nil {
+		p.onWriteError(cc, err)
+	}
+
+	return nil
+}
+
+func (p *Publisher) Close() error {
+	p.m.Lock()
+	if p.isClosed {
+		p.m.Unlock()
+		return nil
+	}
+	p.isClosed = true
+
+	cc := p.cc
+	p.cc, p.stream = nil, nil
+	p.m.Unlock()
+
+	p.cancel()
+	p.wg.Wait() // Wait for reconnecting
+
+	if cc == nil {
+		return nil
+	}
+	return cc.Close()
+}
+
+func (p *Publisher) writeMedia(timestamp uint32, payload io.Reader, isAudio bool) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+
+	p.m.Lock()
+	if err := p.writable(); err != nil {
+		p.m.Unlock()
+		return err
+	}
+
+	if isAudio && isAudioSequenceHeader(data) {
+		p.audioSequenceHeader = data
+		p.replayVersion++
+	}
+	if !isAudio && isVideoSequenceHeader(data) {
+		p.videoSequenceHeader = data
+		p.replayVersion++
+	}
+
+	if !p.available() {
+		p.droppedMessages++
+		p.m.Unlock()
+		return nil
+	}
+	cc, stream := p.cc, p.stream
+	timestamp = p.rebaseTimestamp(timestamp)
+	p.m.Unlock()
+
+	write := stream.WriteVideo
+	if isAudio {
+		write = stream.WriteAudio
+	}
+
+	// Do not lock p.m while writing because writing may wait for acknowledgements from the server
+	if err := write(timestamp, bytes.NewReader(data)); err != nil {
+		p.onWriteError(cc, err)
+	}
+
+	return nil
+}
+
+// onWriteError Starts reconnecting if the connection has not been replaced yet, and counts the message as dropped.
+func (p *Publisher) onWriteError(cc *ClientConn, err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.isClosed {
+		return
+	}
+
+	if p.connected && p.cc == cc {
+		p.disconnect(err)
+	}
+	if !p.connected {
+		p.droppedMessages++
+	}
+}
+
+// writable Returns an error if the publisher cannot be used anymore. p.m must be locked by a caller.
+func (p *Publisher) writable() error {
+	if p.isClosed {
+		return errors.New("Publisher is closed")
+	}
+	if p.lastErr != nil {
+		return errors.Wrap(p.lastErr, "Publisher gave up reconnecting")
+	}
+
+	return nil
+}
+
+// available Returns true if the connection is alive. Otherwise, it starts reconnecting. p.m must be locked by a caller.
+func (p *Publisher) available() bool {
+	if !p.connected {
+		return false
+	}
+
+	if err := p.cc.LastError(); err != nil {
+		p.disconnect(err)
+		return false
+	}
+
+	return true
+}
+
+// rebaseTimestamp Rebases a timestamp on the first media in the current connection. p.m must be locked by a caller.
+func (p *Publisher) rebaseTimestamp(timestamp uint32) uint32 {
+	if !p.baseTimestampSet {
+		p.baseTimestamp = timestamp
+		p.baseTimestampSet = true
+	}
+
+	// Media before the first one, e.g. audio just before video, are regarded as 0
+	if int32(timestamp-p.baseTimestamp) < 0 {
+		return 0
+	}
+	return timestamp - p.baseTimestamp
+}
+
+// disconnect Starts reconnecting in background. p.m must be locked by a caller.
+func (p *Publisher) disconnect(err error) {
+	l := p.cc.conn.logger
+	l.Warnf("Publisher is disconnected, reconnecting: Err = %+v", err)
+
+	cc := p.cc
+	p.cc, p.stream = nil, nil
+	p.connected = false
+
+	p.disconnectedAt = time.Now()
+	p.disconnectedErr = err
+	p.droppedMessages = 0
+
+	p.wg.Add(1)
+	go p.reconnectLoop(cc)
+}
+
+func (p *Publisher) reconnectLoop(oldCC *ClientConn) {
+	defer p.wg.Done()
+
+	_ = oldCC.Close()
+
+	backoff := p.config.MinReconnectBackoff
+	for retries := 0; ; retries++ {
+		err := p.reconnect(retries)
+		if err == nil {
+			return
+		}
+		oldCC.conn.logger.Warnf("Failed to reconnect: Retries = %d, Err = %+v", retries, err)
+
+		if p.config.MaxReconnectRetries > 0 && retries+1 >= p.config.MaxReconnectRetries {
+			p.m.Lock()
+			p.lastErr = err
+			p.m.Unlock()
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-p.ctx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > p.config.MaxReconnectBackoff {
+			backoff = p.config.MaxReconnectBackoff
+		}
+	}
+}
+
+func (p *Publisher) reconnect(retries int) error {
+	ctx, cancel := context.WithTimeout(p.ctx, p.config.ReconnectTimeout)
+	defer cancel()
+
+	p.m.Lock()
+	rawURL := p.rawURL
+	p.m.Unlock()
+
+	cc, stream, err := PublishURLContext(ctx, rawURL, p.config.ConnConfig)
+	if err != nil {
+		return err
+	}
+	p.watchReconnectRequest(cc)
+
+	// Replay again if replayed data are updated while writing them without locking p.m
+	replayedVersion := -1
+	for {
+		p.m.Lock()
+		if p.isClosed {
+			p.m.Unlock()
+			_ = cc.Close()
+			return nil
+		}
+
+		if replayedVersion == p.replayVersion {
+			break // Keep p.m locked
+		}
+		replayedVersion = p.replayVersion
+		r := p.replayData()
+		p.m.Unlock()
+
+		if err := r.writeTo(stream); err != nil {
+			_ = cc.Close()
+			return err
+		}
+	}
+
+	p.cc, p.stream = cc, stream
+	p.connected = true
+	p.baseTimestampSet = false
+
+	gap := &PublisherGap{
+		Err:             p.disconnectedErr,
+		Retries:         retries,
+		DroppedMessages: p.droppedMessages,
+		Duration:        time.Since(p.disconnectedAt),
+	}
+	p.m.Unlock()
+
+	if p.config.OnGap != nil {
+		p.config.OnGap(gap)
+	}
+
+	return nil
+}
+
+func (p *Publisher) watchReconnectRequest(cc *ClientConn) {
+	cc.SetReconnectRequestHandler(func(req *ReconnectRequest) {
+		// The handler is called from the reader goroutine, thus do not wait for p.m on it
+		go p.onReconnectRequest(cc, req)
+	})
+}
+
+// onReconnectRequest Reconnects to the URL which consists of the requested tcUrl and the current publishing name.
+func (p *Publisher) onReconnectRequest(cc *ClientConn, req *ReconnectRequest) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.isClosed || p.cc != cc {
+		return // Already disconnected
+	}
+
+	if req.TCURL != "" {
+		u, err := ParseURL(p.rawURL)
+		if err != nil {
+			return // Unreachable, the URL has been parsed when dialed
+		}
+
+		rawURL := strings.TrimSuffix(req.TCURL, "/") + "/" + u.PublishingName()
+		if _, err := ParseURL(rawURL); err != nil {
+			cc.conn.logger.Warnf("Ignored a reconnect request to the invalid URL: Request = %#v, Err = %+v", req, err)
+			return
+		}
+		p.rawURL = rawURL
+	}
+
+	p.disconnect(&ReconnectRequestedError{
+		Request: req,
+	})
+}
+
+// publisherReplayData The last metadata and sequence headers which are replayed to a new stream.
+type publisherReplayData struct {
+	metadata            map[string]interface{}
+	audioSequenceHeader []byte
+	videoSequenceHeader []byte
+}
+
+// replayData Returns data to be replayed. p.m must be locked by a caller.
+func (p *Publisher) replayData() *publisherReplayData {
+	return &publisherReplayData{
+		metadata:            p.metadata,
+		audioSequenceHeader: p.audioSequenceHeader,
+		videoSequenceHeader: p.videoSequenceHeader,
+	}
+}
+
+func (r *publisherReplayData) writeTo(stream *Stream) error {
+	if r.metadata != nil {
+		if err := stream.WriteMetadata(r.metadata); err != nil {
+			return err
+		}
+	}
+
+	if r.audioSequenceHeader != nil {
+		if err := stream.WriteAudio(0, bytes.NewReader(r.audioSequenceHeader)); err != nil {
+			return err
+		}
+	}
+
+	if r.videoSequenceHeader != nil {
+		if err := stream.WriteVideo(0, bytes.NewReader(r.videoSequenceHeader)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isAudioSequenceHeader Returns true if the payload is a sequence header of AAC or codecs of Enhanced RTMP.
+func isAudioSequenceHeader(payload []byte) bool {
+	var h message.AudioTagHeader
+	if err := message.DecodeAudioTagHeader(bytes.NewReader(payload), &h); err != nil {
+		return false
+	}
+	return h.IsSequenceHeader()
+}
+
+// isVideoSequenceHeader Returns true if the payload is a sequence header of AVC or codecs of Enhanced RTMP.
+func isVideoSequenceHeader(payload []byte) bool {
+	var h message.VideoTagHeader
+	if err := message.DecodeVideoTagHeader(bytes.NewReader(payload), &h); err != nil {
+		return false
+	}
+	return h.IsSequenceHeader()
+}
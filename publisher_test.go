@@ -0,0 +1,201 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type serverCanBeDisconnectedHandler struct {
+	serverCanReceiveMediaHandler
+	connCh chan *Conn
+}
+
+func (h *serverCanBeDisconnectedHandler) OnServe(conn *Conn) {
+	h.connCh <- conn
+}
+
+func TestPublisherCanReconnectAndResume(t *testing.T) {
+	handler := &serverCanBeDisconnectedHandler{
+		serverCanReceiveMediaHandler: serverCanReceiveMediaHandler{
+			eventCh: make(chan string, 10),
+		},
+		connCh: make(chan *Conn, 2),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	receiveEvents := func(n int) []string {
+		events := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			select {
+			case event := <-handler.eventCh:
+				events = append(events, event)
+			case <-time.After(3 * time.Second):
+				require.FailNow(t, "Events are not received", "Events = %v", events)
+			}
+		}
+		return events
+	}
+
+	prepareServer(t, config, func(addr string) {
+		gapCh := make(chan *PublisherGap, 1)
+		p, err := DialPublisher(fmt.Sprintf("rtmp://%s/live/theStream", addr), &PublisherConfig{
+			ConnConfig: &ConnConfig{
+				Logger: logrus.StandardLogger(),
+			},
+			MinReconnectBackoff: 10 * time.Millisecond,
+			OnGap: func(gap *PublisherGap) {
+				gapCh <- gap
+			},
+		})
+		require.Nil(t, err)
+		defer p.Close()
+
+		serverConn := <-handler.connCh
+
+		err = p.WriteMetadata(map[string]interface{}{"width": float64(1280)})
+		require.Nil(t, err)
+		err = p.WriteVideo(1000, bytes.NewReader([]byte{0x17, 0x00, 0x00, 0x00, 0x00})) // Sequence header
+		require.Nil(t, err)
+		err = p.WriteVideo(1033, bytes.NewReader([]byte{0x27, 0x01, 0x00, 0x00, 0x00}))
+		require.Nil(t, err)
+
+		require.ElementsMatch(t, []string{
+			"SetDataFrame: 0, onMetaData, map[width:1280]",
+			"Video: 0, \x17\x00\x00\x00\x00",
+			"Video: 33, \x27\x01\x00\x00\x00",
+		}, receiveEvents(3))
+
+		// Disconnected by the server
+		err = serverConn.Close()
+		require.Nil(t, err)
+		require.Eventually(t, func() bool {
+			p.m.Lock()
+			defer p.m.Unlock()
+			return p.cc.LastError() != nil
+		}, 3*time.Second, 10*time.Millisecond)
+
+		// Dropped while reconnecting
+		err = p.WriteVideo(2000, bytes.NewReader([]byte{0x27, 0x01, 0x00, 0x00, 0x00}))
+		require.Nil(t, err)
+
+		select {
+		case gap := <-gapCh:
+			require.NotNil(t, gap.Err)
+			require.Equal(t, 1, gap.DroppedMessages)
+		case <-time.After(3 * time.Second):
+			require.FailNow(t, "Publisher is not reconnected")
+		}
+		<-handler.connCh
+
+		err = p.WriteVideo(5000, bytes.NewReader([]byte{0x27, 0x01, 0x00, 0x00, 0x01}))
+		require.Nil(t, err)
+
+		// Metadata and the sequence header are replayed, and timestamps are rebased
+		require.ElementsMatch(t, []string{
+			"SetDataFrame: 0, onMetaData, map[width:1280]",
+			"Video: 0, \x17\x00\x00\x00\x00",
+			"Video: 0, \x27\x01\x00\x00\x01",
+		}, receiveEvents(3))
+	})
+}
+
+func TestPublisherFollowsReconnectRequest(t *testing.T) {
+	newHandler := func() *serverCanBeDisconnectedHandler {
+		return &serverCanBeDisconnectedHandler{
+			serverCanReceiveMediaHandler: serverCanReceiveMediaHandler{
+				eventCh: make(chan string, 10),
+			},
+			connCh: make(chan *Conn, 1),
+		}
+	}
+	receiveEvents := func(handler *serverCanBeDisconnectedHandler, n int) []string {
+		events := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			select {
+			case event := <-handler.eventCh:
+				events = append(events, event)
+			case <-time.After(3 * time.Second):
+				require.FailNow(t, "Events are not received", "Events = %v", events)
+			}
+		}
+		return events
+	}
+
+	handlerA, handlerB := newHandler(), newHandler()
+	prepareServer(t, &ConnConfig{Handler: handlerA, Logger: logrus.StandardLogger()}, func(addrA string) {
+		prepareServer(t, &ConnConfig{Handler: handlerB, Logger: logrus.StandardLogger()}, func(addrB string) {
+			gapCh := make(chan *PublisherGap, 1)
+			p, err := DialPublisher(fmt.Sprintf("rtmp://%s/live/theStream", addrA), &PublisherConfig{
+				ConnConfig: &ConnConfig{
+					Logger: logrus.StandardLogger(),
+				},
+				OnGap: func(gap *PublisherGap) {
+					gapCh <- gap
+				},
+			})
+			require.Nil(t, err)
+			defer p.Close()
+
+			connA := <-handlerA.connCh
+
+			err = p.WriteMetadata(map[string]interface{}{"width": float64(1280)})
+			require.Nil(t, err)
+			err = p.WriteVideo(1000, bytes.NewReader([]byte{0x17, 0x00, 0x00, 0x00, 0x00})) // Sequence header
+			require.Nil(t, err)
+			require.Len(t, receiveEvents(handlerA, 2), 2)
+
+			tcURL := fmt.Sprintf("rtmp://%s/live", addrB)
+			err = connA.RequestReconnect(tcURL, "Maintenance")
+			require.Nil(t, err)
+
+			select {
+			case gap := <-gapCh:
+				require.Equal(t, &ReconnectRequestedError{
+					Request: &ReconnectRequest{
+						TCURL:       tcURL,
+						Description: "Maintenance",
+					},
+				}, gap.Err)
+			case <-time.After(3 * time.Second):
+				require.FailNow(t, "Publisher is not reconnected")
+			}
+			<-handlerB.connCh
+
+			// Resumed on the requested server
+			require.ElementsMatch(t, []string{
+				"SetDataFrame: 0, onMetaData, map[width:1280]",
+				"Video: 0, \x17\x00\x00\x00\x00",
+			}, receiveEvents(handlerB, 2))
+		})
+	})
+}
+
+func TestIsSequenceHeader(t *testing.T) {
+	require.True(t, isAudioSequenceHeader([]byte{0xaf, 0x00}))
+	require.False(t, isAudioSequenceHeader([]byte{0xaf, 0x01}))
+	require.False(t, isAudioSequenceHeader([]byte{0x2f, 0x00})) // MP3
+	require.False(t, isAudioSequenceHeader([]byte{0xaf}))
+	require.True(t, isAudioSequenceHeader([]byte{0x90, 'O', 'p', 'u', 's'}))
+
+	require.True(t, isVideoSequenceHeader([]byte{0x17, 0x00, 0x00, 0x00, 0x00}))
+	require.False(t, isVideoSequenceHeader([]byte{0x17, 0x01, 0x00, 0x00, 0x00}))
+	require.True(t, isVideoSequenceHeader([]byte{0x90, 'h', 'v', 'c', '1'}))
+	require.False(t, isVideoSequenceHeader([]byte{0x93, 'h', 'v', 'c', '1'}))
+	require.False(t, isVideoSequenceHeader([]byte{0x12, 0x00})) // H.263
+	require.False(t, isVideoSequenceHeader([]byte{0x17}))
+}
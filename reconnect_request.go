@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"github.com/yutopp/go-rtmp/message"
+)
+
+// ReconnectRequest A request from a server to reconnect (Enhanced RTMP NetConnection.Connect.ReconnectRequest).
+type ReconnectRequest struct {
+	TCURL       string // A destination. Reconnect to the same server if empty
+	Description string
+}
+
+// ReconnectRequestHandlerFunc A function which is called when a server requested to reconnect.
+// It is called on the goroutine which handles messages, thus it must not block.
+type ReconnectRequestHandlerFunc func(req *ReconnectRequest)
+
+// RequestReconnect Requests a client to reconnect to tcURL, e.g. to drain the server for maintenance.
+// The client reconnects to the same server if tcURL is empty.
+func (c *Conn) RequestReconnect(tcURL, description string) error {
+	stream, err := c.streams.At(ControlStreamID)
+	if err != nil {
+		return err
+	}
+
+	return stream.writeCommandMessage(
+		commandChunkStreamID, 0,
+		"onStatus",
+		0, // 7.2.2
+		&message.NetConnectionOnStatus{
+			InfoObject: message.NetConnectionOnStatusInfoObject{
+				Level:       message.NetConnectionOnStatusLevelStatus,
+				Code:        message.NetConnectionConnectCodeReconnectRequest,
+				Description: description,
+				TCURL:       tcURL,
+			},
+		},
+	)
+}
+
+// SetReconnectRequestHandler Sets a function which is called when the server requested to reconnect.
+// Requests are only logged if no function is set.
+func (cc *ClientConn) SetReconnectRequestHandler(f ReconnectRequestHandlerFunc) {
+	cc.conn.m.Lock()
+	defer cc.conn.m.Unlock()
+
+	cc.conn.reconnectRequestHandler = f
+}
+
+func (c *Conn) handleReconnectRequest(req *ReconnectRequest) {
+	c.m.Lock()
+	f := c.reconnectRequestHandler
+	c.m.Unlock()
+
+	if f == nil {
+		c.logger.Infof("Reconnect is requested, but ignored: Request = %#v", req)
+		return
+	}
+
+	f(req)
+}
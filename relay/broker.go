@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"sync"
+	"time"
+)
+
+// TakeoverPolicy A policy for a publisher which comes to a channel already published.
+type TakeoverPolicy int
+
+const (
+	// TakeoverPolicyReject The new publisher is rejected by ErrAlreadyPublished
+	TakeoverPolicyReject TakeoverPolicy = iota
+	// TakeoverPolicyReplace The current publisher is closed by ErrPublisherTakenOver, and subscribers continue with the new one
+	TakeoverPolicyReplace
+)
+
+type BrokerConfig struct {
+	TakeoverPolicy TakeoverPolicy
+
+	DisableGOPCache bool
+	// The GOP cache is dropped until the next key frame if a GOP has more messages than this. 1024 if 0
+	MaxGOPCacheMessages int
+
+	// Messages are dropped if a subscriber has more messages than this in its queue. 512 if 0
+	MaxSubscriberQueueMessages int
+	// A subscriber is removed by ErrSubscriberTooSlow if a message has been waiting in its queue longer than this.
+	// Disabled if 0
+	MaxSubscriberLag time.Duration
+}
+
+func (cb *BrokerConfig) normalize() *BrokerConfig {
+	c := BrokerConfig(*cb)
+
+	if c.MaxGOPCacheMessages == 0 {
+		c.MaxGOPCacheMessages = 1024
+	}
+
+	if c.MaxSubscriberQueueMessages == 0 {
+		c.MaxSubscriberQueueMessages = 512
+	}
+
+	return &c
+}
+
+// Broker Relays live streams from publishers to subscribers through named channels.
+// A channel exists while it has a publisher or subscribers.
+type Broker struct {
+	config   *BrokerConfig
+	channels map[string]*channel
+	m        sync.Mutex
+}
+
+func NewBroker(config *BrokerConfig) *Broker {
+	if config == nil {
+		config = &BrokerConfig{}
+	}
+
+	return &Broker{
+		config:   config.normalize(),
+		channels: make(map[string]*channel),
+	}
+}
+
+// Publish Starts publishing to the channel. Subscribers of the channel receive media written to the publisher.
+func (b *Broker) Publish(name string) (*Publisher, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ch := b.channelOrNew(name)
+
+	return ch.attachPublisher(b.config.TakeoverPolicy)
+}
+
+// Subscribe Subscribes the channel. Cached metadata, sequence headers and the GOP are written to the sink first if
+// the channel is published. Otherwise, the subscriber waits for a publisher.
+func (b *Broker) Subscribe(name string, sink Sink) (*Subscriber, error) {
+	b.m.Lock()
+	ch := b.channelOrNew(name)
+	sub, attached := ch.attachSubscriber(sink)
+	b.m.Unlock()
+
+	if !attached {
+		b.removeChannelIfUnused(ch) // As same as Subscriber.Close, the channel must not be left unused
+	}
+
+	return sub, nil
+}
+
+// IsPublished Returns true if the channel has a publisher.
+func (b *Broker) IsPublished(name string) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ch, ok := b.channels[name]
+	if !ok {
+		return false
+	}
+
+	return ch.isPublished()
+}
+
+func (b *Broker) channelOrNew(name string) *channel {
+	ch, ok := b.channels[name]
+	if !ok {
+		ch = newChannel(b, name)
+		b.channels[name] = ch
+	}
+
+	return ch
+}
+
+// removeChannelIfUnused Removes the channel if it has neither a publisher nor subscribers.
+func (b *Broker) removeChannelIfUnused(ch *channel) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.channels[ch.name] != ch || !ch.isUnused() {
+		return
+	}
+
+	delete(b.channels, ch.name)
+}
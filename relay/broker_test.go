@@ -0,0 +1,341 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	aacSequenceHeader = []byte{0xaf, 0x00, 0x12, 0x10}
+	aacRaw            = []byte{0xaf, 0x01, 0x21}
+	avcSequenceHeader = []byte{0x17, 0x00, 0x00, 0x00, 0x00, 0x01}
+	avcKeyFrame       = []byte{0x17, 0x01, 0x00, 0x00, 0x00, 0x02}
+	avcInterFrame     = []byte{0x27, 0x01, 0x00, 0x00, 0x00, 0x03}
+	hevcSequenceStart = []byte{0x90, 'h', 'v', 'c', '1', 0x01}
+	hevcKeyFrame      = []byte{0x93, 'h', 'v', 'c', '1', 0x02}
+	hevcInterFrame    = []byte{0xa3, 'h', 'v', 'c', '1', 0x03}
+)
+
+type sinkEvent struct {
+	kind      string
+	timestamp uint32
+	payload   []byte
+	metadata  map[string]interface{}
+}
+
+type recordingSink struct {
+	events []sinkEvent
+	err    error // Returned by writes if not nil
+	m      sync.Mutex
+}
+
+func (s *recordingSink) WriteAudio(timestamp uint32, payload io.Reader) error {
+	return s.record("audio", timestamp, payload, nil)
+}
+
+func (s *recordingSink) WriteVideo(timestamp uint32, payload io.Reader) error {
+	return s.record("video", timestamp, payload, nil)
+}
+
+func (s *recordingSink) WriteMetadata(metadata map[string]interface{}) error {
+	return s.record("metadata", 0, nil, metadata)
+}
+
+func (s *recordingSink) SendEOF() error {
+	return s.record("eof", 0, nil, nil)
+}
+
+func (s *recordingSink) record(kind string, timestamp uint32, payload io.Reader, metadata map[string]interface{}) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	var data []byte
+	if payload != nil {
+		d, err := ioutil.ReadAll(payload)
+		if err != nil {
+			return err
+		}
+		data = d
+	}
+	s.events = append(s.events, sinkEvent{kind: kind, timestamp: timestamp, payload: data, metadata: metadata})
+
+	return nil
+}
+
+func (s *recordingSink) takeEvents() []sinkEvent {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	events := s.events
+	s.events = nil
+
+	return events
+}
+
+// waitEvents Waits until queued messages are written to the sink, and takes written events.
+func waitEvents(t *testing.T, sub *Subscriber, sink *recordingSink) []sinkEvent {
+	require.Eventually(t, func() bool {
+		return sub.Stats().QueuedMessages == 0
+	}, 1*time.Second, 1*time.Millisecond)
+
+	return sink.takeEvents()
+}
+
+func writeVideo(t *testing.T, pub *Publisher, timestamp uint32, payload []byte) {
+	require.Nil(t, pub.WriteVideo(timestamp, bytes.NewReader(payload)))
+}
+
+func writeAudio(t *testing.T, pub *Publisher, timestamp uint32, payload []byte) {
+	require.Nil(t, pub.WriteAudio(timestamp, bytes.NewReader(payload)))
+}
+
+func TestSubscriberReceivesCachedGOP(t *testing.T) {
+	b := NewBroker(nil)
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+	require.True(t, b.IsPublished("app/stream"))
+
+	metadata := map[string]interface{}{"width": float64(1280)}
+	require.Nil(t, pub.WriteMetadata(metadata))
+	writeAudio(t, pub, 1000, aacSequenceHeader)
+	writeVideo(t, pub, 1000, avcSequenceHeader)
+	writeVideo(t, pub, 1000, avcKeyFrame)
+	writeVideo(t, pub, 1033, avcInterFrame)
+	writeVideo(t, pub, 2000, avcKeyFrame) // A new GOP
+	writeAudio(t, pub, 2010, aacRaw)
+	writeVideo(t, pub, 2033, avcInterFrame)
+
+	sink := &recordingSink{}
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+
+	require.Equal(t, []sinkEvent{
+		{kind: "metadata", metadata: metadata},
+		{kind: "audio", timestamp: 0, payload: aacSequenceHeader},
+		{kind: "video", timestamp: 0, payload: avcSequenceHeader},
+		{kind: "video", timestamp: 0, payload: avcKeyFrame},
+		{kind: "audio", timestamp: 10, payload: aacRaw},
+		{kind: "video", timestamp: 33, payload: avcInterFrame},
+	}, waitEvents(t, sub, sink))
+
+	// Live messages continue from the cached ones
+	writeVideo(t, pub, 2066, avcInterFrame)
+	require.Equal(t, []sinkEvent{
+		{kind: "video", timestamp: 66, payload: avcInterFrame},
+	}, waitEvents(t, sub, sink))
+}
+
+func TestSubscriberReceivesCachedHEVCSequenceHeader(t *testing.T) {
+	b := NewBroker(nil)
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	writeVideo(t, pub, 0, hevcSequenceStart)
+	writeVideo(t, pub, 0, hevcKeyFrame)
+	writeVideo(t, pub, 33, hevcInterFrame)
+
+	sink := &recordingSink{}
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+
+	require.Equal(t, []sinkEvent{
+		{kind: "video", timestamp: 0, payload: hevcSequenceStart},
+		{kind: "video", timestamp: 0, payload: hevcKeyFrame},
+		{kind: "video", timestamp: 33, payload: hevcInterFrame},
+	}, waitEvents(t, sub, sink))
+}
+
+func TestSubscriberSkipsVideoUntilKeyFrame(t *testing.T) {
+	b := NewBroker(&BrokerConfig{
+		DisableGOPCache: true,
+	})
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	writeVideo(t, pub, 0, avcSequenceHeader)
+	writeVideo(t, pub, 0, avcKeyFrame)
+	writeVideo(t, pub, 33, avcInterFrame)
+
+	sink := &recordingSink{}
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+
+	// Sequence headers are cached even if the GOP cache is disabled
+	require.Equal(t, []sinkEvent{
+		{kind: "video", timestamp: 0, payload: avcSequenceHeader},
+	}, waitEvents(t, sub, sink))
+
+	writeVideo(t, pub, 66, avcInterFrame)
+	writeVideo(t, pub, 100, avcKeyFrame)
+	require.Equal(t, []sinkEvent{
+		{kind: "video", timestamp: 0, payload: avcKeyFrame},
+	}, waitEvents(t, sub, sink))
+}
+
+func TestGOPCacheIsDroppedIfTooLarge(t *testing.T) {
+	b := NewBroker(&BrokerConfig{
+		MaxGOPCacheMessages: 2,
+	})
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	writeVideo(t, pub, 0, avcKeyFrame)
+	writeVideo(t, pub, 33, avcInterFrame)
+	writeVideo(t, pub, 66, avcInterFrame) // Exceeds
+
+	sink := &recordingSink{}
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+	require.Empty(t, waitEvents(t, sub, sink))
+
+	writeVideo(t, pub, 100, avcKeyFrame)
+
+	sink2 := &recordingSink{}
+	sub2, err := b.Subscribe("app/stream", sink2)
+	require.Nil(t, err)
+	require.Equal(t, []sinkEvent{
+		{kind: "video", timestamp: 0, payload: avcKeyFrame},
+	}, waitEvents(t, sub2, sink2))
+}
+
+func TestTakeoverPolicy(t *testing.T) {
+	t.Run("Reject", func(t *testing.T) {
+		b := NewBroker(&BrokerConfig{
+			TakeoverPolicy: TakeoverPolicyReject,
+		})
+
+		pub, err := b.Publish("app/stream")
+		require.Nil(t, err)
+
+		_, err = b.Publish("app/stream")
+		require.Equal(t, ErrAlreadyPublished, err)
+
+		writeVideo(t, pub, 0, avcKeyFrame) // Still alive
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		b := NewBroker(&BrokerConfig{
+			TakeoverPolicy: TakeoverPolicyReplace,
+		})
+
+		pub1, err := b.Publish("app/stream")
+		require.Nil(t, err)
+		writeVideo(t, pub1, 5000, avcSequenceHeader)
+		writeVideo(t, pub1, 5000, avcKeyFrame)
+		writeVideo(t, pub1, 5100, avcInterFrame)
+
+		sink := &recordingSink{}
+		sub, err := b.Subscribe("app/stream", sink)
+		require.Nil(t, err)
+		waitEvents(t, sub, sink)
+
+		pub2, err := b.Publish("app/stream")
+		require.Nil(t, err)
+
+		<-pub1.Done()
+		require.Equal(t, ErrPublisherTakenOver, pub1.Err())
+		require.Equal(t, ErrPublisherTakenOver, pub1.WriteVideo(5200, bytes.NewReader(avcInterFrame)))
+
+		// Timestamps continue from the previous publisher
+		writeVideo(t, pub2, 0, avcSequenceHeader)
+		writeVideo(t, pub2, 0, avcKeyFrame)
+		require.Equal(t, []sinkEvent{
+			{kind: "video", timestamp: 100, payload: avcSequenceHeader},
+			{kind: "video", timestamp: 100, payload: avcKeyFrame},
+		}, waitEvents(t, sub, sink))
+	})
+}
+
+func TestPublisherCloseSendsEOF(t *testing.T) {
+	b := NewBroker(nil)
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+	writeVideo(t, pub, 0, avcSequenceHeader)
+	writeVideo(t, pub, 0, avcKeyFrame)
+
+	sink := &recordingSink{}
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+	waitEvents(t, sub, sink)
+
+	require.Nil(t, pub.Close())
+	<-pub.Done()
+	require.Equal(t, ErrPublisherClosed, pub.Err())
+	require.False(t, b.IsPublished("app/stream"))
+	require.Equal(t, []sinkEvent{
+		{kind: "eof"},
+	}, waitEvents(t, sub, sink))
+
+	// The subscriber waits for a next publisher, and the cache of the previous one is not sent
+	pub, err = b.Publish("app/stream")
+	require.Nil(t, err)
+	writeVideo(t, pub, 0, avcKeyFrame)
+	require.Equal(t, []sinkEvent{
+		{kind: "video", timestamp: 0, payload: avcKeyFrame},
+	}, waitEvents(t, sub, sink))
+
+	require.Nil(t, pub.Close())
+	require.Nil(t, sub.Close())
+
+	b.m.Lock()
+	require.Empty(t, b.channels)
+	b.m.Unlock()
+}
+
+func TestSubscriberIsRemoved(t *testing.T) {
+	b := NewBroker(nil)
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	closedSink := &recordingSink{}
+	closedSub, err := b.Subscribe("app/stream", closedSink)
+	require.Nil(t, err)
+
+	brokenSink := &recordingSink{err: errors.New("broken")}
+	brokenSub, err := b.Subscribe("app/stream", brokenSink)
+	require.Nil(t, err)
+
+	sink := &recordingSink{}
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+
+	require.Nil(t, closedSub.Close())
+	<-closedSub.Done()
+	require.Equal(t, ErrSubscriberClosed, closedSub.Err())
+
+	writeVideo(t, pub, 0, avcKeyFrame)
+	<-brokenSub.Done()
+	require.EqualError(t, brokenSub.Err(), "broken")
+
+	ch := b.channels["app/stream"]
+	ch.m.Lock()
+	require.Len(t, ch.subscribers, 1)
+	ch.m.Unlock()
+
+	require.Empty(t, waitEvents(t, closedSub, closedSink))
+	require.Len(t, waitEvents(t, sub, sink), 1)
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"sync"
+)
+
+// channel A named channel which has a publisher and subscribers.
+type channel struct {
+	broker *Broker
+	name   string
+
+	publisher   *Publisher
+	subscribers []*Subscriber
+	cache       *gopCache
+
+	m sync.Mutex
+}
+
+func newChannel(broker *Broker, name string) *channel {
+	return &channel{
+		broker: broker,
+		name:   name,
+
+		cache: newGOPCache(broker.config),
+	}
+}
+
+func (ch *channel) attachPublisher(policy TakeoverPolicy) (*Publisher, error) {
+	ch.m.Lock()
+	defer ch.m.Unlock()
+
+	if ch.publisher != nil {
+		if policy != TakeoverPolicyReplace {
+			return nil, ErrAlreadyPublished
+		}
+		ch.publisher.detach(ErrPublisherTakenOver)
+
+		for _, sub := range ch.subscribers {
+			sub.resetPublisher()
+		}
+	}
+
+	// Media of the previous publisher must not be sent to new subscribers
+	ch.cache.reset()
+
+	pub := newPublisher(ch)
+	ch.publisher = pub
+
+	return pub, nil
+}
+
+// detachPublisher Removes the publisher and notifies EOF to subscribers. ch.m must be locked by a caller.
+func (ch *channel) detachPublisher(pub *Publisher) {
+	if ch.publisher != pub {
+		return // Already taken over
+	}
+	ch.publisher = nil
+	ch.cache.reset()
+
+	ch.forEachSubscriber(func(sub *Subscriber) error {
+		return sub.sendEOF()
+	})
+}
+
+// attachSubscriber Attaches a subscriber and delivers cached packets to it.
+// It returns false if the subscriber has been removed because delivering cached packets failed.
+func (ch *channel) attachSubscriber(sink Sink) (*Subscriber, bool) {
+	ch.m.Lock()
+	defer ch.m.Unlock()
+
+	sub := newSubscriber(ch, sink)
+	ch.subscribers = append(ch.subscribers, sub)
+
+	if ch.publisher != nil {
+		for _, p := range ch.cache.packets() {
+			if err := sub.deliver(p); err != nil {
+				ch.removeSubscriber(sub, err)
+				return sub, false
+			}
+		}
+	}
+
+	return sub, true
+}
+
+// removeSubscriber Removes the subscriber from the channel. ch.m must be locked by a caller.
+func (ch *channel) removeSubscriber(sub *Subscriber, err error) {
+	for i, s := range ch.subscribers {
+		if s == sub {
+			ch.subscribers = append(ch.subscribers[:i], ch.subscribers[i+1:]...)
+			break
+		}
+	}
+	sub.detach(err)
+}
+
+// publish Caches the packet and delivers it to subscribers. Subscribers which failed to write are removed.
+func (ch *channel) publish(pub *Publisher, p *packet) error {
+	ch.m.Lock()
+	defer ch.m.Unlock()
+
+	if ch.publisher != pub {
+		return pub.Err()
+	}
+
+	ch.cache.add(p)
+	ch.forEachSubscriber(func(sub *Subscriber) error {
+		return sub.deliver(p)
+	})
+
+	return nil
+}
+
+// forEachSubscriber Calls f for each subscriber, and removes subscribers for which f failed. ch.m must be locked by a caller.
+func (ch *channel) forEachSubscriber(f func(sub *Subscriber) error) {
+	alive := ch.subscribers[:0]
+	for _, sub := range ch.subscribers {
+		if err := f(sub); err != nil {
+			sub.detach(err)
+			continue
+		}
+		alive = append(alive, sub)
+	}
+	for i := len(alive); i < len(ch.subscribers); i++ {
+		ch.subscribers[i] = nil // Not to leak removed subscribers
+	}
+	ch.subscribers = alive
+}
+
+func (ch *channel) isPublished() bool {
+	ch.m.Lock()
+	defer ch.m.Unlock()
+
+	return ch.publisher != nil
+}
+
+func (ch *channel) isUnused() bool {
+	ch.m.Lock()
+	defer ch.m.Unlock()
+
+	return ch.publisher == nil && len(ch.subscribers) == 0
+}
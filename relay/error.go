@@ -0,0 +1,18 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"github.com/pkg/errors"
+)
+
+var ErrAlreadyPublished = errors.New("Channel is already published")
+var ErrPublisherClosed = errors.New("Publisher is closed")
+var ErrPublisherTakenOver = errors.New("Publisher is taken over by another one")
+var ErrSubscriberClosed = errors.New("Subscriber is closed")
+var ErrSubscriberTooSlow = errors.New("Subscriber is too slow")
@@ -0,0 +1,160 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"bytes"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+type packetType int
+
+const (
+	packetTypeAudio packetType = iota
+	packetTypeVideo
+	packetTypeMetadata
+)
+
+// packet A media message which is shared by subscribers. It must not be modified after published.
+type packet struct {
+	ty        packetType
+	timestamp uint32
+	payload   []byte
+	metadata  map[string]interface{}
+
+	isSequenceHeader bool
+	isSequenceEnd    bool
+	isKeyFrame       bool
+}
+
+func newAudioPacket(timestamp uint32, payload []byte) *packet {
+	p := &packet{
+		ty:        packetTypeAudio,
+		timestamp: timestamp,
+		payload:   payload,
+	}
+
+	var h message.AudioTagHeader
+	if err := message.DecodeAudioTagHeader(bytes.NewReader(payload), &h); err == nil {
+		p.isSequenceHeader = h.IsSequenceHeader()
+		p.isSequenceEnd = h.IsExHeader() && h.PacketType == message.AudioPacketTypeSequenceEnd
+	}
+
+	return p
+}
+
+func newVideoPacket(timestamp uint32, payload []byte) *packet {
+	p := &packet{
+		ty:        packetTypeVideo,
+		timestamp: timestamp,
+		payload:   payload,
+	}
+
+	var h message.VideoTagHeader
+	if err := message.DecodeVideoTagHeader(bytes.NewReader(payload), &h); err == nil {
+		p.isSequenceHeader = h.IsSequenceHeader()
+		if h.IsExHeader {
+			p.isSequenceEnd = h.PacketType == message.VideoPacketTypeSequenceEnd
+			p.isKeyFrame = h.IsKeyFrame() &&
+				(h.PacketType == message.VideoPacketTypeCodedFrames || h.PacketType == message.VideoPacketTypeCodedFramesX)
+		} else {
+			p.isSequenceEnd = h.CodecID == message.VideoCodecIDAVC && h.AVCPacketType == message.AVCPacketTypeEndOfSequence
+			p.isKeyFrame = h.IsKeyFrame() && !p.isSequenceHeader && !p.isSequenceEnd
+		}
+	}
+
+	return p
+}
+
+func newMetadataPacket(metadata map[string]interface{}) *packet {
+	return &packet{
+		ty:       packetTypeMetadata,
+		metadata: metadata,
+	}
+}
+
+// gopCache Caches messages which are required by subscribers to start decoding in the middle of a stream:
+// the last metadata, the last sequence headers of audio and video, and messages since the last key frame.
+type gopCache struct {
+	disabled    bool
+	maxMessages int
+
+	metadata            *packet
+	audioSequenceHeader *packet
+	videoSequenceHeader *packet
+	gop                 []*packet // Starts with a key frame
+}
+
+func newGOPCache(config *BrokerConfig) *gopCache {
+	return &gopCache{
+		disabled:    config.DisableGOPCache,
+		maxMessages: config.MaxGOPCacheMessages,
+	}
+}
+
+func (c *gopCache) reset() {
+	c.metadata = nil
+	c.audioSequenceHeader = nil
+	c.videoSequenceHeader = nil
+	c.gop = nil
+}
+
+func (c *gopCache) add(p *packet) {
+	switch p.ty {
+	case packetTypeMetadata:
+		c.metadata = p
+		return
+
+	case packetTypeAudio:
+		if p.isSequenceHeader {
+			c.audioSequenceHeader = p
+			return
+		}
+		if p.isSequenceEnd {
+			c.audioSequenceHeader = nil
+			return
+		}
+
+	case packetTypeVideo:
+		if p.isSequenceHeader {
+			c.videoSequenceHeader = p
+			return
+		}
+		if p.isSequenceEnd {
+			c.videoSequenceHeader = nil
+			c.gop = nil
+			return
+		}
+		if p.isKeyFrame {
+			c.gop = make([]*packet, 0, cap(c.gop)) // Starts a new GOP
+		}
+	}
+
+	if c.disabled || (c.gop == nil && !p.isKeyFrame) {
+		return // Wait for a key frame
+	}
+
+	if len(c.gop) >= c.maxMessages {
+		c.gop = nil // Too large, drop until the next key frame
+		return
+	}
+	c.gop = append(c.gop, p)
+}
+
+// packets Returns cached packets in the order to be sent to a new subscriber.
+func (c *gopCache) packets() []*packet {
+	packets := make([]*packet, 0, 3+len(c.gop))
+	for _, p := range []*packet{c.metadata, c.audioSequenceHeader, c.videoSequenceHeader} {
+		if p != nil {
+			packets = append(packets, p)
+		}
+	}
+
+	return append(packets, c.gop...)
+}
@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/yutopp/go-rtmp"
+	"github.com/yutopp/go-rtmp/message"
+)
+
+var _ rtmp.Handler = (*Handler)(nil)
+
+// Handler An rtmp.Handler which relays streams through the Broker. It must be created for each connection.
+// Channels are named as "app/name", and queries of names (e.g. "name?token=...") are not included.
+type Handler struct {
+	rtmp.DefaultHandler
+	broker *Broker
+
+	conn *rtmp.Conn
+	app  string
+	pub  *Publisher
+	sub  *Subscriber
+}
+
+func NewHandler(broker *Broker) *Handler {
+	return &Handler{
+		broker: broker,
+	}
+}
+
+func (h *Handler) OnServe(conn *rtmp.Conn) {
+	h.conn = conn
+}
+
+func (h *Handler) OnConnect(timestamp uint32, cmd *message.NetConnectionConnect) error {
+	h.app = cmd.Command.App
+	return nil
+}
+
+func (h *Handler) OnPublish(_ *rtmp.StreamContext, timestamp uint32, cmd *message.NetStreamPublish) error {
+	if h.pub != nil || h.sub != nil {
+		return errors.New("Cannot publish on this connection")
+	}
+
+	pub, err := h.broker.Publish(h.channelName(cmd.PublishingName))
+	if err != nil {
+		return err
+	}
+	h.pub = pub
+
+	return nil
+}
+
+func (h *Handler) OnPlay(ctx *rtmp.StreamContext, timestamp uint32, cmd *message.NetStreamPlay) error {
+	if h.pub != nil || h.sub != nil {
+		return errors.New("Cannot play on this connection")
+	}
+
+	sub, err := h.broker.Subscribe(h.channelName(cmd.StreamName), ctx.PlaySession)
+	if err != nil {
+		return err
+	}
+	h.sub = sub
+
+	go h.watchSubscriber(sub, ctx.PlaySession)
+
+	return nil
+}
+
+func (h *Handler) OnSetDataFrame(timestamp uint32, data *message.NetStreamSetDataFrame) error {
+	if h.pub == nil {
+		return nil
+	}
+	return h.pub.WriteSetDataFrame(data)
+}
+
+func (h *Handler) OnAudio(timestamp uint32, payload io.Reader) error {
+	if h.pub == nil {
+		return nil
+	}
+	return h.pub.WriteAudio(timestamp, payload)
+}
+
+func (h *Handler) OnVideo(timestamp uint32, payload io.Reader) error {
+	if h.pub == nil {
+		return nil
+	}
+	return h.pub.WriteVideo(timestamp, payload)
+}
+
+func (h *Handler) OnClose() {
+	if h.pub != nil {
+		_ = h.pub.Close()
+	}
+
+	if h.sub != nil {
+		_ = h.sub.Close()
+	}
+}
+
+// watchSubscriber Disconnects the player if the subscriber is removed by the broker, e.g. by ErrSubscriberTooSlow or an error of the sink.
+// The player cannot continue playing because messages have been lost.
+func (h *Handler) watchSubscriber(sub *Subscriber, session *rtmp.PlaySession) {
+	<-sub.Done()
+	if sub.Err() == ErrSubscriberClosed {
+		return // Closed by OnClose
+	}
+
+	_ = session.Stop()
+	if h.conn != nil {
+		_ = h.conn.Close()
+	}
+}
+
+func (h *Handler) channelName(name string) string {
+	if i := strings.Index(name, "?"); i >= 0 {
+		name = name[:i]
+	}
+
+	return h.app + "/" + name
+}
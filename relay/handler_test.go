@@ -0,0 +1,77 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yutopp/go-rtmp"
+	"github.com/yutopp/go-rtmp/message"
+)
+
+func TestHandlerDisconnectsRemovedPlayer(t *testing.T) {
+	b := NewBroker(nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.Nil(t, err)
+
+	srv := rtmp.NewServer(&rtmp.ServerConfig{
+		OnConnect: func(conn net.Conn) (io.ReadWriteCloser, *rtmp.ConnConfig) {
+			return conn, &rtmp.ConnConfig{
+				Handler: NewHandler(b),
+			}
+		},
+	})
+	defer func() {
+		err := srv.Close()
+		require.Nil(t, err)
+	}()
+
+	go func() {
+		err := srv.Serve(l)
+		require.Equal(t, rtmp.ErrClosed, err)
+	}()
+
+	c, err := rtmp.Dial("rtmp", l.Addr().String(), nil)
+	require.Nil(t, err)
+	defer c.Close()
+
+	err = c.Connect(&message.NetConnectionConnect{
+		Command: message.NetConnectionConnectCommand{
+			App: "app",
+		},
+	})
+	require.Nil(t, err)
+
+	s, err := c.CreateStream(nil, 128)
+	require.Nil(t, err)
+
+	err = s.Play(&message.NetStreamPlay{
+		StreamName: "stream",
+	}, nil)
+	require.Nil(t, err)
+
+	b.m.Lock()
+	ch := b.channels["app/stream"]
+	b.m.Unlock()
+	ch.m.Lock()
+	require.Len(t, ch.subscribers, 1)
+	sub := ch.subscribers[0]
+	ch.m.Unlock()
+
+	// The player is disconnected because it cannot continue playing
+	sub.remove(ErrSubscriberTooSlow)
+	require.Eventually(t, func() bool {
+		return c.LastError() != nil
+	}, 3*time.Second, 10*time.Millisecond)
+}
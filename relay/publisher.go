@@ -0,0 +1,120 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yutopp/go-amf0"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+// Publisher A publisher of a channel. Methods return an error if it was closed or taken over.
+// Writes are not blocked by slow subscribers because messages are queued for each subscriber.
+type Publisher struct {
+	ch *channel
+
+	err  error
+	done chan struct{}
+	m    sync.Mutex
+}
+
+func newPublisher(ch *channel) *Publisher {
+	return &Publisher{
+		ch:   ch,
+		done: make(chan struct{}),
+	}
+}
+
+// WriteAudio Relays an audio message. The payload is copied, thus it can be reused after returned.
+func (p *Publisher) WriteAudio(timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+
+	return p.ch.publish(p, newAudioPacket(timestamp, data))
+}
+
+// WriteVideo Relays a video message. The payload is copied, thus it can be reused after returned.
+func (p *Publisher) WriteVideo(timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+
+	return p.ch.publish(p, newVideoPacket(timestamp, data))
+}
+
+// WriteMetadata Relays metadata. It is also sent to subscribers which come later.
+func (p *Publisher) WriteMetadata(metadata map[string]interface{}) error {
+	return p.ch.publish(p, newMetadataPacket(metadata))
+}
+
+// WriteSetDataFrame Relays metadata in "@setDataFrame" which is given to Handler.OnSetDataFrame.
+func (p *Publisher) WriteSetDataFrame(data *message.NetStreamSetDataFrame) error {
+	d := amf0.NewDecoder(bytes.NewReader(data.Payload))
+
+	var name string
+	if err := d.Decode(&name); err != nil {
+		return errors.Wrap(err, "Failed to decode a name of @setDataFrame")
+	}
+	if name != "onMetaData" {
+		return nil // Not metadata, ignore
+	}
+
+	var metadata map[string]interface{}
+	if err := d.Decode(&metadata); err != nil {
+		return errors.Wrap(err, "Failed to decode metadata of @setDataFrame")
+	}
+
+	return p.WriteMetadata(metadata)
+}
+
+// Close Stops publishing. EOF is sent to subscribers, and they wait for a next publisher.
+func (p *Publisher) Close() error {
+	ch := p.ch
+
+	ch.m.Lock()
+	ch.detachPublisher(p)
+	p.detach(ErrPublisherClosed)
+	ch.m.Unlock()
+
+	ch.broker.removeChannelIfUnused(ch)
+
+	return nil
+}
+
+// Done Returns a channel which is closed when the publisher is closed or taken over.
+func (p *Publisher) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err Returns ErrPublisherClosed or ErrPublisherTakenOver after Done is closed, otherwise nil.
+func (p *Publisher) Err() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.err
+}
+
+func (p *Publisher) detach(err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.err != nil {
+		return
+	}
+	p.err = err
+	close(p.done)
+}
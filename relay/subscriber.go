@@ -0,0 +1,366 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// Sink A destination of relayed media. *rtmp.PlaySession implements it.
+// Methods are called from a goroutine dedicated to each subscriber, thus a slow sink does not block others.
+type Sink interface {
+	WriteAudio(timestamp uint32, payload io.Reader) error
+	WriteVideo(timestamp uint32, payload io.Reader) error
+	WriteMetadata(metadata map[string]interface{}) error
+	SendEOF() error
+}
+
+// SubscriberStats Statistics of a subscriber.
+type SubscriberStats struct {
+	QueuedMessages       int           // Including a message being written
+	Lag                  time.Duration // How long the oldest queued message has been waiting
+	DroppedAudioMessages uint64
+	DroppedVideoMessages uint64
+}
+
+// Subscriber A subscriber of a channel. Messages are queued and written to the sink asynchronously.
+// If the queue is full, video is dropped until a next key frame first, and audio is dropped as the last resort.
+// It is removed from the channel when it is closed, its sink failed to write or it lagged behind too much.
+type Subscriber struct {
+	ch   *channel
+	sink Sink
+
+	maxQueueMessages int
+	maxLag           time.Duration
+
+	// Timestamps are rebased to start from 0, and continue across publishers
+	baseTimestamp    uint32 // A timestamp of the first message of the current publisher
+	baseTimestampSet bool
+	offsetTimestamp  uint32 // A rebased timestamp of the first message of the current publisher
+	lastTimestamp    uint32
+
+	waitingKeyFrame bool // Video is skipped until a key frame arrives
+	droppingVideo   bool // Video is skipped because the queue was full
+
+	queue    []*queueItem
+	inflight *queueItem
+	stats    SubscriberStats
+	notify   chan struct{}
+	qm       sync.Mutex
+
+	err  error
+	done chan struct{}
+	m    sync.Mutex
+}
+
+type queueItem struct {
+	p          *packet // nil if EOF
+	timestamp  uint32
+	enqueuedAt time.Time
+}
+
+// droppable Returns true if the item can be dropped without breaking a stream.
+func (i *queueItem) droppable() bool {
+	return i.p != nil && i.p.ty != packetTypeMetadata && !i.p.isSequenceHeader && !i.p.isSequenceEnd
+}
+
+func newSubscriber(ch *channel, sink Sink) *Subscriber {
+	s := &Subscriber{
+		ch:   ch,
+		sink: sink,
+
+		maxQueueMessages: ch.broker.config.MaxSubscriberQueueMessages,
+		maxLag:           ch.broker.config.MaxSubscriberLag,
+
+		waitingKeyFrame: true,
+
+		notify: make(chan struct{}, 1),
+
+		done: make(chan struct{}),
+	}
+	go s.runWriter()
+
+	return s
+}
+
+// Close Unsubscribes the channel. Queued messages are discarded.
+func (s *Subscriber) Close() error {
+	s.remove(ErrSubscriberClosed)
+
+	return nil
+}
+
+// Done Returns a channel which is closed when the subscriber is removed from the channel.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err Returns ErrSubscriberClosed, ErrSubscriberTooSlow or an error of the sink after Done is closed, otherwise nil.
+func (s *Subscriber) Err() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.err
+}
+
+// Stats Returns statistics of the subscriber.
+func (s *Subscriber) Stats() SubscriberStats {
+	s.qm.Lock()
+	defer s.qm.Unlock()
+
+	stats := s.stats
+	stats.QueuedMessages = len(s.queue)
+	if s.inflight != nil {
+		stats.QueuedMessages++
+	}
+	stats.Lag = s.lagLocked(time.Now())
+
+	return stats
+}
+
+// deliver Queues the packet. ch.m must be locked by a caller.
+func (s *Subscriber) deliver(p *packet) error {
+	switch p.ty {
+	case packetTypeMetadata:
+		return s.enqueue(&queueItem{p: p})
+
+	case packetTypeAudio:
+		return s.enqueue(&queueItem{p: p, timestamp: s.rebaseTimestamp(p)})
+
+	case packetTypeVideo:
+		if s.waitingKeyFrame && !p.isSequenceHeader && !p.isSequenceEnd {
+			if !p.isKeyFrame {
+				if s.droppingVideo {
+					s.qm.Lock()
+					s.stats.DroppedVideoMessages++
+					s.qm.Unlock()
+				}
+				return nil // Cannot be decoded
+			}
+			s.waitingKeyFrame = false
+			s.droppingVideo = false
+		}
+		return s.enqueue(&queueItem{p: p, timestamp: s.rebaseTimestamp(p)})
+
+	default:
+		panic("unreachable")
+	}
+}
+
+// sendEOF Notifies that the publisher has gone. ch.m must be locked by a caller.
+func (s *Subscriber) sendEOF() error {
+	s.resetPublisher()
+
+	return s.enqueue(&queueItem{})
+}
+
+// resetPublisher Prepares for a next publisher which continues from the last timestamp. ch.m must be locked by a caller.
+func (s *Subscriber) resetPublisher() {
+	s.baseTimestampSet = false
+	s.offsetTimestamp = s.lastTimestamp
+	s.waitingKeyFrame = true
+	s.droppingVideo = false
+}
+
+// rebaseTimestamp Rebases a timestamp on the first message since the publisher started. ch.m must be locked by a caller.
+func (s *Subscriber) rebaseTimestamp(p *packet) uint32 {
+	if !s.baseTimestampSet {
+		if p.isSequenceHeader {
+			return s.offsetTimestamp // Sent before the first frame
+		}
+		s.baseTimestamp = p.timestamp
+		s.baseTimestampSet = true
+	}
+
+	// Messages before the first one, e.g. audio just before a key frame, are regarded as the first one
+	diff := int32(p.timestamp - s.baseTimestamp)
+	if diff < 0 {
+		diff = 0
+	}
+	timestamp := s.offsetTimestamp + uint32(diff)
+
+	if int32(timestamp-s.lastTimestamp) > 0 {
+		s.lastTimestamp = timestamp
+	}
+
+	return timestamp
+}
+
+// enqueue Queues the item with dropping messages if the queue is full. ch.m must be locked by a caller.
+func (s *Subscriber) enqueue(item *queueItem) error {
+	s.qm.Lock()
+	defer s.qm.Unlock()
+
+	now := time.Now()
+	if s.maxLag > 0 && s.lagLocked(now) > s.maxLag {
+		return ErrSubscriberTooSlow
+	}
+	item.enqueuedAt = now
+
+	if item.droppable() && len(s.queue) >= s.maxQueueMessages {
+		// Video which depends on dropped frames cannot be decoded, thus video is dropped until a next key frame
+		if item.p.ty == packetTypeVideo && !item.p.isKeyFrame {
+			s.stats.DroppedVideoMessages++
+			s.waitingKeyFrame = true
+			s.droppingVideo = true
+			return nil
+		}
+
+		if s.dropQueuedLocked(packetTypeVideo, len(s.queue)) > 0 && item.p.ty == packetTypeAudio {
+			s.waitingKeyFrame = true
+			s.droppingVideo = true
+		}
+
+		if len(s.queue) >= s.maxQueueMessages {
+			if item.p.ty == packetTypeAudio {
+				s.stats.DroppedAudioMessages++
+				return nil
+			}
+			// Keep a key frame rather than old audio
+			s.dropQueuedLocked(packetTypeAudio, len(s.queue)-s.maxQueueMessages+1)
+		}
+	}
+
+	s.queue = append(s.queue, item)
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// dropQueuedLocked Drops at most n droppable queued messages of the type from the oldest. s.qm must be locked by a caller.
+func (s *Subscriber) dropQueuedLocked(ty packetType, n int) int {
+	dropped := 0
+	queue := s.queue[:0]
+	for _, item := range s.queue {
+		if dropped < n && item.droppable() && item.p.ty == ty {
+			dropped++
+			continue
+		}
+		queue = append(queue, item)
+	}
+	for i := len(queue); i < len(s.queue); i++ {
+		s.queue[i] = nil // Not to leak dropped messages
+	}
+	s.queue = queue
+
+	switch ty {
+	case packetTypeAudio:
+		s.stats.DroppedAudioMessages += uint64(dropped)
+	case packetTypeVideo:
+		s.stats.DroppedVideoMessages += uint64(dropped)
+	}
+
+	return dropped
+}
+
+// lagLocked Returns how long the oldest message has been waiting. s.qm must be locked by a caller.
+func (s *Subscriber) lagLocked(now time.Time) time.Duration {
+	oldest := s.inflight
+	if oldest == nil && len(s.queue) > 0 {
+		oldest = s.queue[0]
+	}
+	if oldest == nil {
+		return 0
+	}
+
+	return now.Sub(oldest.enqueuedAt)
+}
+
+func (s *Subscriber) runWriter() {
+	for {
+		item, ok := s.dequeue()
+		if !ok {
+			return
+		}
+
+		err := s.write(item)
+
+		s.qm.Lock()
+		s.inflight = nil
+		s.qm.Unlock()
+
+		if err != nil {
+			s.remove(err)
+			return
+		}
+	}
+}
+
+func (s *Subscriber) dequeue() (*queueItem, bool) {
+	for {
+		select {
+		case <-s.done:
+			return nil, false
+		default:
+		}
+
+		s.qm.Lock()
+		if len(s.queue) > 0 {
+			item := s.queue[0]
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+			s.inflight = item
+			s.qm.Unlock()
+
+			return item, true
+		}
+		s.qm.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return nil, false
+		}
+	}
+}
+
+func (s *Subscriber) write(item *queueItem) error {
+	if item.p == nil {
+		return s.sink.SendEOF()
+	}
+
+	switch item.p.ty {
+	case packetTypeMetadata:
+		return s.sink.WriteMetadata(item.p.metadata)
+	case packetTypeAudio:
+		return s.sink.WriteAudio(item.timestamp, bytes.NewReader(item.p.payload))
+	case packetTypeVideo:
+		return s.sink.WriteVideo(item.timestamp, bytes.NewReader(item.p.payload))
+	default:
+		panic("unreachable")
+	}
+}
+
+// remove Removes the subscriber from the channel.
+func (s *Subscriber) remove(err error) {
+	ch := s.ch
+
+	ch.m.Lock()
+	ch.removeSubscriber(s, err)
+	ch.m.Unlock()
+
+	ch.broker.removeChannelIfUnused(ch)
+}
+
+func (s *Subscriber) detach(err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	close(s.done)
+}
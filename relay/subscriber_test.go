@@ -0,0 +1,168 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package relay
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingSink A sink which blocks writes until released.
+type blockingSink struct {
+	recordingSink
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func newBlockingSink() *blockingSink {
+	return &blockingSink{
+		entered: make(chan struct{}, 1),
+		gate:    make(chan struct{}),
+	}
+}
+
+func (s *blockingSink) WriteAudio(timestamp uint32, payload io.Reader) error {
+	s.block()
+	return s.recordingSink.WriteAudio(timestamp, payload)
+}
+
+func (s *blockingSink) WriteVideo(timestamp uint32, payload io.Reader) error {
+	s.block()
+	return s.recordingSink.WriteVideo(timestamp, payload)
+}
+
+func (s *blockingSink) block() {
+	select {
+	case s.entered <- struct{}{}:
+	default:
+	}
+	<-s.gate
+}
+
+func (s *blockingSink) release() {
+	close(s.gate)
+}
+
+func TestSlowSubscriberDropsMessages(t *testing.T) {
+	b := NewBroker(&BrokerConfig{
+		MaxSubscriberQueueMessages: 4,
+	})
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	slowSink := newBlockingSink()
+	slowSub, err := b.Subscribe("app/stream", slowSink)
+	require.Nil(t, err)
+
+	writeVideo(t, pub, 0, avcKeyFrame)
+	<-slowSink.entered // Being written
+
+	writeVideo(t, pub, 10, avcInterFrame)
+	writeVideo(t, pub, 20, avcInterFrame)
+	writeAudio(t, pub, 30, aacRaw)
+	writeAudio(t, pub, 40, aacRaw)
+	writeVideo(t, pub, 50, avcInterFrame) // Full, dropped and video waits for a key frame
+	writeAudio(t, pub, 60, aacRaw)        // Full, queued video is dropped
+	writeVideo(t, pub, 70, avcInterFrame) // Waiting for a key frame
+	writeAudio(t, pub, 80, aacRaw)
+	writeAudio(t, pub, 90, aacRaw)       // Full, no video to drop, thus dropped
+	writeVideo(t, pub, 100, avcKeyFrame) // Full, the oldest audio is dropped
+
+	stats := slowSub.Stats()
+	require.Equal(t, 5, stats.QueuedMessages)
+	require.Equal(t, uint64(4), stats.DroppedVideoMessages)
+	require.Equal(t, uint64(2), stats.DroppedAudioMessages)
+
+	slowSink.release()
+	require.Equal(t, []sinkEvent{
+		{kind: "video", timestamp: 0, payload: avcKeyFrame},
+		{kind: "audio", timestamp: 40, payload: aacRaw},
+		{kind: "audio", timestamp: 60, payload: aacRaw},
+		{kind: "audio", timestamp: 80, payload: aacRaw},
+		{kind: "video", timestamp: 100, payload: avcKeyFrame},
+	}, waitEvents(t, slowSub, &slowSink.recordingSink))
+}
+
+func TestSlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	b := NewBroker(nil)
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	slowSink := newBlockingSink()
+	defer slowSink.release()
+
+	_, err = b.Subscribe("app/stream", slowSink)
+	require.Nil(t, err)
+
+	sink := &recordingSink{}
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+
+	writeVideo(t, pub, 0, avcKeyFrame)
+	<-slowSink.entered
+
+	for i := 1; i <= 10; i++ {
+		writeVideo(t, pub, uint32(i*10), avcInterFrame)
+	}
+
+	require.Len(t, waitEvents(t, sub, sink), 11)
+	require.Equal(t, SubscriberStats{}, sub.Stats())
+}
+
+func TestSlowSubscriberKeepsSequenceHeaders(t *testing.T) {
+	b := NewBroker(&BrokerConfig{
+		MaxSubscriberQueueMessages: 1,
+	})
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	sink := newBlockingSink()
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+
+	writeVideo(t, pub, 0, avcKeyFrame)
+	<-sink.entered
+
+	writeAudio(t, pub, 0, aacRaw)
+	writeAudio(t, pub, 0, aacSequenceHeader)
+	writeVideo(t, pub, 0, avcSequenceHeader)
+	require.Nil(t, pub.WriteMetadata(map[string]interface{}{}))
+
+	require.Equal(t, 5, sub.Stats().QueuedMessages)
+}
+
+func TestSlowSubscriberIsRemoved(t *testing.T) {
+	b := NewBroker(&BrokerConfig{
+		MaxSubscriberLag: 50 * time.Millisecond,
+	})
+
+	pub, err := b.Publish("app/stream")
+	require.Nil(t, err)
+
+	sink := newBlockingSink()
+	defer sink.release()
+
+	sub, err := b.Subscribe("app/stream", sink)
+	require.Nil(t, err)
+
+	writeVideo(t, pub, 0, avcKeyFrame)
+	<-sink.entered
+
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, sub.Stats().Lag >= 50*time.Millisecond)
+
+	writeVideo(t, pub, 10, avcInterFrame)
+	<-sub.Done()
+	require.Equal(t, ErrSubscriberTooSlow, sub.Err())
+}
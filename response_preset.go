@@ -32,6 +32,16 @@ func NewDefaultResponsePreset() *DefaultResponsePreset {
 			FMSVer:       "GO-RTMP/0,0,0,0", // TODO: fix
 			Capabilities: 31,                // TODO: fix
 			Mode:         1,                 // TODO: fix
+			// Payloads of these codecs are passed through (Enhanced RTMP)
+			FourCCList: []message.FourCC{
+				message.FourCCAV1,
+				message.FourCCVP9,
+				message.FourCCHEVC,
+				message.FourCCOpus,
+				message.FourCCFLAC,
+				message.FourCCAC3,
+				message.FourCCEAC3,
+			},
 		},
 		// Sent to clients as result when Connect message is received
 		ServerConnectResultData: map[string]interface{}{
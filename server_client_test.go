@@ -8,15 +8,21 @@
 package rtmp
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/yutopp/go-amf0"
 
+	"github.com/yutopp/go-rtmp/handshake"
 	"github.com/yutopp/go-rtmp/message"
 )
 
@@ -63,6 +69,7 @@ func TestServerCanRejectConnect(t *testing.T) {
 					FMSVer:       "GO-RTMP/0,0,0,0",
 					Capabilities: 31,
 					Mode:         1,
+					FourCCList:   []message.FourCC{"av01", "vp09", "hvc1", "Opus", "fLaC", "ac-3", "ec-3"},
 				},
 				Information: message.NetConnectionConnectResultInformation{
 					Level:       "error",
@@ -75,6 +82,40 @@ func TestServerCanRejectConnect(t *testing.T) {
 	})
 }
 
+type serverCanGetPeerVersionHandler struct {
+	DefaultHandler
+	conn      *Conn
+	versionCh chan [4]byte
+}
+
+func (h *serverCanGetPeerVersionHandler) OnServe(conn *Conn) {
+	h.conn = conn
+}
+
+func (h *serverCanGetPeerVersionHandler) OnConnect(_ uint32, _ *message.NetConnectionConnect) error {
+	h.versionCh <- h.conn.PeerVersion()
+	return nil
+}
+
+func TestServerCanGetPeerVersion(t *testing.T) {
+	handler := &serverCanGetPeerVersionHandler{
+		versionCh: make(chan [4]byte, 1),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		require.Equal(t, handshake.ComplexClientVersion, <-handler.versionCh)
+		require.Equal(t, handshake.ModeComplex, c.conn.HandshakeMode())
+		require.Equal(t, handshake.ComplexServerVersion, c.conn.PeerVersion())
+	})
+}
+
 type serverCanAcceptCreateStreamHandler struct {
 	DefaultHandler
 }
@@ -99,15 +140,189 @@ func TestServerCanAcceptCreateStream(t *testing.T) {
 		// Rejected because a number of message streams is exceeded the limits
 		s1, err := c.CreateStream(nil, chunkSize)
 		require.Equal(t, &CreateStreamRejectedError{
-			TransactionID: 2,
+			TransactionID: 3, // 1: connect, 2: the first createStream
 			Result: &message.NetConnectionCreateStreamResult{
 				StreamID: 0,
 			},
 		}, err)
-		defer s1.Close()
+		require.Nil(t, s1)
+	})
+}
+
+type serverCanNegotiateAMF3Handler struct {
+	DefaultHandler
+	conn *Conn
+}
+
+func (h *serverCanNegotiateAMF3Handler) OnServe(conn *Conn) {
+	h.conn = conn
+}
+
+func (h *serverCanNegotiateAMF3Handler) OnCreateStream(_ uint32, _ *message.NetConnectionCreateStream) error {
+	if h.conn.streams.encTy != message.EncodingTypeAMF3 {
+		return fmt.Errorf("AMF3 is not negotiated")
+	}
+	return nil
+}
+
+func TestServerCanNegotiateAMF3(t *testing.T) {
+	config := &ConnConfig{
+		Handler: &serverCanNegotiateAMF3Handler{},
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(&message.NetConnectionConnect{
+			Command: message.NetConnectionConnectCommand{
+				ObjectEncoding: message.EncodingTypeAMF3,
+			},
+		})
+		require.Nil(t, err)
+
+		// Commands are sent as AMF3 messages
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+		require.Equal(t, message.EncodingTypeAMF3, s.encodingType())
+
+		err = c.DeleteStream(&message.NetStreamDeleteStream{
+			StreamID: s.streamID,
+		})
+		require.Nil(t, err)
+	})
+}
+
+type serverCanDelayConnectHandler struct {
+	DefaultHandler
+	releaseCh chan struct{}
+}
+
+func (h *serverCanDelayConnectHandler) OnConnect(_ uint32, _ *message.NetConnectionConnect) error {
+	<-h.releaseCh
+	return nil
+}
+
+func TestClientCanTimeoutConnect(t *testing.T) {
+	releaseCh := make(chan struct{})
+	config := &ConnConfig{
+		Handler: &serverCanDelayConnectHandler{
+			releaseCh: releaseCh,
+		},
+		Logger: logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		defer close(releaseCh)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := c.ConnectContext(ctx, nil)
+		require.Equal(t, &TransactionTimeoutError{
+			CommandName:   "connect",
+			TransactionID: 1,
+			Err:           context.DeadlineExceeded,
+		}, err)
+		require.True(t, err.(*TransactionTimeoutError).Timeout())
+
+		// The pending transaction is removed
+		s, err := c.conn.streams.At(ControlStreamID)
+		require.Nil(t, err)
+		_, err = s.transactions.At(1)
+		require.Error(t, err)
+	})
+}
+
+func TestClientCanCreateStreamsConcurrently(t *testing.T) {
+	config := &ConnConfig{
+		Handler: &serverCanAcceptCreateStreamHandler{},
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		const N = 3
+		streamCh := make(chan *Stream, N)
+		errCh := make(chan error, N)
+		for i := 0; i < N; i++ {
+			go func() {
+				s, err := c.CreateStream(nil, chunkSize)
+				errCh <- err
+				streamCh <- s
+			}()
+		}
+
+		streamIDs := make(map[uint32]struct{})
+		for i := 0; i < N; i++ {
+			require.Nil(t, <-errCh)
+			streamIDs[(<-streamCh).StreamID()] = struct{}{}
+		}
+		require.Len(t, streamIDs, N)
 	})
 }
 
+type serverCanRejectCreateStreamHandler struct {
+	DefaultHandler
+}
+
+func (h *serverCanRejectCreateStreamHandler) OnCreateStream(_ uint32, _ *message.NetConnectionCreateStream) error {
+	return fmt.Errorf("Reject")
+}
+
+func TestClientCanCall(t *testing.T) {
+	testCases := []struct {
+		name           string
+		handler        Handler
+		expectedResult []interface{}
+		expectedErr    error
+	}{
+		{
+			name:           "_result",
+			handler:        &serverCanAcceptCreateStreamHandler{},
+			expectedResult: []interface{}{nil, float64(1)},
+		},
+		{
+			name:    "_error",
+			handler: &serverCanRejectCreateStreamHandler{},
+			expectedErr: &CallRejectedError{
+				CommandName:   "createStream",
+				TransactionID: 2,
+				Result:        []interface{}{nil, float64(0)},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &ConnConfig{
+				Handler: tc.handler,
+				Logger:  logrus.StandardLogger(),
+			}
+
+			prepareConnection(t, config, func(c *ClientConn) {
+				err := c.Connect(nil)
+				require.Nil(t, err)
+
+				s, err := c.conn.streams.At(ControlStreamID)
+				require.Nil(t, err)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				result, err := s.Call(ctx, "createStream", nil)
+				require.Equal(t, tc.expectedErr, err)
+				require.Equal(t, tc.expectedResult, result)
+			})
+		})
+	}
+}
+
 type serverCanAcceptDeleteStreamHandler struct {
 	DefaultHandler
 }
@@ -145,8 +360,814 @@ func TestServerCanAcceptDeleteStream(t *testing.T) {
 	})
 }
 
+type playedStream struct {
+	conn     *Conn
+	streamID uint32
+	session  *PlaySession
+}
+
+type serverCanAcceptPlayHandler struct {
+	DefaultHandler
+	conn   *Conn
+	playCh chan playedStream
+}
+
+func (h *serverCanAcceptPlayHandler) OnServe(conn *Conn) {
+	h.conn = conn
+}
+
+func (h *serverCanAcceptPlayHandler) OnPlay(ctx *StreamContext, _ uint32, _ *message.NetStreamPlay) error {
+	h.playCh <- playedStream{conn: h.conn, streamID: ctx.StreamID, session: ctx.PlaySession}
+	return nil
+}
+
+type clientPlayRecorder struct {
+	eventCh chan string
+}
+
+func (h *clientPlayRecorder) OnStreamBegin(timestamp uint32) error {
+	h.eventCh <- "StreamBegin"
+	return nil
+}
+
+func (h *clientPlayRecorder) OnStreamEOF(timestamp uint32) error {
+	h.eventCh <- "StreamEOF"
+	return nil
+}
+
+func (h *clientPlayRecorder) OnAudio(timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	h.eventCh <- fmt.Sprintf("Audio: %d, %s", timestamp, data)
+	return nil
+}
+
+func (h *clientPlayRecorder) OnVideo(timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	h.eventCh <- fmt.Sprintf("Video: %d, %s", timestamp, data)
+	return nil
+}
+
+func (h *clientPlayRecorder) OnMetaData(timestamp uint32, data *message.NetStreamOnMetaData) error {
+	h.eventCh <- fmt.Sprintf("MetaData: %v", data.MetaData)
+	return nil
+}
+
+func TestClientCanPlay(t *testing.T) {
+	handler := &serverCanAcceptPlayHandler{
+		playCh: make(chan playedStream, 1),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		recorder := &clientPlayRecorder{
+			eventCh: make(chan string, 5),
+		}
+		err = s.Play(&message.NetStreamPlay{
+			StreamName: "theStream",
+			StartMode:  message.NetStreamPlayStartModeLiveOrRecorded,
+		}, recorder)
+		require.Nil(t, err)
+
+		var played playedStream
+		select {
+		case played = <-handler.playCh:
+		case <-time.After(3 * time.Second):
+			require.FailNow(t, "Play is not received")
+		}
+		require.Equal(t, s.streamID, played.streamID)
+
+		require.Equal(t, streamStateClientPlay, s.handler.State())
+
+		// StreamBegin is sent by the server when the play is accepted
+		session := played.session
+		err = session.WriteMetadata(map[string]interface{}{"width": float64(1280)})
+		require.Nil(t, err)
+		err = session.WriteAudio(10, bytes.NewReader([]byte("audio")))
+		require.Nil(t, err)
+		err = session.WriteVideo(20, bytes.NewReader([]byte("video")))
+		require.Nil(t, err)
+		err = session.SendEOF()
+		require.Nil(t, err)
+
+		events := make([]string, 0, 5)
+		for i := 0; i < 5; i++ {
+			select {
+			case event := <-recorder.eventCh:
+				events = append(events, event)
+			case <-time.After(3 * time.Second):
+				require.FailNow(t, "Events are not received", "Events = %v", events)
+			}
+		}
+		// Messages on different chunk streams may be reordered
+		require.ElementsMatch(t, []string{
+			"StreamBegin",
+			"MetaData: map[width:1280]",
+			"Audio: 10, audio",
+			"Video: 20, video",
+			"StreamEOF",
+		}, events)
+
+		// The player returns to inactive by NetStream.Play.Stop
+		err = session.Stop()
+		require.Nil(t, err)
+		require.Eventually(t, func() bool {
+			return s.handler.State() == streamStateClientInactive
+		}, 3*time.Second, 10*time.Millisecond)
+
+		serverStream, err := played.conn.streams.At(played.streamID)
+		require.Nil(t, err)
+		require.Equal(t, streamStateServerInactive, serverStream.handler.State())
+
+		err = session.WriteAudio(30, bytes.NewReader([]byte("audio")))
+		require.EqualError(t, err, "Play session is stopped")
+	})
+}
+
+type serverWritesMediaInPlayHandler struct {
+	DefaultHandler
+}
+
+func (h *serverWritesMediaInPlayHandler) OnPlay(ctx *StreamContext, _ uint32, _ *message.NetStreamPlay) error {
+	session := ctx.PlaySession
+	if err := session.WriteAudio(10, bytes.NewReader([]byte("audio"))); err != nil {
+		return err
+	}
+
+	go func() {
+		_ = session.WriteVideo(20, bytes.NewReader([]byte("video")))
+	}()
+
+	return nil
+}
+
+func TestClientCanPlayMediaWrittenInOnPlay(t *testing.T) {
+	config := &ConnConfig{
+		Handler: &serverWritesMediaInPlayHandler{},
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		recorder := &clientPlayRecorder{
+			eventCh: make(chan string, 3),
+		}
+		err = s.Play(&message.NetStreamPlay{
+			StreamName: "theStream",
+		}, recorder)
+		require.Nil(t, err)
+
+		// Media are passed to the recorder only after NetStream.Play.Start is received,
+		// thus they are dropped if they preceded it
+		events := make([]string, 0, 3)
+		for i := 0; i < 3; i++ {
+			select {
+			case event := <-recorder.eventCh:
+				events = append(events, event)
+			case <-time.After(3 * time.Second):
+				require.FailNow(t, "Events are not received", "Events = %v", events)
+			}
+		}
+		require.ElementsMatch(t, []string{
+			"StreamBegin",
+			"Audio: 10, audio",
+			"Video: 20, video",
+		}, events)
+	})
+}
+
+type serverCanRejectPlayHandler struct {
+	DefaultHandler
+	sessionCh chan *PlaySession
+}
+
+func (h *serverCanRejectPlayHandler) OnPlay(ctx *StreamContext, _ uint32, _ *message.NetStreamPlay) error {
+	h.sessionCh <- ctx.PlaySession
+	return fmt.Errorf("Reject")
+}
+
+func TestClientPlayIsRejected(t *testing.T) {
+	handler := &serverCanRejectPlayHandler{
+		sessionCh: make(chan *PlaySession, 1),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		err = s.Play(&message.NetStreamPlay{
+			StreamName: "theStream",
+		}, nil)
+		require.Equal(t, &PlayRejectedError{
+			Result: &message.NetStreamOnStatus{
+				InfoObject: message.NetStreamOnStatusInfoObject{
+					Level:       message.NetStreamOnStatusLevelError,
+					Code:        message.NetStreamOnStatusCodePlayFailed,
+					Description: "Play failed.",
+				},
+			},
+		}, err)
+		require.Equal(t, streamStateClientInactive, s.handler.State())
+
+		// The session of the rejected request cannot be used
+		session := <-handler.sessionCh
+		err = session.WriteAudio(0, bytes.NewReader([]byte("audio")))
+		require.EqualError(t, err, "Play session is stopped")
+	})
+}
+
+type serverStopsPlayInPlayHandler struct {
+	DefaultHandler
+	isStopped bool // Only the first request is stopped
+	stopCh    chan error
+}
+
+func (h *serverStopsPlayInPlayHandler) OnPlay(ctx *StreamContext, _ uint32, _ *message.NetStreamPlay) error {
+	if h.isStopped {
+		return nil
+	}
+	h.isStopped = true
+
+	h.stopCh <- ctx.PlaySession.Stop()
+	// The stopped session cannot be used even if it is not started
+	h.stopCh <- ctx.PlaySession.WriteAudio(0, bytes.NewReader([]byte("audio")))
+
+	return nil
+}
+
+func TestClientPlayIsStoppedInOnPlay(t *testing.T) {
+	handler := &serverStopsPlayInPlayHandler{
+		stopCh: make(chan error, 2),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		recorder := &clientPlayRecorder{
+			eventCh: make(chan string, 5),
+		}
+
+		// The session is never started, thus neither NetStream.Play.Start nor NetStream.Play.Stop is sent
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		err = s.PlayContext(ctx, &message.NetStreamPlay{
+			StreamName: "theStream",
+		}, recorder)
+		require.IsType(t, &TransactionTimeoutError{}, err)
+		require.Nil(t, <-handler.stopCh)
+		require.EqualError(t, <-handler.stopCh, "Play session is stopped")
+		require.Len(t, recorder.eventCh, 0)
+
+		// The stream is still inactive, thus it can play again
+		err = s.Play(&message.NetStreamPlay{
+			StreamName: "theStream",
+		}, recorder)
+		require.Nil(t, err)
+		require.Equal(t, "StreamBegin", <-recorder.eventCh)
+	})
+}
+
+func TestClientCanReceiveStatusAsynchronously(t *testing.T) {
+	handler := &serverCanAcceptPlayHandler{
+		playCh: make(chan playedStream, 1),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		err = s.Play(&message.NetStreamPlay{
+			StreamName: "theStream",
+		}, nil)
+		require.Nil(t, err)
+
+		// Set after NetStream.Play.Reset which is sent before NetStream.Play.Start
+		statusCh := make(chan *message.NetStreamOnStatus, 1)
+		s.SetStatusHandler(func(_ uint32, status *message.NetStreamOnStatus) {
+			statusCh <- status
+		})
+
+		played := <-handler.playCh
+		serverStream, err := played.conn.streams.At(played.streamID)
+		require.Nil(t, err)
+
+		status := &message.NetStreamOnStatus{
+			InfoObject: message.NetStreamOnStatusInfoObject{
+				Level:       message.NetStreamOnStatusLevelStatus,
+				Code:        message.NetStreamOnStatusCodePlayUnpublishNotify,
+				Description: "Unpublished.",
+			},
+		}
+		err = serverStream.NotifyStatus(3, 0, status)
+		require.Nil(t, err)
+
+		select {
+		case actual := <-statusCh:
+			require.Equal(t, status, actual)
+		case <-time.After(3 * time.Second):
+			require.FailNow(t, "Status is not received")
+		}
+	})
+}
+
+var _ PlayControlHandler = (*serverCanControlPlayHandler)(nil)
+
+type serverCanControlPlayHandler struct {
+	serverCanAcceptPlayHandler
+	controlCh chan string
+}
+
+func (h *serverCanControlPlayHandler) OnPause(ctx *StreamContext, _ uint32, cmd *message.NetStreamPause) error {
+	h.controlCh <- fmt.Sprintf("Pause: %t, %d, %t", cmd.Pause, cmd.Milliseconds, ctx.PlaySession != nil)
+	return nil
+}
+
+func (h *serverCanControlPlayHandler) OnSeek(_ *StreamContext, _ uint32, cmd *message.NetStreamSeek) error {
+	if cmd.Milliseconds < 0 {
+		return errors.New("Invalid time")
+	}
+	h.controlCh <- fmt.Sprintf("Seek: %d", cmd.Milliseconds)
+	return nil
+}
+
+func (h *serverCanControlPlayHandler) OnReceiveAudio(_ *StreamContext, _ uint32, cmd *message.NetStreamReceiveAudio) error {
+	h.controlCh <- fmt.Sprintf("ReceiveAudio: %t", cmd.Receive)
+	return nil
+}
+
+func (h *serverCanControlPlayHandler) OnReceiveVideo(_ *StreamContext, _ uint32, cmd *message.NetStreamReceiveVideo) error {
+	h.controlCh <- fmt.Sprintf("ReceiveVideo: %t", cmd.Receive)
+	return nil
+}
+
+func TestClientCanControlPlay(t *testing.T) {
+	handler := &serverCanControlPlayHandler{
+		serverCanAcceptPlayHandler: serverCanAcceptPlayHandler{
+			playCh: make(chan playedStream, 1),
+		},
+		controlCh: make(chan string, 1),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		err = s.Play(&message.NetStreamPlay{
+			StreamName: "theStream",
+		}, nil)
+		require.Nil(t, err)
+		<-handler.playCh
+
+		err = s.Pause(&message.NetStreamPause{
+			Pause:        true,
+			Milliseconds: 1000,
+		})
+		require.Nil(t, err)
+		require.Equal(t, "Pause: true, 1000, true", <-handler.controlCh)
+
+		err = s.Pause(&message.NetStreamPause{
+			Pause:        false,
+			Milliseconds: 1000,
+		})
+		require.Nil(t, err)
+		require.Equal(t, "Pause: false, 1000, true", <-handler.controlCh)
+
+		err = s.Seek(&message.NetStreamSeek{
+			Milliseconds: 2000,
+		})
+		require.Nil(t, err)
+		require.Equal(t, "Seek: 2000", <-handler.controlCh)
+
+		err = s.Seek(&message.NetStreamSeek{
+			Milliseconds: -1,
+		})
+		require.Equal(t, &SeekRejectedError{
+			Result: &message.NetStreamOnStatus{
+				InfoObject: message.NetStreamOnStatusInfoObject{
+					Level:       message.NetStreamOnStatusLevelError,
+					Code:        message.NetStreamOnStatusCodeSeekFailed,
+					Description: "Seek failed.",
+				},
+			},
+		}, err)
+
+		statusCh := make(chan message.NetStreamOnStatusCode, 2)
+		s.SetStatusHandler(func(_ uint32, status *message.NetStreamOnStatus) {
+			statusCh <- status.InfoObject.Code
+		})
+
+		// Nothing is replied if receiving is disabled
+		err = s.ReceiveVideo(&message.NetStreamReceiveVideo{
+			Receive: false,
+		})
+		require.Nil(t, err)
+		require.Equal(t, "ReceiveVideo: false", <-handler.controlCh)
+
+		err = s.ReceiveAudio(&message.NetStreamReceiveAudio{
+			Receive: true,
+		})
+		require.Nil(t, err)
+		require.Equal(t, "ReceiveAudio: true", <-handler.controlCh)
+		require.Equal(t, message.NetStreamOnStatusCodeSeekNotify, <-statusCh)
+		require.Equal(t, message.NetStreamOnStatusCodePlayStart, <-statusCh)
+	})
+}
+
+type serverCanRejectPublishHandler struct {
+	DefaultHandler
+}
+
+func TestClientCanControlPlayWithoutPlayControlHandler(t *testing.T) {
+	handler := &serverCanAcceptPlayHandler{
+		playCh: make(chan playedStream, 1),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		err = s.Play(&message.NetStreamPlay{
+			StreamName: "theStream",
+		}, nil)
+		require.Nil(t, err)
+		<-handler.playCh
+
+		// Requests are accepted by default
+		err = s.Pause(&message.NetStreamPause{
+			Pause:        true,
+			Milliseconds: 1000,
+		})
+		require.Nil(t, err)
+
+		err = s.Seek(&message.NetStreamSeek{
+			Milliseconds: -1,
+		})
+		require.Nil(t, err)
+	})
+}
+
+func (h *serverCanRejectPublishHandler) OnPublish(_ *StreamContext, _ uint32, _ *message.NetStreamPublish) error {
+	return fmt.Errorf("Reject")
+}
+
+type serverCanDelayPublishHandler struct {
+	DefaultHandler
+	releaseCh chan struct{}
+}
+
+func (h *serverCanDelayPublishHandler) OnPublish(_ *StreamContext, _ uint32, _ *message.NetStreamPublish) error {
+	<-h.releaseCh
+	return nil
+}
+
+func TestClientCanPublish(t *testing.T) {
+	type testCase struct {
+		name          string
+		handler       Handler
+		timeout       time.Duration
+		expectedErr   error
+		expectedState streamState
+	}
+
+	releaseCh := make(chan struct{})
+
+	testCases := []testCase{
+		{
+			name:          "Accepted",
+			handler:       &DefaultHandler{},
+			timeout:       3 * time.Second,
+			expectedErr:   nil,
+			expectedState: streamStateClientPublish,
+		},
+		{
+			name:    "Rejected",
+			handler: &serverCanRejectPublishHandler{},
+			timeout: 3 * time.Second,
+			expectedErr: &PublishRejectedError{
+				Result: &message.NetStreamOnStatus{
+					InfoObject: message.NetStreamOnStatusInfoObject{
+						Level:       message.NetStreamOnStatusLevelError,
+						Code:        message.NetStreamOnStatusCodePublishFailed,
+						Description: "Publish failed.",
+					},
+				},
+			},
+			expectedState: streamStateClientInactive,
+		},
+		{
+			name: "Timeout",
+			handler: &serverCanDelayPublishHandler{
+				releaseCh: releaseCh,
+			},
+			timeout: 100 * time.Millisecond,
+			expectedErr: &TransactionTimeoutError{
+				CommandName:   "publish",
+				TransactionID: 0,
+				Err:           context.DeadlineExceeded,
+			},
+			expectedState: streamStateClientInactive,
+		},
+	}
+
+	// Wait for parallel tests to release the delayed handler after all of them finished
+	t.Run("group", func(t *testing.T) {
+		for _, tc := range testCases {
+			tc := tc // capture
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				config := &ConnConfig{
+					Handler: tc.handler,
+					Logger:  logrus.StandardLogger(),
+				}
+
+				prepareConnection(t, config, func(c *ClientConn) {
+					err := c.Connect(nil)
+					require.Nil(t, err)
+
+					s, err := c.CreateStream(nil, chunkSize)
+					require.Nil(t, err)
+					defer s.Close()
+
+					ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+					defer cancel()
+
+					err = s.PublishContext(ctx, &message.NetStreamPublish{
+						PublishingName: "theStream",
+						PublishingType: "live",
+					})
+					require.Equal(t, tc.expectedErr, err)
+					require.Equal(t, tc.expectedState, s.handler.State())
+				})
+			})
+		}
+	})
+	close(releaseCh)
+}
+
+type serverCanReceiveMediaHandler struct {
+	DefaultHandler
+	eventCh chan string
+}
+
+func (h *serverCanReceiveMediaHandler) OnSetDataFrame(timestamp uint32, data *message.NetStreamSetDataFrame) error {
+	d := amf0.NewDecoder(bytes.NewReader(data.Payload))
+
+	var name string
+	if err := d.Decode(&name); err != nil {
+		return err
+	}
+	var metadata map[string]interface{}
+	if err := d.Decode(&metadata); err != nil {
+		return err
+	}
+
+	h.eventCh <- fmt.Sprintf("SetDataFrame: %d, %s, %v", timestamp, name, metadata)
+	return nil
+}
+
+func (h *serverCanReceiveMediaHandler) OnAudio(timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	h.eventCh <- fmt.Sprintf("Audio: %d, %s", timestamp, data)
+	return nil
+}
+
+func (h *serverCanReceiveMediaHandler) OnVideo(timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	h.eventCh <- fmt.Sprintf("Video: %d, %s", timestamp, data)
+	return nil
+}
+
+func TestClientCanWriteMedia(t *testing.T) {
+	handler := &serverCanReceiveMediaHandler{
+		eventCh: make(chan string, 5),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnection(t, config, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+
+		err = s.Publish(&message.NetStreamPublish{
+			PublishingName: "theStream",
+			PublishingType: "live",
+		})
+		require.Nil(t, err)
+
+		err = s.WriteMetadata(map[string]interface{}{"width": float64(1280)})
+		require.Nil(t, err)
+		err = s.WriteAudio(10, bytes.NewReader([]byte("audio0")))
+		require.Nil(t, err)
+		err = s.WriteVideo(20, bytes.NewReader([]byte("video0")))
+		require.Nil(t, err)
+		err = s.WriteAudio(5, bytes.NewReader([]byte("audio1"))) // Reversed
+		require.Nil(t, err)
+		err = s.WriteVideo(30, bytes.NewReader([]byte("video1")))
+		require.Nil(t, err)
+
+		// Each types of media are written to dedicated chunk streams
+		chunkStreamIDs := []int{s.dataTrack.chunkStreamID, s.audioTrack.chunkStreamID, s.videoTrack.chunkStreamID}
+		require.Equal(t, []int{4, 5, 6}, chunkStreamIDs)
+
+		events := make([]string, 0, 5)
+		for i := 0; i < 5; i++ {
+			select {
+			case event := <-handler.eventCh:
+				events = append(events, event)
+			case <-time.After(3 * time.Second):
+				require.FailNow(t, "Events are not received", "Events = %v", events)
+			}
+		}
+		// Messages on different chunk streams may be reordered
+		require.ElementsMatch(t, []string{
+			"SetDataFrame: 0, onMetaData, map[width:1280]",
+			"Audio: 10, audio0",
+			"Video: 20, video0",
+			"Audio: 10, audio1",
+			"Video: 30, video1",
+		}, events)
+	})
+}
+
+type serverCanRecordURLHandler struct {
+	DefaultHandler
+	eventCh chan string
+}
+
+func (h *serverCanRecordURLHandler) OnConnect(_ uint32, cmd *message.NetConnectionConnect) error {
+	h.eventCh <- fmt.Sprintf("Connect: %s, %s", cmd.Command.App, cmd.Command.TCURL)
+	return nil
+}
+
+func (h *serverCanRecordURLHandler) OnPublish(_ *StreamContext, _ uint32, cmd *message.NetStreamPublish) error {
+	h.eventCh <- fmt.Sprintf("Publish: %s, %s", cmd.PublishingName, cmd.PublishingType)
+	return nil
+}
+
+func (h *serverCanRecordURLHandler) OnPlay(_ *StreamContext, _ uint32, cmd *message.NetStreamPlay) error {
+	h.eventCh <- fmt.Sprintf("Play: %s, %s", cmd.StreamName, cmd.StartMode)
+	return nil
+}
+
+func TestClientCanOpenStreamsByURL(t *testing.T) {
+	handler := &serverCanRecordURLHandler{
+		eventCh: make(chan string, 4),
+	}
+	config := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareServer(t, config, func(addr string) {
+		clientConfig := &ConnConfig{
+			Logger: logrus.StandardLogger(),
+		}
+		rawURL := fmt.Sprintf("rtmp://%s/live/instance/streamkey?token=abc", addr)
+
+		c0, s0, err := PublishURL(rawURL, clientConfig)
+		require.Nil(t, err)
+		defer c0.Close()
+		require.Equal(t, streamStateClientPublish, s0.handler.State())
+
+		c1, s1, err := PlayURL(rawURL, clientConfig, nil)
+		require.Nil(t, err)
+		defer c1.Close()
+		require.Equal(t, streamStateClientPlay, s1.handler.State())
+
+		tcURL := fmt.Sprintf("rtmp://%s/live/instance", addr)
+		events := make([]string, 0, 4)
+		for i := 0; i < 4; i++ {
+			events = append(events, <-handler.eventCh)
+		}
+		require.Equal(t, []string{
+			"Connect: live/instance, " + tcURL,
+			"Publish: streamkey?token=abc, live",
+			"Connect: live/instance, " + tcURL,
+			"Play: streamkey?token=abc, LiveOrRecorded",
+		}, events)
+	})
+}
+
+func TestServerCanAcceptEncryptedConnection(t *testing.T) {
+	config := &ConnConfig{
+		Handler:          &serverCanAcceptConnectHandler{},
+		Logger:           logrus.StandardLogger(),
+		EnableEncryption: true,
+	}
+	clientConfig := &ConnConfig{
+		Logger:           logrus.StandardLogger(),
+		EnableEncryption: true,
+	}
+
+	prepareConnectionWithClientConfig(t, config, clientConfig, func(c *ClientConn) {
+		require.True(t, c.conn.Encrypted())
+
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		s, err := c.CreateStream(nil, chunkSize)
+		require.Nil(t, err)
+		defer s.Close()
+	})
+}
+
 func prepareConnection(t *testing.T, config *ConnConfig, f func(c *ClientConn)) {
-	// prepare server
+	prepareConnectionWithClientConfig(t, config, &ConnConfig{
+		Logger: logrus.StandardLogger(),
+	}, f)
+}
+
+func prepareConnectionWithClientConfig(t *testing.T, config, clientConfig *ConnConfig, f func(c *ClientConn)) {
+	prepareServer(t, config, func(addr string) {
+		// prepare client
+		c, err := Dial("rtmp", addr, clientConfig)
+		require.Nil(t, err)
+		defer func() {
+			err := c.Close()
+			require.Nil(t, err)
+		}()
+
+		f(c)
+	})
+}
+
+func prepareServer(t *testing.T, config *ConnConfig, f func(addr string)) {
 	l, err := net.Listen("tcp", "127.0.0.1:")
 	require.Nil(t, err)
 
@@ -165,15 +1186,5 @@ func prepareConnection(t *testing.T, config *ConnConfig, f func(c *ClientConn))
 		require.Equal(t, ErrClosed, err)
 	}()
 
-	// prepare client
-	c, err := Dial("rtmp", l.Addr().String(), &ConnConfig{
-		Logger: logrus.StandardLogger(),
-	})
-	require.Nil(t, err)
-	defer func() {
-		err := c.Close()
-		require.Nil(t, err)
-	}()
-
-	f(c)
+	f(l.Addr().String())
 }
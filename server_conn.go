@@ -8,6 +8,8 @@
 package rtmp
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 
 	"github.com/yutopp/go-rtmp/handshake"
@@ -25,11 +27,14 @@ func newServerConn(conn *Conn) *serverConn {
 }
 
 func (sc *serverConn) Serve() error {
-	if err := handshake.HandshakeWithClient(sc.conn.rwc, sc.conn.rwc, &handshake.Config{
-		SkipHandshakeVerification: sc.conn.config.SkipHandshakeVerification,
-	}); err != nil {
+	ctx, cancel := sc.conn.handshakeContext(context.Background())
+	defer cancel()
+
+	result, err := handshake.HandshakeWithClientContext(ctx, sc.conn.rwc, sc.conn.rwc, sc.conn.handshakeConfig())
+	if err != nil {
 		return errors.Wrap(err, "Failed to handshake")
 	}
+	sc.conn.setHandshakeResult(result)
 
 	ctrlStream, err := sc.conn.streams.Create(ControlStreamID)
 	if err != nil {
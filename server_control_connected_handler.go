@@ -30,7 +30,23 @@ func (h *serverControlConnectedHandler) onMessage(
 	timestamp uint32,
 	msg message.Message,
 ) error {
-	return internal.ErrPassThroughMsg
+	switch msg := msg.(type) {
+	case *message.SharedObjectMessage:
+		if soh, ok := h.sh.stream.userHandler().(SharedObjectHandler); ok {
+			if err := soh.OnSharedObject(timestamp, msg); err != nil {
+				return err
+			}
+		}
+
+		if registry := h.sh.stream.streams().conn.config.SharedObjects; registry != nil {
+			registry.handle(h.sh.stream.streams().conn, msg)
+		}
+
+		return nil
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
 }
 
 func (h *serverControlConnectedHandler) onData(
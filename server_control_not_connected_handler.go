@@ -95,7 +95,12 @@ func (h *serverControlNotConnectedHandler) onCommand(
 			return err
 		}
 
-		result := h.newConnectSuccessResult()
+		encTy := message.EncodingTypeAMF0
+		if cmd.Command.ObjectEncoding == message.EncodingTypeAMF3 {
+			encTy = message.EncodingTypeAMF3
+		}
+
+		result := h.newConnectSuccessResult(encTy)
 
 		l.Infof("Connect: ResponseBody = %#v", result)
 		if err := h.sh.stream.ReplyConnect(chunkStreamID, timestamp, result); err != nil {
@@ -103,6 +108,9 @@ func (h *serverControlNotConnectedHandler) onCommand(
 		}
 		l.Info("Connected")
 
+		// The reply is encoded as same as "connect", and subsequent messages are encoded by the negotiated one
+		h.sh.stream.conn.streams.SetEncodingType(encTy)
+
 		h.sh.ChangeState(streamStateServerConnected)
 
 		return nil
@@ -112,7 +120,9 @@ func (h *serverControlNotConnectedHandler) onCommand(
 	}
 }
 
-func (h *serverControlNotConnectedHandler) newConnectSuccessResult() *message.NetConnectionConnectResult {
+func (h *serverControlNotConnectedHandler) newConnectSuccessResult(
+	encTy message.EncodingType,
+) *message.NetConnectionConnectResult {
 	rPreset := h.sh.stream.conn.config.RPreset
 	if rPreset == nil {
 		rPreset = defaultResponsePreset
@@ -120,10 +130,11 @@ func (h *serverControlNotConnectedHandler) newConnectSuccessResult() *message.Ne
 	return &message.NetConnectionConnectResult{
 		Properties: rPreset.GetServerConnectResultProperties(),
 		Information: message.NetConnectionConnectResultInformation{
-			Level:       "status",
-			Code:        message.NetConnectionConnectCodeSuccess,
-			Description: "Connection succeeded.",
-			Data:        rPreset.GetServerConnectResultData(),
+			Level:          "status",
+			Code:           message.NetConnectionConnectCodeSuccess,
+			Description:    "Connection succeeded.",
+			Data:           rPreset.GetServerConnectResultData(),
+			ObjectEncoding: encTy,
 		},
 	}
 }
@@ -83,10 +83,13 @@ func (h *serverDataInactiveHandler) onCommand(
 	case *message.NetStreamPlay:
 		l.Infof("Player is comming: %#v", cmd)
 
+		session := newPlaySession(h.sh.stream)
 		streamCtx := &StreamContext{
-			StreamID: h.sh.stream.streamID,
+			StreamID:    h.sh.stream.streamID,
+			PlaySession: session,
 		}
 		if err := h.sh.stream.userHandler().OnPlay(streamCtx, timestamp, cmd); err != nil {
+			session.cancel() // The session may be retained by the handler, thus make it unavailable
 			result := h.newOnStatus(message.NetStreamOnStatusCodePlayFailed, "Play failed.")
 
 			l.Infof("Reject a Play request: Response = %#v, Err = %+v", result, err)
@@ -97,14 +100,20 @@ func (h *serverDataInactiveHandler) onCommand(
 			return err
 		}
 
-		result := h.newOnStatus(message.NetStreamOnStatusCodePlayStart, "Play succeeded.")
-		if err := h.sh.stream.NotifyStatus(chunkStreamID, timestamp, result); err != nil {
-			return err
+		if session.stopped() {
+			// The handler stopped the session in OnPlay, thus the stream remains inactive
+			l.Infof("Player is stopped before starting")
+			return nil
 		}
-		l.Infof("Player accepted")
 
+		h.sh.stream.setPlaySession(session)
 		h.sh.ChangeState(streamStateServerPlay)
 
+		if err := session.start(chunkStreamID, timestamp, cmd); err != nil {
+			return err
+		}
+		l.Infof("Player accepted")
+
 		return nil
 
 	default:
@@ -14,10 +14,16 @@ import (
 
 var _ stateHandler = (*serverDataPlayHandler)(nil)
 
-// serverDataPlayHandler Handle data messages from a player at server side (NOT IMPLEMENTED).
+// serverDataPlayHandler Handle data messages from a player at server side. Media are pushed by PlaySession.
 //
 //	transitions:
 //	  | _ -> self
+//
+//	replies (requests are accepted if the handler does not implement PlayControlHandler):
+//	  | "pause"                -> NetStream.Pause.Notify / NetStream.Unpause.Notify (NetStream.Failed if rejected)
+//	  | "seek"                 -> NetStream.Seek.Notify (NetStream.Seek.Failed if rejected)
+//	  | "receiveAudio" (true)  -> NetStream.Seek.Notify, NetStream.Play.Start
+//	  | "receiveVideo" (true)  -> NetStream.Seek.Notify, NetStream.Play.Start
 type serverDataPlayHandler struct {
 	sh *streamHandler
 }
@@ -45,5 +51,90 @@ func (h *serverDataPlayHandler) onCommand(
 	cmdMsg *message.CommandMessage,
 	body interface{},
 ) error {
-	return internal.ErrPassThroughMsg
+	l := h.sh.Logger()
+
+	switch cmd := body.(type) {
+	case *message.NetStreamPause:
+		l.Infof("Pause: %#v", cmd)
+
+		if pch, ok := h.sh.stream.userHandler().(PlayControlHandler); ok {
+			if err := pch.OnPause(h.newStreamContext(), timestamp, cmd); err != nil {
+				l.Infof("Reject a Pause request: Err = %+v", err)
+				return h.notifyStatus(chunkStreamID, timestamp, message.NetStreamOnStatusCodeFailed, "Pause failed.")
+			}
+		}
+
+		if cmd.Pause {
+			return h.notifyStatus(chunkStreamID, timestamp, message.NetStreamOnStatusCodePauseNotify, "Paused.")
+		}
+		return h.notifyStatus(chunkStreamID, timestamp, message.NetStreamOnStatusCodeUnpauseNotify, "Unpaused.")
+
+	case *message.NetStreamSeek:
+		l.Infof("Seek: %#v", cmd)
+
+		if pch, ok := h.sh.stream.userHandler().(PlayControlHandler); ok {
+			if err := pch.OnSeek(h.newStreamContext(), timestamp, cmd); err != nil {
+				l.Infof("Reject a Seek request: Err = %+v", err)
+				return h.notifyStatus(chunkStreamID, timestamp, message.NetStreamOnStatusCodeSeekFailed, "Seek failed.")
+			}
+		}
+
+		return h.notifyStatus(chunkStreamID, timestamp, message.NetStreamOnStatusCodeSeekNotify, "Seeking.")
+
+	case *message.NetStreamReceiveAudio:
+		l.Infof("ReceiveAudio: %#v", cmd)
+
+		if pch, ok := h.sh.stream.userHandler().(PlayControlHandler); ok {
+			if err := pch.OnReceiveAudio(h.newStreamContext(), timestamp, cmd); err != nil {
+				l.Infof("Reject a ReceiveAudio request: Err = %+v", err)
+				return nil // No response is defined
+			}
+		}
+
+		return h.notifyResumed(chunkStreamID, timestamp, cmd.Receive)
+
+	case *message.NetStreamReceiveVideo:
+		l.Infof("ReceiveVideo: %#v", cmd)
+
+		if pch, ok := h.sh.stream.userHandler().(PlayControlHandler); ok {
+			if err := pch.OnReceiveVideo(h.newStreamContext(), timestamp, cmd); err != nil {
+				l.Infof("Reject a ReceiveVideo request: Err = %+v", err)
+				return nil // No response is defined
+			}
+		}
+
+		return h.notifyResumed(chunkStreamID, timestamp, cmd.Receive)
+
+	default:
+		return internal.ErrPassThroughMsg
+	}
+}
+
+func (h *serverDataPlayHandler) newStreamContext() *StreamContext {
+	return &StreamContext{
+		StreamID:    h.sh.stream.streamID,
+		PlaySession: h.sh.stream.playSession(),
+	}
+}
+
+func (h *serverDataPlayHandler) notifyStatus(
+	chunkStreamID int,
+	timestamp uint32,
+	code message.NetStreamOnStatusCode,
+	description string,
+) error {
+	return h.sh.stream.NotifyStatus(chunkStreamID, timestamp, newPlayOnStatus(code, description))
+}
+
+// notifyResumed Replies to receiveAudio and receiveVideo. Nothing is sent if receiving is disabled (7.2.2.4, 7.2.2.5).
+func (h *serverDataPlayHandler) notifyResumed(chunkStreamID int, timestamp uint32, receive bool) error {
+	if !receive {
+		return nil
+	}
+
+	if err := h.notifyStatus(chunkStreamID, timestamp, message.NetStreamOnStatusCodeSeekNotify, "Seeking."); err != nil {
+		return err
+	}
+
+	return h.notifyStatus(chunkStreamID, timestamp, message.NetStreamOnStatusCodePlayStart, "Play succeeded.")
 }
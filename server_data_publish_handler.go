@@ -8,6 +8,12 @@
 package rtmp
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
 	"github.com/yutopp/go-rtmp/internal"
 	"github.com/yutopp/go-rtmp/message"
 )
@@ -29,9 +35,15 @@ func (h *serverDataPublishHandler) onMessage(
 ) error {
 	switch msg := msg.(type) {
 	case *message.AudioMessage:
+		if mh, ok := h.sh.stream.userHandler().(MultitrackHandler); ok {
+			return h.onAudioTracks(mh, timestamp, msg.Payload)
+		}
 		return h.sh.stream.userHandler().OnAudio(timestamp, msg.Payload)
 
 	case *message.VideoMessage:
+		if mh, ok := h.sh.stream.userHandler().(MultitrackHandler); ok {
+			return h.onVideoTracks(mh, timestamp, msg.Payload)
+		}
 		return h.sh.stream.userHandler().OnVideo(timestamp, msg.Payload)
 
 	default:
@@ -62,3 +74,59 @@ func (h *serverDataPublishHandler) onCommand(
 ) error {
 	return internal.ErrPassThroughMsg
 }
+
+// onAudioTracks Passes each track of multitrack audio to the handler. Other audio are passed to OnAudio.
+func (h *serverDataPublishHandler) onAudioTracks(mh MultitrackHandler, timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+
+	r := bytes.NewReader(data)
+	var header message.AudioTagHeader
+	if err := message.DecodeAudioTagHeader(r, &header); err != nil || !header.IsMultitrack {
+		return h.sh.stream.userHandler().OnAudio(timestamp, bytes.NewReader(data))
+	}
+
+	tracks, err := message.DecodeMultitrackTracks(r, header.MultitrackType, header.FourCC)
+	if err != nil {
+		return errors.Wrap(err, "Failed to decode multitrack audio")
+	}
+	for _, track := range tracks {
+		trackHeader := header
+		trackHeader.FourCC = track.FourCC
+		if err := mh.OnAudioTrack(timestamp, track.TrackID, &trackHeader, bytes.NewReader(track.Body)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// onVideoTracks Passes each track of multitrack video to the handler. Other video are passed to OnVideo.
+func (h *serverDataPublishHandler) onVideoTracks(mh MultitrackHandler, timestamp uint32, payload io.Reader) error {
+	data, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+
+	r := bytes.NewReader(data)
+	var header message.VideoTagHeader
+	if err := message.DecodeVideoTagHeader(r, &header); err != nil || !header.IsMultitrack {
+		return h.sh.stream.userHandler().OnVideo(timestamp, bytes.NewReader(data))
+	}
+
+	tracks, err := message.DecodeMultitrackTracks(r, header.MultitrackType, header.FourCC)
+	if err != nil {
+		return errors.Wrap(err, "Failed to decode multitrack video")
+	}
+	for _, track := range tracks {
+		trackHeader := header
+		trackHeader.FourCC = track.FourCC
+		if err := mh.OnVideoTrack(timestamp, track.TrackID, &trackHeader, bytes.NewReader(track.Body)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
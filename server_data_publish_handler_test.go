@@ -8,11 +8,123 @@
 package rtmp
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/yutopp/go-rtmp/message"
 )
 
+type publishAggregateHandler struct {
+	DefaultHandler
+	timestamps []uint32
+}
+
+func (h *publishAggregateHandler) OnAudio(timestamp uint32, _ io.Reader) error {
+	h.timestamps = append(h.timestamps, timestamp)
+	return nil
+}
+
+func (h *publishAggregateHandler) OnVideo(timestamp uint32, _ io.Reader) error {
+	h.timestamps = append(h.timestamps, timestamp)
+	return nil
+}
+
+func TestHandlePublisherAggregateMessage(t *testing.T) {
+	h := &publishAggregateHandler{}
+	rwc := &rwcMock{}
+	c := newConn(rwc, &ConnConfig{
+		Handler: h,
+	})
+
+	s := newStream(42, c)
+	s.handler.ChangeState(streamStateServerPublish)
+
+	// Timestamps of sub messages are rebased on the timestamp of the aggregate message
+	err := s.handle(0, 1000, &message.AggregateMessage{
+		Messages: []*message.AggregateSubMessage{
+			{Timestamp: 10, Message: &message.VideoMessage{Payload: bytes.NewReader([]byte("v"))}},
+			{Timestamp: 30, Message: &message.AudioMessage{Payload: bytes.NewReader([]byte("a"))}},
+		},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []uint32{1000, 1020}, h.timestamps)
+}
+
+type publishMultitrackHandler struct {
+	DefaultHandler
+	events []string
+}
+
+func (h *publishMultitrackHandler) OnAudio(timestamp uint32, payload io.Reader) error {
+	data, _ := ioutil.ReadAll(payload)
+	h.events = append(h.events, fmt.Sprintf("Audio: %d, %x", timestamp, data))
+	return nil
+}
+
+func (h *publishMultitrackHandler) OnAudioTrack(
+	timestamp uint32,
+	trackID uint8,
+	header *message.AudioTagHeader,
+	payload io.Reader,
+) error {
+	data, _ := ioutil.ReadAll(payload)
+	h.events = append(h.events, fmt.Sprintf("AudioTrack: %d, %d, %s, %s", timestamp, trackID, header.FourCC, data))
+	return nil
+}
+
+func (h *publishMultitrackHandler) OnVideoTrack(
+	timestamp uint32,
+	trackID uint8,
+	header *message.VideoTagHeader,
+	payload io.Reader,
+) error {
+	data, _ := ioutil.ReadAll(payload)
+	h.events = append(h.events, fmt.Sprintf("VideoTrack: %d, %d, %s, %s", timestamp, trackID, header.FourCC, data))
+	return nil
+}
+
+func TestHandlePublisherMultitrackMessage(t *testing.T) {
+	h := &publishMultitrackHandler{}
+	rwc := &rwcMock{}
+	c := newConn(rwc, &ConnConfig{
+		Handler: h,
+	})
+
+	s := newStream(42, c)
+	s.handler.ChangeState(streamStateServerPublish)
+
+	audio := []byte{
+		0x95, 0x21, // ExHeader, Multitrack, ManyTracksManyCodecs, CodedFrames
+		'O', 'p', 'u', 's', 0x00, 0x00, 0x00, 0x02, 'e', 'n',
+		'm', 'p', '4', 'a', 0x01, 0x00, 0x00, 0x02, 'j', 'a',
+	}
+	err := s.handle(0, 10, &message.AudioMessage{Payload: bytes.NewReader(audio)})
+	require.Nil(t, err)
+
+	video := []byte{
+		0x96, 0x03, 'a', 'v', '0', '1', // ExHeader, Multitrack, OneTrack, CodedFramesX
+		0x02, 'v',
+	}
+	err = s.handle(0, 20, &message.VideoMessage{Payload: bytes.NewReader(video)})
+	require.Nil(t, err)
+
+	// Not multitrack
+	err = s.handle(0, 30, &message.AudioMessage{Payload: bytes.NewReader([]byte{0xaf, 0x01})})
+	require.Nil(t, err)
+
+	require.Equal(t, []string{
+		"AudioTrack: 10, 0, Opus, en",
+		"AudioTrack: 10, 1, mp4a, ja",
+		"VideoTrack: 20, 2, av01, v",
+		"Audio: 30, af01",
+	}, h.events)
+}
+
 func BenchmarkHandlePublisherVideoMessage(b *testing.B) {
 	rwc := &rwcMock{}
 	c := newConn(rwc, nil)
@@ -8,6 +8,9 @@
 package rtmp
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
 	"net"
 	"testing"
 	"time"
@@ -30,3 +33,53 @@ func TestServerCanClose(t *testing.T) {
 	err = srv.Serve(l)
 	require.Equal(t, ErrClosed, err)
 }
+
+func TestServerClosesConnectionOnHandshakeTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.Nil(t, err)
+
+	srv := NewServer(&ServerConfig{
+		OnConnect: func(conn net.Conn) (io.ReadWriteCloser, *ConnConfig) {
+			return conn, &ConnConfig{
+				HandshakeTimeout: 100 * time.Millisecond,
+			}
+		},
+	})
+	defer srv.Close()
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// Send nothing. The server must close the connection
+	err = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	require.Nil(t, err)
+
+	_, err = conn.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDialContextTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.Nil(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept() // Accept but never reply to handshakes
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(ioutil.Discard, conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = DialContext(ctx, "rtmp", l.Addr().String(), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,364 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+const sharedObjectChunkStreamID = 3
+
+// maxSharedObjectQueueMessages A max number of messages which are queued for each connection
+const maxSharedObjectQueueMessages = 256
+
+type sharedObjectKey struct {
+	name       string
+	persistent bool
+}
+
+type sharedObject struct {
+	version     uint32
+	properties  map[string]interface{}
+	subscribers map[*Conn]message.EncodingType
+}
+
+// SharedObjectRegistry An in-process registry of remote shared objects.
+// It tracks versions and properties of shared objects, and broadcasts changes to all subscribed connections.
+// Messages are queued for each connection and sent asynchronously, thus slow connections do not block others.
+// Persistent shared objects are kept after all subscribers released them, but they are not stored outside of the process.
+type SharedObjectRegistry struct {
+	objects map[sharedObjectKey]*sharedObject
+	senders map[*Conn]*sharedObjectSender
+	m       sync.Mutex
+}
+
+func NewSharedObjectRegistry() *SharedObjectRegistry {
+	return &SharedObjectRegistry{
+		objects: make(map[sharedObjectKey]*sharedObject),
+		senders: make(map[*Conn]*sharedObjectSender),
+	}
+}
+
+// Properties Returns a copy of properties and a version of the shared object.
+func (r *SharedObjectRegistry) Properties(name string, persistent bool) (map[string]interface{}, uint32, bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	so, ok := r.objects[sharedObjectKey{name: name, persistent: persistent}]
+	if !ok {
+		return nil, 0, false
+	}
+
+	props := make(map[string]interface{}, len(so.properties))
+	for k, v := range so.properties {
+		props[k] = v
+	}
+
+	return props, so.version, true
+}
+
+// SetProperty Changes a property of the shared object from the server side, and broadcasts it to all subscribers.
+func (r *SharedObjectRegistry) SetProperty(name string, persistent bool, key string, value interface{}) {
+	r.m.Lock()
+	soKey := sharedObjectKey{name: name, persistent: persistent}
+	so := r.objectOf(soKey)
+	so.properties[key] = value
+	so.version++
+
+	outs := newSharedObjectOutbox(soKey, so.version)
+	for conn, encTy := range so.subscribers {
+		outs.add(conn, encTy, &message.SharedObjectEventChange{Name: key, Value: value})
+	}
+	r.send(outs)
+	r.m.Unlock()
+}
+
+// SendMessage Broadcasts a message to all subscribers of the shared object.
+func (r *SharedObjectRegistry) SendMessage(name string, persistent bool, method string, args ...interface{}) {
+	r.m.Lock()
+	key := sharedObjectKey{name: name, persistent: persistent}
+	so, ok := r.objects[key]
+	if !ok {
+		r.m.Unlock()
+		return
+	}
+
+	outs := newSharedObjectOutbox(key, so.version)
+	for conn, encTy := range so.subscribers {
+		outs.add(conn, encTy, &message.SharedObjectEventSendMessage{Method: method, Args: args})
+	}
+	r.send(outs)
+	r.m.Unlock()
+}
+
+func (r *SharedObjectRegistry) handle(conn *Conn, msg *message.SharedObjectMessage) {
+	r.m.Lock()
+	key := sharedObjectKey{name: msg.ObjectName, persistent: msg.Persistent}
+	outs := newSharedObjectOutbox(key, 0)
+
+	for _, event := range msg.Events {
+		switch event := event.(type) {
+		case *message.SharedObjectEventUse:
+			so := r.objectOf(key)
+			so.subscribers[conn] = msg.Encoding
+
+			outs.add(conn, msg.Encoding, &message.SharedObjectEventUseSuccess{})
+			if msg.Version != so.version || msg.Version == 0 {
+				// Synchronize all properties
+				outs.add(conn, msg.Encoding, &message.SharedObjectEventClear{})
+				for _, name := range sortedPropertyNames(so.properties) {
+					outs.add(conn, msg.Encoding, &message.SharedObjectEventChange{
+						Name:  name,
+						Value: so.properties[name],
+					})
+				}
+			}
+
+		case *message.SharedObjectEventRelease:
+			r.release(key, conn)
+
+		case *message.SharedObjectEventRequestChange:
+			so, ok := r.subscribedObject(key, conn)
+			if !ok {
+				continue
+			}
+			so.properties[event.Name] = event.Value
+			so.version++
+
+			for sub, encTy := range so.subscribers {
+				if sub == conn {
+					outs.add(sub, encTy, &message.SharedObjectEventSuccess{Name: event.Name})
+					continue
+				}
+				outs.add(sub, encTy, &message.SharedObjectEventChange{Name: event.Name, Value: event.Value})
+			}
+
+		case *message.SharedObjectEventRequestRemove:
+			so, ok := r.subscribedObject(key, conn)
+			if !ok {
+				continue
+			}
+			delete(so.properties, event.Name)
+			so.version++
+
+			for sub, encTy := range so.subscribers {
+				outs.add(sub, encTy, &message.SharedObjectEventRemove{Name: event.Name})
+			}
+
+		case *message.SharedObjectEventSendMessage:
+			so, ok := r.subscribedObject(key, conn)
+			if !ok {
+				continue
+			}
+
+			for sub, encTy := range so.subscribers {
+				outs.add(sub, encTy, event)
+			}
+
+		default:
+			// Events which are sent from servers are ignored
+		}
+	}
+
+	if so, ok := r.objects[key]; ok {
+		outs.version = so.version
+	}
+	r.send(outs)
+	r.m.Unlock()
+}
+
+// releaseAll Unsubscribes the connection from all shared objects
+func (r *SharedObjectRegistry) releaseAll(conn *Conn) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for key := range r.objects {
+		r.release(key, conn)
+	}
+
+	if sender, ok := r.senders[conn]; ok {
+		sender.close()
+		delete(r.senders, conn)
+	}
+}
+
+// send Enqueues collected messages to senders of each connection. It must be called while the registry is locked
+// to keep the order of messages among connections.
+func (r *SharedObjectRegistry) send(outs *sharedObjectOutbox) {
+	for _, conn := range outs.conns {
+		msg := outs.msgs[conn]
+		msg.Version = outs.version
+
+		sender, ok := r.senders[conn]
+		if !ok {
+			sender = &sharedObjectSender{conn: conn}
+			r.senders[conn] = sender
+		}
+		sender.enqueue(msg)
+	}
+}
+
+func (r *SharedObjectRegistry) release(key sharedObjectKey, conn *Conn) {
+	so, ok := r.objects[key]
+	if !ok {
+		return
+	}
+
+	delete(so.subscribers, conn)
+	if len(so.subscribers) == 0 && !key.persistent {
+		delete(r.objects, key)
+	}
+}
+
+func (r *SharedObjectRegistry) objectOf(key sharedObjectKey) *sharedObject {
+	so, ok := r.objects[key]
+	if !ok {
+		so = &sharedObject{
+			properties:  make(map[string]interface{}),
+			subscribers: make(map[*Conn]message.EncodingType),
+		}
+		r.objects[key] = so
+	}
+
+	return so
+}
+
+func (r *SharedObjectRegistry) subscribedObject(key sharedObjectKey, conn *Conn) (*sharedObject, bool) {
+	so, ok := r.objects[key]
+	if !ok {
+		return nil, false
+	}
+
+	if _, ok := so.subscribers[conn]; !ok {
+		return nil, false
+	}
+
+	return so, true
+}
+
+// sharedObjectOutbox Collects events for each connections to send them as a message per connection.
+type sharedObjectOutbox struct {
+	key     sharedObjectKey
+	version uint32
+	conns   []*Conn
+	msgs    map[*Conn]*message.SharedObjectMessage
+}
+
+func newSharedObjectOutbox(key sharedObjectKey, version uint32) *sharedObjectOutbox {
+	return &sharedObjectOutbox{
+		key:     key,
+		version: version,
+		msgs:    make(map[*Conn]*message.SharedObjectMessage),
+	}
+}
+
+func (o *sharedObjectOutbox) add(conn *Conn, encTy message.EncodingType, event message.SharedObjectEvent) {
+	msg, ok := o.msgs[conn]
+	if !ok {
+		msg = &message.SharedObjectMessage{
+			ObjectName: o.key.name,
+			Persistent: o.key.persistent,
+			Encoding:   encTy,
+		}
+		o.msgs[conn] = msg
+		o.conns = append(o.conns, conn)
+	}
+
+	msg.Events = append(msg.Events, event)
+}
+
+// sharedObjectSender Sends queued shared object messages to a connection in order.
+// A goroutine to write messages runs only while the queue is not empty.
+type sharedObjectSender struct {
+	conn    *Conn
+	queue   []*message.SharedObjectMessage
+	running bool
+	closed  bool
+	m       sync.Mutex
+}
+
+func (s *sharedObjectSender) enqueue(msg *message.SharedObjectMessage) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if len(s.queue) >= maxSharedObjectQueueMessages {
+		s.conn.logger.Warnf("Dropped a shared object message because the queue is full: Name = %s", msg.ObjectName)
+		return
+	}
+	s.queue = append(s.queue, msg)
+
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+}
+
+func (s *sharedObjectSender) run() {
+	for {
+		msg, ok := s.dequeue()
+		if !ok {
+			return
+		}
+
+		if err := s.conn.writeSharedObjectMessage(msg); err != nil {
+			s.conn.logger.Warnf("Failed to send a shared object message: Name = %s, Err = %+v", msg.ObjectName, err)
+		}
+	}
+}
+
+func (s *sharedObjectSender) dequeue() (*message.SharedObjectMessage, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed || len(s.queue) == 0 {
+		s.running = false
+		return nil, false
+	}
+
+	msg := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+
+	return msg, true
+}
+
+func (s *sharedObjectSender) close() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.closed = true
+	s.queue = nil
+}
+
+func (c *Conn) writeSharedObjectMessage(msg *message.SharedObjectMessage) error {
+	ctx, cancel := c.newWriteContext()
+	defer cancel()
+
+	// Use own ChunkMessage instead of Stream.Write because it may be called from goroutines of other connections
+	return c.streamer.Write(ctx, sharedObjectChunkStreamID, 0, &ChunkMessage{
+		StreamID: ControlStreamID,
+		Message:  msg,
+	})
+}
+
+func sortedPropertyNames(props map[string]interface{}) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
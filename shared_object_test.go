@@ -0,0 +1,113 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+type sharedObjectClientHandler struct {
+	DefaultHandler
+	msgCh chan *message.SharedObjectMessage
+}
+
+func (h *sharedObjectClientHandler) OnSharedObject(_ uint32, msg *message.SharedObjectMessage) error {
+	h.msgCh <- msg
+	return nil
+}
+
+func TestServerCanShareObjects(t *testing.T) {
+	registry := NewSharedObjectRegistry()
+	config := &ConnConfig{
+		Handler:       &serverCanAcceptConnectHandler{},
+		Logger:        logrus.StandardLogger(),
+		SharedObjects: registry,
+	}
+
+	handler := &sharedObjectClientHandler{
+		msgCh: make(chan *message.SharedObjectMessage, 1),
+	}
+	clientConfig := &ConnConfig{
+		Handler: handler,
+		Logger:  logrus.StandardLogger(),
+	}
+
+	prepareConnectionWithClientConfig(t, config, clientConfig, func(c *ClientConn) {
+		err := c.Connect(nil)
+		require.Nil(t, err)
+
+		send := func(events ...message.SharedObjectEvent) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err := c.conn.Write(ctx, sharedObjectChunkStreamID, 0, &ChunkMessage{
+				StreamID: ControlStreamID,
+				Message: &message.SharedObjectMessage{
+					ObjectName: "so",
+					Persistent: true,
+					Events:     events,
+				},
+			})
+			require.Nil(t, err)
+		}
+
+		receive := func() *message.SharedObjectMessage {
+			select {
+			case msg := <-handler.msgCh:
+				return msg
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "Timeout")
+				return nil
+			}
+		}
+
+		send(&message.SharedObjectEventUse{})
+		require.Equal(t, &message.SharedObjectMessage{
+			ObjectName: "so",
+			Persistent: true,
+			Events: []message.SharedObjectEvent{
+				&message.SharedObjectEventUseSuccess{},
+				&message.SharedObjectEventClear{},
+			},
+		}, receive())
+
+		send(&message.SharedObjectEventRequestChange{Name: "a", Value: float64(1)})
+		require.Equal(t, &message.SharedObjectMessage{
+			ObjectName: "so",
+			Version:    1,
+			Persistent: true,
+			Events: []message.SharedObjectEvent{
+				&message.SharedObjectEventSuccess{Name: "a"},
+			},
+		}, receive())
+
+		registry.SetProperty("so", true, "b", "x")
+		require.Equal(t, &message.SharedObjectMessage{
+			ObjectName: "so",
+			Version:    2,
+			Persistent: true,
+			Events: []message.SharedObjectEvent{
+				&message.SharedObjectEventChange{Name: "b", Value: "x"},
+			},
+		}, receive())
+
+		props, version, ok := registry.Properties("so", true)
+		require.True(t, ok)
+		require.Equal(t, uint32(2), version)
+		require.Equal(t, map[string]interface{}{"a": float64(1), "b": "x"}, props)
+
+		send(&message.SharedObjectEventRelease{})
+	})
+}
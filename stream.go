@@ -10,7 +10,8 @@ package rtmp
 import (
 	"bytes"
 	"context"
-	"time"
+	"io"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -21,10 +22,19 @@ import (
 // Stream represents a logical message stream
 type Stream struct {
 	streamID     uint32
-	encTy        message.EncodingType
+	encTy        message.EncodingType // Guarded by m because it is changed when AMF3 is negotiated
 	transactions *transactions
 	handler      *streamHandler
-	cmsg         ChunkMessage
+
+	playHandlerValue PlayHandler  // A handler for media when the stream is played by a client
+	playSessionValue *PlaySession // A session to push media when the stream is played at server side
+	statusWaiter     *statusWaiter
+	statusHandler    StatusHandlerFunc
+	m                sync.Mutex
+
+	audioTrack mediaTrack
+	videoTrack mediaTrack
+	dataTrack  mediaTrack
 
 	conn *Conn
 }
@@ -34,9 +44,6 @@ func newStream(streamID uint32, conn *Conn) *Stream {
 		streamID:     streamID,
 		encTy:        message.EncodingTypeAMF0, // Default AMF encoding type
 		transactions: newTransactions(),
-		cmsg: ChunkMessage{
-			StreamID: streamID,
-		},
 
 		conn: conn,
 	}
@@ -63,6 +70,14 @@ func (s *Stream) WriteUserCtrl(chunkStreamID int, timestamp uint32, msg *message
 
 func (s *Stream) Connect(
 	body *message.NetConnectionConnect,
+) (*message.NetConnectionConnectResult, error) {
+	return s.ConnectContext(context.Background(), body)
+}
+
+// ConnectContext Sends a connect command and waits for the result until ctx is done.
+func (s *Stream) ConnectContext(
+	ctx context.Context,
+	body *message.NetConnectionConnect,
 ) (*message.NetConnectionConnectResult, error) {
 	transactionID := int64(1) // Always 1 (7.2.1.1)
 	t, err := s.transactions.Create(transactionID)
@@ -75,41 +90,38 @@ func (s *Stream) Connect(
 	}
 
 	chunkStreamID := 3 // TODO: fix
-	err = s.writeCommandMessage(
+	err = s.writeCommandMessageContext(
+		ctx,
 		chunkStreamID, 0, // Timestamp is 0
 		"connect",
 		transactionID,
 		body,
 	)
 	if err != nil {
+		_ = s.transactions.Delete(transactionID)
 		return nil, err
 	}
 
-	// TODO: support timeout
-	timeoutCtx := context.TODO()
-	select {
-	case <-timeoutCtx.Done():
-		return nil, timeoutCtx.Err()
-	case <-t.doneCh:
-		amfDec := message.NewAMFDecoder(t.body, t.encoding)
+	if err := s.waitTransaction(ctx, "connect", transactionID, t); err != nil {
+		return nil, err
+	}
 
-		var value message.AMFConvertible
-		if err := message.DecodeBodyConnectResult(t.body, amfDec, &value); err != nil {
-			return nil, errors.Wrap(err, "Failed to decode result")
-		}
-		result := value.(*message.NetConnectionConnectResult)
+	amfDec := message.NewAMFDecoder(t.body, t.encoding)
 
-		if t.commandName == "_error" {
-			return nil, &ConnectRejectedError{
-				TransactionID: transactionID,
-				Result:        result,
-			}
-		}
+	var value message.AMFConvertible
+	if err := message.DecodeBodyConnectResult(t.body, amfDec, &value); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode result")
+	}
+	result := value.(*message.NetConnectionConnectResult)
 
-		return result, nil
+	if t.commandName == "_error" {
+		return nil, &ConnectRejectedError{
+			TransactionID: transactionID,
+			Result:        result,
+		}
 	}
 
-	//return nil, errors.New("Failed to get result")
+	return result, nil
 }
 
 func (s *Stream) ReplyConnect(
@@ -134,6 +146,15 @@ func (s *Stream) ReplyConnect(
 }
 
 func (s *Stream) CreateStream(body *message.NetConnectionCreateStream, chunkSize uint32) (*message.NetConnectionCreateStreamResult, error) {
+	return s.CreateStreamContext(context.Background(), body, chunkSize)
+}
+
+// CreateStreamContext Sends a createStream command and waits for the result until ctx is done.
+func (s *Stream) CreateStreamContext(
+	ctx context.Context,
+	body *message.NetConnectionCreateStream,
+	chunkSize uint32,
+) (*message.NetConnectionCreateStreamResult, error) {
 	oldChunkSize := s.conn.streamer.selfState.chunkSize
 	if chunkSize > 0 && chunkSize != oldChunkSize {
 		logrus.Infof("Changing chunkSize %d->%d", oldChunkSize, chunkSize)
@@ -144,7 +165,7 @@ func (s *Stream) CreateStream(body *message.NetConnectionCreateStream, chunkSize
 		}
 	}
 
-	transactionID := int64(2) // TODO: fix
+	transactionID := s.conn.newTransactionID()
 	t, err := s.transactions.Create(transactionID)
 	if err != nil {
 		return nil, err
@@ -155,48 +176,50 @@ func (s *Stream) CreateStream(body *message.NetConnectionCreateStream, chunkSize
 	}
 
 	chunkStreamID := 3 // TODO: fix
-	err = s.writeCommandMessage(
+	err = s.writeCommandMessageContext(
+		ctx,
 		chunkStreamID, 0, // TODO: fix, Timestamp is 0
 		"createStream",
 		transactionID,
 		body,
 	)
 	if err != nil {
+		_ = s.transactions.Delete(transactionID)
+		return nil, err
+	}
+
+	if err := s.waitTransaction(ctx, "createStream", transactionID, t); err != nil {
 		return nil, err
 	}
 
-	// TODO: support timeout
 	// TODO: check result
-	timeoutCtx := context.TODO()
-	select {
-	case <-timeoutCtx.Done():
-		return nil, timeoutCtx.Err()
-	case <-t.doneCh:
-		amfDec := message.NewAMFDecoder(t.body, t.encoding)
+	amfDec := message.NewAMFDecoder(t.body, t.encoding)
 
-		var value message.AMFConvertible
-		if err := message.DecodeBodyCreateStreamResult(t.body, amfDec, &value); err != nil {
-			return nil, errors.Wrap(err, "Failed to decode result")
-		}
-		result := value.(*message.NetConnectionCreateStreamResult)
+	var value message.AMFConvertible
+	if err := message.DecodeBodyCreateStreamResult(t.body, amfDec, &value); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode result")
+	}
+	result := value.(*message.NetConnectionCreateStreamResult)
 
-		if t.commandName == "_error" {
-			return nil, &CreateStreamRejectedError{
-				TransactionID: transactionID,
-				Result:        result,
-			}
+	if t.commandName == "_error" {
+		return nil, &CreateStreamRejectedError{
+			TransactionID: transactionID,
+			Result:        result,
 		}
-
-		return result, nil
 	}
 
-	//return nil, errors.New("Failed to get result")
+	return result, nil
 }
 
 func (s *Stream) DeleteStream(body *message.NetStreamDeleteStream) error {
+	return s.DeleteStreamContext(context.Background(), body)
+}
+
+func (s *Stream) DeleteStreamContext(ctx context.Context, body *message.NetStreamDeleteStream) error {
 	chunkStreamID := 3 // TODO: fix
 
-	return s.writeCommandMessage(
+	return s.writeCommandMessageContext(
+		ctx,
 		chunkStreamID,
 		0,
 		"deleteStream",
@@ -229,18 +252,217 @@ func (s *Stream) ReplyCreateStream(
 
 func (s *Stream) Publish(
 	body *message.NetStreamPublish,
+) error {
+	return s.PublishContext(context.Background(), body)
+}
+
+// PublishContext Sends a publish command and waits for NetStream.Publish.Start until ctx is done.
+// PublishRejectedError is returned if the server replied with an error status.
+func (s *Stream) PublishContext(
+	ctx context.Context,
+	body *message.NetStreamPublish,
 ) error {
 	if body == nil {
 		body = &message.NetStreamPublish{}
 	}
 
-	chunkStreamID := 3 // TODO: fix
-	return s.writeCommandMessage(
-		chunkStreamID, 0, // TODO: fix, Timestamp is 0
-		"publish",
-		int64(0), // Always 0, 7.2.2.6
-		body,
+	// Transaction ID is always 0, 7.2.2.6
+	result, err := s.writeCommandAndWaitStatus(ctx, "publish", body, message.NetStreamOnStatusCodePublishStart)
+	if err != nil {
+		return err
+	}
+
+	if result.InfoObject.Level == message.NetStreamOnStatusLevelError {
+		return &PublishRejectedError{
+			Result: result,
+		}
+	}
+
+	return nil
+}
+
+func (s *Stream) Play(
+	body *message.NetStreamPlay,
+	handler PlayHandler,
+) error {
+	return s.PlayContext(context.Background(), body, handler)
+}
+
+// PlayContext Sends a play command and waits for NetStream.Play.Start until ctx is done.
+// PlayRejectedError is returned if the server replied with an error status.
+// Media delivered to the stream are passed to handler. If handler is nil, media are discarded.
+func (s *Stream) PlayContext(
+	ctx context.Context,
+	body *message.NetStreamPlay,
+	handler PlayHandler,
+) error {
+	if body == nil {
+		body = &message.NetStreamPlay{}
+	}
+
+	// Set the handler before sending the command because media may arrive immediately
+	s.setPlayHandler(handler)
+
+	// Transaction ID is always 0, 7.2.2.1
+	result, err := s.writeCommandAndWaitStatus(ctx, "play", body, message.NetStreamOnStatusCodePlayStart)
+	if err != nil {
+		return err
+	}
+
+	if result.InfoObject.Level == message.NetStreamOnStatusLevelError {
+		return &PlayRejectedError{
+			Result: result,
+		}
+	}
+
+	return nil
+}
+
+func (s *Stream) Pause(body *message.NetStreamPause) error {
+	return s.PauseContext(context.Background(), body)
+}
+
+// PauseContext Sends a pause command and waits for NetStream.Pause.Notify (or NetStream.Unpause.Notify) until ctx is done.
+// PauseRejectedError is returned if the server replied with an error status.
+func (s *Stream) PauseContext(ctx context.Context, body *message.NetStreamPause) error {
+	if body == nil {
+		body = &message.NetStreamPause{}
+	}
+
+	code := message.NetStreamOnStatusCodeUnpauseNotify
+	if body.Pause {
+		code = message.NetStreamOnStatusCodePauseNotify
+	}
+
+	result, err := s.writeCommandAndWaitStatus(ctx, "pause", body, code)
+	if err != nil {
+		return err
+	}
+
+	if result.InfoObject.Level == message.NetStreamOnStatusLevelError {
+		return &PauseRejectedError{
+			Result: result,
+		}
+	}
+
+	return nil
+}
+
+func (s *Stream) Seek(body *message.NetStreamSeek) error {
+	return s.SeekContext(context.Background(), body)
+}
+
+// SeekContext Sends a seek command and waits for NetStream.Seek.Notify until ctx is done.
+// SeekRejectedError is returned if the server replied with an error status.
+func (s *Stream) SeekContext(ctx context.Context, body *message.NetStreamSeek) error {
+	if body == nil {
+		body = &message.NetStreamSeek{}
+	}
+
+	result, err := s.writeCommandAndWaitStatus(ctx, "seek", body, message.NetStreamOnStatusCodeSeekNotify)
+	if err != nil {
+		return err
+	}
+
+	if result.InfoObject.Level == message.NetStreamOnStatusLevelError {
+		return &SeekRejectedError{
+			Result: result,
+		}
+	}
+
+	return nil
+}
+
+func (s *Stream) ReceiveAudio(body *message.NetStreamReceiveAudio) error {
+	return s.ReceiveAudioContext(context.Background(), body)
+}
+
+// ReceiveAudioContext Sends a receiveAudio command. It does not wait for statuses because nothing is replied
+// if receiving is disabled, thus statuses are passed to the status handler.
+func (s *Stream) ReceiveAudioContext(ctx context.Context, body *message.NetStreamReceiveAudio) error {
+	if body == nil {
+		body = &message.NetStreamReceiveAudio{}
+	}
+
+	// Transaction ID is always 0, 7.2.2.4
+	return s.writeStreamCommandContext(ctx, "receiveAudio", body)
+}
+
+func (s *Stream) ReceiveVideo(body *message.NetStreamReceiveVideo) error {
+	return s.ReceiveVideoContext(context.Background(), body)
+}
+
+// ReceiveVideoContext Sends a receiveVideo command. It does not wait for statuses because nothing is replied
+// if receiving is disabled, thus statuses are passed to the status handler.
+func (s *Stream) ReceiveVideoContext(ctx context.Context, body *message.NetStreamReceiveVideo) error {
+	if body == nil {
+		body = &message.NetStreamReceiveVideo{}
+	}
+
+	// Transaction ID is always 0, 7.2.2.5
+	return s.writeStreamCommandContext(ctx, "receiveVideo", body)
+}
+
+// SetStatusHandler Sets a callback which receives statuses sent asynchronously by the server,
+// e.g. NetStream.Play.Stop, NetStream.Play.UnpublishNotify and NetStream.Play.InsufficientBW.
+// Statuses which are results of Publish and Play are not passed to it.
+func (s *Stream) SetStatusHandler(f StatusHandlerFunc) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.statusHandler = f
+}
+
+// Call Sends an arbitrary command and waits for the result until ctx is done.
+// args are values following the transaction ID, thus a command object (or nil) should be the first of them.
+// The result is values following the transaction ID of _result, and CallRejectedError is returned for _error.
+func (s *Stream) Call(ctx context.Context, name string, args ...interface{}) ([]interface{}, error) {
+	transactionID := s.conn.newTransactionID()
+	t, err := s.transactions.Create(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	body := callArgs(args)
+	err = s.writeCommandMessageContext(
+		ctx,
+		commandChunkStreamID, 0, // TODO: fix, Timestamp is 0
+		name,
+		transactionID,
+		&body,
 	)
+	if err != nil {
+		_ = s.transactions.Delete(transactionID)
+		return nil, err
+	}
+
+	if err := s.waitTransaction(ctx, name, transactionID, t); err != nil {
+		return nil, err
+	}
+
+	amfDec := message.NewAMFDecoder(t.body, t.encoding)
+
+	result := make([]interface{}, 0)
+	for {
+		var v interface{}
+		if err := amfDec.Decode(&v); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "Failed to decode result")
+		}
+		result = append(result, v)
+	}
+
+	if t.commandName == "_error" {
+		return nil, &CallRejectedError{
+			CommandName:   name,
+			TransactionID: transactionID,
+			Result:        result,
+		}
+	}
+
+	return result, nil
 }
 
 func (s *Stream) NotifyStatus(
@@ -262,7 +484,127 @@ func (s *Stream) Close() error {
 }
 
 func (s *Stream) assumeClosed() {
-	// TODO: implement
+	// Do not wait for writers here, because it may be called from the reader goroutine which receives acknowledgements
+	go s.releaseMediaTracks()
+}
+
+// waitTransaction Waits for the result of the transaction until ctx is done.
+// The transaction is removed if ctx is done, and the result which arrives later will be ignored.
+func (s *Stream) waitTransaction(ctx context.Context, commandName string, transactionID int64, t *transaction) error {
+	select {
+	case <-t.doneCh:
+		return t.lastErr
+
+	case <-ctx.Done():
+		_ = s.transactions.Delete(transactionID) // The transaction may be already resolved
+		return &TransactionTimeoutError{
+			CommandName:   commandName,
+			TransactionID: transactionID,
+			Err:           ctx.Err(),
+		}
+	}
+}
+
+// expectStatus Registers a waiter of a status which is a result of a command.
+// The waiter is resolved by the status of the code, or by any status of the error level.
+// Only one command can wait for a status at a time because statuses do not have transaction IDs.
+func (s *Stream) expectStatus(code message.NetStreamOnStatusCode) (*statusWaiter, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.statusWaiter != nil {
+		return nil, errors.Errorf("Another status is already waited: Code = %s", s.statusWaiter.code)
+	}
+
+	w := &statusWaiter{
+		code:     code,
+		resultCh: make(chan *message.NetStreamOnStatus, 1),
+	}
+	s.statusWaiter = w
+
+	return w, nil
+}
+
+func (s *Stream) unexpectStatus(w *statusWaiter) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.statusWaiter == w {
+		s.statusWaiter = nil
+	}
+}
+
+func (s *Stream) waitStatus(
+	ctx context.Context,
+	commandName string,
+	w *statusWaiter,
+) (*message.NetStreamOnStatus, error) {
+	select {
+	case result := <-w.resultCh:
+		return result, nil
+
+	case <-ctx.Done():
+		s.unexpectStatus(w)
+		return nil, &TransactionTimeoutError{
+			CommandName:   commandName,
+			TransactionID: 0, // Always 0
+			Err:           ctx.Err(),
+		}
+	}
+}
+
+// writeCommandAndWaitStatus Sends a command whose transaction ID is 0, and waits for the status of the code.
+func (s *Stream) writeCommandAndWaitStatus(
+	ctx context.Context,
+	commandName string,
+	body message.AMFConvertible,
+	code message.NetStreamOnStatusCode,
+) (*message.NetStreamOnStatus, error) {
+	w, err := s.expectStatus(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.writeStreamCommandContext(ctx, commandName, body); err != nil {
+		s.unexpectStatus(w)
+		return nil, err
+	}
+
+	return s.waitStatus(ctx, commandName, w)
+}
+
+// writeStreamCommandContext Sends a command of NetStream whose transaction ID is 0.
+func (s *Stream) writeStreamCommandContext(
+	ctx context.Context,
+	commandName string,
+	body message.AMFConvertible,
+) error {
+	return s.writeCommandMessageContext(
+		ctx,
+		commandChunkStreamID, 0, // TODO: fix, Timestamp is 0
+		commandName,
+		int64(0), // Always 0
+		body,
+	)
+}
+
+// handleStatus Passes a status sent by the server to the waiter, or to the status handler if no one is waiting for it.
+func (s *Stream) handleStatus(timestamp uint32, status *message.NetStreamOnStatus) {
+	s.m.Lock()
+	w := s.statusWaiter
+	if w != nil && (status.InfoObject.Code == w.code || status.InfoObject.Level == message.NetStreamOnStatusLevelError) {
+		s.statusWaiter = nil
+		s.m.Unlock()
+
+		w.resultCh <- status // Never blocks because it is sent only once
+		return
+	}
+	f := s.statusHandler
+	s.m.Unlock()
+
+	if f != nil {
+		f(timestamp, status)
+	}
 }
 
 func (s *Stream) writeCommandMessage(
@@ -271,17 +613,32 @@ func (s *Stream) writeCommandMessage(
 	commandName string,
 	transactionID int64,
 	body message.AMFConvertible,
+) error {
+	ctx, cancel := s.conn.newWriteContext()
+	defer cancel()
+
+	return s.writeCommandMessageContext(ctx, chunkStreamID, timestamp, commandName, transactionID, body)
+}
+
+func (s *Stream) writeCommandMessageContext(
+	ctx context.Context,
+	chunkStreamID int,
+	timestamp uint32,
+	commandName string,
+	transactionID int64,
+	body message.AMFConvertible,
 ) error {
 	buf := new(bytes.Buffer)
-	amfEnc := message.NewAMFEncoder(buf, s.encTy)
+	encTy := s.encodingType()
+	amfEnc := message.NewAMFEncoder(buf, encTy)
 	if err := message.EncodeBodyAnyValues(amfEnc, body); err != nil {
 		return err
 	}
 
-	return s.Write(chunkStreamID, timestamp, &message.CommandMessage{
+	return s.WriteContext(ctx, chunkStreamID, timestamp, &message.CommandMessage{
 		CommandName:   commandName,
 		TransactionID: transactionID,
-		Encoding:      s.encTy,
+		Encoding:      encTy,
 		Body:          buf,
 	})
 }
@@ -319,11 +676,26 @@ func (s *Stream) WriteSetChunkSize(chunkSize uint32) error {
 }
 
 func (s *Stream) Write(chunkStreamID int, timestamp uint32, msg message.Message) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second) // TODO: Fix 5s
+	ctx, cancel := s.conn.newWriteContext()
 	defer cancel()
 
-	s.cmsg.Message = msg
-	return s.streamer().Write(ctx, chunkStreamID, timestamp, &s.cmsg)
+	return s.WriteContext(ctx, chunkStreamID, timestamp, msg)
+}
+
+func (s *Stream) WriteContext(ctx context.Context, chunkStreamID int, timestamp uint32, msg message.Message) error {
+	// Write may be called from multiple goroutines, thus a ChunkMessage is allocated per a call
+	return s.streamer().Write(ctx, chunkStreamID, timestamp, &ChunkMessage{
+		StreamID: s.streamID,
+		Message:  msg,
+	})
+}
+
+// AbortWrite Cancels a message which is being written to the chunk stream, e.g. when a subscriber falls behind.
+func (s *Stream) AbortWrite(chunkStreamID int) error {
+	ctx, cancel := s.conn.newWriteContext()
+	defer cancel()
+
+	return s.streamer().AbortWrite(ctx, chunkStreamID)
 }
 
 func (s *Stream) handle(chunkStreamID int, timestamp uint32, msg message.Message) error {
@@ -342,6 +714,71 @@ func (s *Stream) userHandler() Handler {
 	return s.conn.handler
 }
 
+func (s *Stream) setEncodingType(encTy message.EncodingType) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.encTy = encTy
+}
+
+func (s *Stream) encodingType() message.EncodingType {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.encTy
+}
+
+func (s *Stream) setPlayHandler(handler PlayHandler) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.playHandlerValue = handler
+}
+
+func (s *Stream) playHandler() PlayHandler {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.playHandlerValue == nil {
+		return &DefaultPlayHandler{}
+	}
+	return s.playHandlerValue
+}
+
+func (s *Stream) setPlaySession(session *PlaySession) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.playSessionValue = session
+}
+
+func (s *Stream) playSession() *PlaySession {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.playSessionValue
+}
+
 func (s *Stream) logger() logrus.FieldLogger {
 	return s.conn.logger
 }
+
+// callArgs Arguments of a command which are encoded as they are
+type callArgs []interface{}
+
+func (a *callArgs) FromArgs(args ...interface{}) error {
+	*a = args
+	return nil
+}
+
+func (a *callArgs) ToArgs(ty message.EncodingType) ([]interface{}, error) {
+	return *a, nil
+}
+
+// StatusHandlerFunc A callback which receives statuses of a stream.
+type StatusHandlerFunc func(timestamp uint32, status *message.NetStreamOnStatus)
+
+type statusWaiter struct {
+	code     message.NetStreamOnStatusCode
+	resultCh chan *message.NetStreamOnStatus
+}
@@ -10,7 +10,6 @@ package rtmp
 import (
 	"sync"
 
-	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/yutopp/go-rtmp/internal"
@@ -28,6 +27,9 @@ const (
 	streamStateServerPlay
 	streamStateClientNotConnected
 	streamStateClientConnected
+	streamStateClientInactive
+	streamStateClientPlay
+	streamStateClientPublish
 )
 
 func (s streamState) String() string {
@@ -46,6 +48,12 @@ func (s streamState) String() string {
 		return "NotConnected(Client)"
 	case streamStateClientConnected:
 		return "Connected(Client)"
+	case streamStateClientInactive:
+		return "Inactive(Client)"
+	case streamStateClientPlay:
+		return "Play(Client)"
+	case streamStateClientPublish:
+		return "Publish(Client)"
 	default:
 		return "<Unknown>"
 	}
@@ -80,16 +88,32 @@ func (h *streamHandler) Handle(chunkStreamID int, timestamp uint32, msg message.
 	case *message.CommandMessage:
 		return h.handleCommand(chunkStreamID, timestamp, msg)
 
+	case *message.AggregateMessage:
+		return h.handleAggregate(chunkStreamID, timestamp, msg)
+
 	case *message.SetChunkSize:
 		l.Infof("Handle SetChunkSize: Msg = %#v", msg)
 		return h.stream.streamer().PeerState().SetChunkSize(msg.ChunkSize)
 
+	case *message.AbortMessage:
+		l.Infof("Handle AbortMessage: Msg = %#v", msg)
+		h.stream.streamer().abortRead(int(msg.ChunkStreamID))
+		return nil
+
+	case *message.SetPeerBandwidth:
+		l.Infof("Handle SetPeerBandwidth: Msg = %#v", msg)
+		return h.stream.streamer().setPeerBandwidth(msg.Size, msg.Limit)
+
+	case *message.Ack:
+		h.stream.streamer().ackReceived(msg.SequenceNumber)
+		return nil
+
 	case *message.WinAckSize:
 		l.Infof("Handle WinAckSize: Msg = %#v", msg)
 		return h.stream.streamer().PeerState().SetAckWindowSize(msg.Size)
 
 	default:
-		err := h.handler.onMessage(chunkStreamID, timestamp, msg)
+		err := h.stateHandler().onMessage(chunkStreamID, timestamp, msg)
 		if err == internal.ErrPassThroughMsg {
 			return h.stream.userHandler().OnUnknownMessage(timestamp, msg)
 		}
@@ -98,14 +122,22 @@ func (h *streamHandler) Handle(chunkStreamID int, timestamp uint32, msg message.
 }
 
 func (h *streamHandler) ChangeState(state streamState) {
+	prevState := h.State()
+	if !h.setState(state) {
+		return
+	}
+
+	l := h.Logger()
+	l.Infof("Change state: From = %s, To = %s", prevState, h.State())
+}
+
+func (h *streamHandler) setState(state streamState) bool {
 	h.m.Lock()
 	defer h.m.Unlock()
 
-	prevState := h.State()
-
 	switch state {
 	case streamStateUnknown:
-		return // DO NOTHING
+		return false // DO NOTHING
 	case streamStateServerNotConnected:
 		h.handler = &serverControlNotConnectedHandler{sh: h}
 	case streamStateServerConnected:
@@ -118,31 +150,49 @@ func (h *streamHandler) ChangeState(state streamState) {
 		h.handler = &serverDataPlayHandler{sh: h}
 	case streamStateClientNotConnected:
 		h.handler = &clientControlNotConnectedHandler{sh: h}
-		// 	case streamStateClientConnected:
-		// 		h.handler = &serverControlConnectedHandler{sh: h}
+	case streamStateClientConnected:
+		h.handler = &clientControlConnectedHandler{sh: h}
+	case streamStateClientInactive:
+		h.handler = &clientDataInactiveHandler{sh: h}
+	case streamStateClientPlay:
+		h.handler = &clientDataPlayHandler{sh: h}
+	case streamStateClientPublish:
+		h.handler = &clientDataPublishHandler{sh: h}
 	default:
 		panic("Unexpected")
 	}
 	h.state = state
 
-	l := h.Logger()
-	l.Infof("Change state: From = %s, To = %s", prevState, h.State())
+	return true
 }
 
+// State Returns the current state. It can be called from goroutines other than the one handling messages.
 func (h *streamHandler) State() streamState {
+	h.m.Lock()
+	defer h.m.Unlock()
+
 	return h.state
 }
 
 func (h *streamHandler) Logger() *logrus.Entry {
+	h.m.Lock()
+	defer h.m.Unlock()
+
 	if h.loggerEntry == nil {
 		h.loggerEntry = h.stream.logger().WithFields(logrus.Fields{
 			"stream_id": h.stream.streamID,
 		})
 	}
 
-	h.loggerEntry.Data["state"] = h.State()
+	// Make a new entry because the state may be changed by a client from other goroutines
+	return h.loggerEntry.WithField("state", h.state)
+}
 
-	return h.loggerEntry
+func (h *streamHandler) stateHandler() stateHandler {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	return h.handler
 }
 
 func (h *streamHandler) handleData(
@@ -158,13 +208,34 @@ func (h *streamHandler) handleData(
 		return err
 	}
 
-	err := h.handler.onData(chunkStreamID, timestamp, dataMsg, value)
+	err := h.stateHandler().onData(chunkStreamID, timestamp, dataMsg, value)
 	if err == internal.ErrPassThroughMsg {
 		return h.stream.userHandler().OnUnknownDataMessage(timestamp, dataMsg)
 	}
 	return err
 }
 
+// handleAggregate Handles sub messages as if they are sent individually.
+// Timestamps of sub messages are rebased on the timestamp of the aggregate message.
+func (h *streamHandler) handleAggregate(
+	chunkStreamID int,
+	timestamp uint32,
+	aggMsg *message.AggregateMessage,
+) error {
+	if len(aggMsg.Messages) == 0 {
+		return nil
+	}
+
+	baseTimestamp := aggMsg.Messages[0].Timestamp
+	for _, subMsg := range aggMsg.Messages {
+		if err := h.Handle(chunkStreamID, timestamp+(subMsg.Timestamp-baseTimestamp), subMsg.Message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (h *streamHandler) handleCommand(
 	chunkStreamID int,
 	timestamp uint32,
@@ -172,22 +243,17 @@ func (h *streamHandler) handleCommand(
 ) error {
 	switch cmdMsg.CommandName {
 	case "_result", "_error":
-		t, err := h.stream.transactions.At(cmdMsg.TransactionID)
+		t, err := h.stream.transactions.Pop(cmdMsg.TransactionID)
 		if err != nil {
-			return errors.Wrap(err, "Got response to the unexpected transaction")
+			// A waiter of the transaction may have been canceled
+			h.Logger().Warnf("Ignored a response to the unexpected transaction: Err = %+v", err)
+			return nil
 		}
 
 		// Set result (NOTE: should use a mutex for it?)
 		t.Reply(cmdMsg.CommandName, cmdMsg.Encoding, cmdMsg.Body)
 
-		// Remove transacaction because this transaction is resolved
-		if err := h.stream.transactions.Delete(cmdMsg.TransactionID); err != nil {
-			return errors.Wrap(err, "Unexpected behavior: transaction is not found")
-		}
-
 		return nil
-
-		// TODO: Support onStatus
 	}
 
 	amfDec := message.NewAMFDecoder(cmdMsg.Body, cmdMsg.Encoding)
@@ -198,7 +264,7 @@ func (h *streamHandler) handleCommand(
 		return err
 	}
 
-	err := h.handler.onCommand(chunkStreamID, timestamp, cmdMsg, value)
+	err := h.stateHandler().onCommand(chunkStreamID, timestamp, cmdMsg, value)
 	if err == internal.ErrPassThroughMsg {
 		return h.stream.userHandler().OnUnknownCommandMessage(timestamp, cmdMsg)
 	}
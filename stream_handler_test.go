@@ -45,6 +45,22 @@ func TestStreamHandlerChangeState(t *testing.T) {
 	s.handler.ChangeState(streamStateClientNotConnected)
 	require.Equal(t, s.handler.state, streamStateClientNotConnected)
 	require.Equal(t, s.handler.handler, &clientControlNotConnectedHandler{sh: s.handler})
+
+	s.handler.ChangeState(streamStateClientConnected)
+	require.Equal(t, s.handler.state, streamStateClientConnected)
+	require.Equal(t, s.handler.handler, &clientControlConnectedHandler{sh: s.handler})
+
+	s.handler.ChangeState(streamStateClientInactive)
+	require.Equal(t, s.handler.state, streamStateClientInactive)
+	require.Equal(t, s.handler.handler, &clientDataInactiveHandler{sh: s.handler})
+
+	s.handler.ChangeState(streamStateClientPlay)
+	require.Equal(t, s.handler.state, streamStateClientPlay)
+	require.Equal(t, s.handler.handler, &clientDataPlayHandler{sh: s.handler})
+
+	s.handler.ChangeState(streamStateClientPublish)
+	require.Equal(t, s.handler.state, streamStateClientPublish)
+	require.Equal(t, s.handler.handler, &clientDataPublishHandler{sh: s.handler})
 }
 
 func TestStreamStateString(t *testing.T) {
@@ -56,4 +72,7 @@ func TestStreamStateString(t *testing.T) {
 	require.Equal(t, "Play(Server)", streamStateServerPlay.String())
 	require.Equal(t, "NotConnected(Client)", streamStateClientNotConnected.String())
 	require.Equal(t, "Connected(Client)", streamStateClientConnected.String())
+	require.Equal(t, "Inactive(Client)", streamStateClientInactive.String())
+	require.Equal(t, "Play(Client)", streamStateClientPlay.String())
+	require.Equal(t, "Publish(Client)", streamStateClientPublish.String())
 }
@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/yutopp/go-amf0"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+// mediaTrack A chunk stream dedicated to a type of media of a stream.
+type mediaTrack struct {
+	chunkStreamID int // Allocated when the first message is written
+	lastTimestamp uint32
+	written       bool
+	m             sync.Mutex
+}
+
+func (s *Stream) WriteAudio(timestamp uint32, payload io.Reader) error {
+	ctx, cancel := s.conn.newWriteContext()
+	defer cancel()
+
+	return s.WriteAudioContext(ctx, timestamp, payload)
+}
+
+// WriteAudioContext Writes an audio message to the chunk stream dedicated to audio of the stream.
+// It blocks until the message is queued, thus it is throttled when the peer is slow.
+func (s *Stream) WriteAudioContext(ctx context.Context, timestamp uint32, payload io.Reader) error {
+	return s.writeMediaContext(ctx, &s.audioTrack, timestamp, &message.AudioMessage{
+		Payload: payload,
+	})
+}
+
+func (s *Stream) WriteVideo(timestamp uint32, payload io.Reader) error {
+	ctx, cancel := s.conn.newWriteContext()
+	defer cancel()
+
+	return s.WriteVideoContext(ctx, timestamp, payload)
+}
+
+// WriteVideoContext Writes a video message to the chunk stream dedicated to video of the stream.
+// It blocks until the message is queued, thus it is throttled when the peer is slow.
+func (s *Stream) WriteVideoContext(ctx context.Context, timestamp uint32, payload io.Reader) error {
+	return s.writeMediaContext(ctx, &s.videoTrack, timestamp, &message.VideoMessage{
+		Payload: payload,
+	})
+}
+
+func (s *Stream) WriteMetadata(metadata map[string]interface{}) error {
+	ctx, cancel := s.conn.newWriteContext()
+	defer cancel()
+
+	return s.WriteMetadataContext(ctx, metadata)
+}
+
+// WriteMetadataContext Writes metadata as "@setDataFrame", "onMetaData", {...} to the chunk stream dedicated to data.
+func (s *Stream) WriteMetadataContext(ctx context.Context, metadata map[string]interface{}) error {
+	buf := new(bytes.Buffer)
+	amfEnc := message.NewAMFEncoder(buf, message.EncodingTypeAMF0)
+	if err := message.EncodeBodyAnyValues(amfEnc, &message.NetStreamSetDataFrame{
+		AmfData: amf0.ECMAArray(metadata),
+	}); err != nil {
+		return err
+	}
+
+	return s.writeMediaContext(ctx, &s.dataTrack, 0, &message.DataMessage{
+		Name:     "@setDataFrame",
+		Encoding: message.EncodingTypeAMF0,
+		Body:     buf,
+	})
+}
+
+// releaseMediaTracks Releases chunk streams of media to reuse them by other streams.
+func (s *Stream) releaseMediaTracks() {
+	ctx, cancel := s.conn.newWriteContext()
+	defer cancel()
+
+	for _, track := range []*mediaTrack{&s.audioTrack, &s.videoTrack, &s.dataTrack} {
+		track.m.Lock()
+		if track.chunkStreamID != 0 {
+			if err := s.conn.releaseChunkStreamID(ctx, track.chunkStreamID); err != nil {
+				s.logger().Warnf("Failed to release a chunk stream: ID = %d, Err = %+v", track.chunkStreamID, err)
+			}
+			track.chunkStreamID = 0
+			track.written = false
+		}
+		track.m.Unlock()
+	}
+}
+
+func (s *Stream) writeMediaContext(ctx context.Context, track *mediaTrack, timestamp uint32, msg message.Message) error {
+	// Keep the order of messages as same as the order of timestamps
+	track.m.Lock()
+	defer track.m.Unlock()
+
+	if track.chunkStreamID == 0 {
+		chunkStreamID, err := s.conn.newChunkStreamID()
+		if err != nil {
+			return err
+		}
+		track.chunkStreamID = chunkStreamID
+	}
+
+	// Timestamps must be monotonic in a chunk stream, thus a reversed timestamp is adjusted to the last one.
+	// Differences are compared as signed values to allow timestamps to wrap around.
+	if track.written && int32(timestamp-track.lastTimestamp) < 0 {
+		s.logger().Warnf(
+			"Timestamp is reversed, adjusted to the last one: ChunkStreamID = %d, Timestamp = %d, Last = %d",
+			track.chunkStreamID,
+			timestamp,
+			track.lastTimestamp,
+		)
+		timestamp = track.lastTimestamp
+	}
+
+	if err := s.WriteContext(ctx, track.chunkStreamID, timestamp, msg); err != nil {
+		return err
+	}
+	track.lastTimestamp = timestamp
+	track.written = true
+
+	return nil
+}
@@ -0,0 +1,36 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+func TestStreamExpectsOnlyOneStatus(t *testing.T) {
+	rwc := &rwcMock{}
+	c := newConn(rwc, nil)
+	s := newStream(42, c)
+
+	w, err := s.expectStatus(message.NetStreamOnStatusCodePauseNotify)
+	require.Nil(t, err)
+
+	_, err = s.expectStatus(message.NetStreamOnStatusCodeSeekNotify)
+	require.EqualError(t, err, "Another status is already waited: Code = NetStream.Pause.Notify")
+
+	// The waiter is resolved by the status, and then a next one can be registered
+	status := newPlayOnStatus(message.NetStreamOnStatusCodePauseNotify, "Paused.")
+	s.handleStatus(0, status)
+	require.Equal(t, status, <-w.resultCh)
+
+	_, err = s.expectStatus(message.NetStreamOnStatusCodeSeekNotify)
+	require.Nil(t, err)
+}
@@ -11,6 +11,8 @@ import (
 	"sync"
 
 	"github.com/pkg/errors"
+
+	"github.com/yutopp/go-rtmp/message"
 )
 
 // ControlStreamID StreamID 0 is a control stream
@@ -18,6 +20,7 @@ const ControlStreamID = 0
 
 type streams struct {
 	streams map[uint32]*Stream
+	encTy   message.EncodingType // Encoding of streams which will be created
 	m       sync.Mutex
 
 	conn *Conn
@@ -26,6 +29,7 @@ type streams struct {
 func newStreams(conn *Conn) *streams {
 	return &streams{
 		streams: make(map[uint32]*Stream),
+		encTy:   message.EncodingTypeAMF0, // Default AMF encoding type
 
 		conn: conn,
 	}
@@ -46,9 +50,22 @@ func (ss *streams) Create(streamID uint32) (*Stream, error) {
 		)
 	}
 
-	ss.streams[streamID] = newStream(streamID, ss.conn)
+	s := newStream(streamID, ss.conn)
+	s.setEncodingType(ss.encTy)
+	ss.streams[streamID] = s
+
+	return s, nil
+}
+
+// SetEncodingType Changes the AMF encoding of all streams, e.g. when AMF3 is negotiated by "connect".
+func (ss *streams) SetEncodingType(encTy message.EncodingType) {
+	ss.m.Lock()
+	defer ss.m.Unlock()
 
-	return ss.streams[streamID], nil
+	ss.encTy = encTy
+	for _, s := range ss.streams {
+		s.setEncodingType(encTy)
+	}
 }
 
 func (ss *streams) CreateIfAvailable() (*Stream, error) {
@@ -83,6 +100,9 @@ func (ss *streams) Delete(streamID uint32) error {
 }
 
 func (ss *streams) At(streamID uint32) (*Stream, error) {
+	ss.m.Lock()
+	defer ss.m.Unlock()
+
 	stream, ok := ss.streams[streamID]
 	if !ok {
 		return nil, errors.Errorf("Stream is not found: StreamID = %d", streamID)
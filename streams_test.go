@@ -8,7 +8,9 @@
 package rtmp
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -38,3 +40,35 @@ func TestStreams(t *testing.T) {
 	err = streams.Delete(s.streamID)
 	require.NotNil(t, err)
 }
+
+func TestStreamsReleaseChunkStreamsOfDeletedStreams(t *testing.T) {
+	b := &rwcMock{}
+	conn := newConn(b, nil)
+	defer conn.Close()
+
+	streams := newStreams(conn)
+
+	s, err := streams.Create(1)
+	require.Nil(t, err)
+	err = s.WriteAudio(0, bytes.NewReader([]byte("audio")))
+	require.Nil(t, err)
+	chunkStreamID := s.audioTrack.chunkStreamID
+
+	err = streams.Delete(s.streamID)
+	require.Nil(t, err)
+
+	// Chunk streams are released after writers are finished
+	require.Eventually(t, func() bool {
+		conn.chunkStreamIDsM.Lock()
+		defer conn.chunkStreamIDsM.Unlock()
+
+		return len(conn.freeChunkStreamIDs) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// The chunk stream ID is reused by another stream
+	s, err = streams.Create(2)
+	require.Nil(t, err)
+	err = s.WriteAudio(0, bytes.NewReader([]byte("audio")))
+	require.Nil(t, err)
+	require.Equal(t, chunkStreamID, s.audioTrack.chunkStreamID)
+}
@@ -76,6 +76,9 @@ func (ts *transactions) Delete(transactionID int64) error {
 }
 
 func (ts *transactions) At(transactionID int64) (*transaction, error) {
+	ts.m.RLock()
+	defer ts.m.RUnlock()
+
 	t, ok := ts.transactions[transactionID]
 	if !ok {
 		return nil, errors.Errorf("Transaction is not found: TransactionID = %d", transactionID)
@@ -83,3 +86,18 @@ func (ts *transactions) At(transactionID int64) (*transaction, error) {
 
 	return t, nil
 }
+
+// Pop Removes the transaction and returns it. It is used to resolve a transaction exclusively
+// because the transaction may be removed concurrently by a waiter which is canceled.
+func (ts *transactions) Pop(transactionID int64) (*transaction, error) {
+	ts.m.Lock()
+	defer ts.m.Unlock()
+
+	t, ok := ts.transactions[transactionID]
+	if !ok {
+		return nil, errors.Errorf("Transaction is not found: TransactionID = %d", transactionID)
+	}
+	delete(ts.transactions, transactionID)
+
+	return t, nil
+}
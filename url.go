@@ -0,0 +1,242 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+const (
+	DefaultRTMPPort  = "1935"
+	DefaultRTMPSPort = "443"
+)
+
+// DefaultFlashVer A flashVer sent by clients which are created by URL helpers.
+const DefaultFlashVer = "FMLE/3.0 (compatible; go-rtmp)"
+
+// URL A parsed RTMP URL, e.g. rtmp://host:port/app/instance/streamName?query
+type URL struct {
+	Scheme     string // "rtmp" or "rtmps"
+	Host       string // Includes a port. A default port of the scheme is filled if it is omitted
+	App        string
+	Instance   string // Optional
+	StreamName string // Optional
+	RawQuery   string // Optional, without '?'
+}
+
+// ParseURL Parses an RTMP URL. The first path segment is an app, the second one is an instance if there are more than
+// two segments, and the rest is a stream name.
+func ParseURL(rawURL string) (*URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse URL")
+	}
+
+	var defaultPort string
+	switch u.Scheme {
+	case "rtmp":
+		defaultPort = DefaultRTMPPort
+	case "rtmps":
+		defaultPort = DefaultRTMPSPort
+	default:
+		return nil, errors.Errorf("Unknown scheme: %s", u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return nil, errors.Errorf("Host is empty: URL = %s", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if segments[0] == "" {
+		return nil, errors.Errorf("App is empty: URL = %s", rawURL)
+	}
+
+	result := &URL{
+		Scheme:   u.Scheme,
+		Host:     net.JoinHostPort(u.Hostname(), port),
+		App:      segments[0],
+		RawQuery: u.RawQuery,
+	}
+	switch {
+	case len(segments) == 2:
+		result.StreamName = segments[1]
+	case len(segments) > 2:
+		result.Instance = segments[1]
+		result.StreamName = strings.Join(segments[2:], "/")
+	}
+
+	return result, nil
+}
+
+// ConnectApp Returns an app sent by connect, which includes an instance if it exists.
+func (u *URL) ConnectApp() string {
+	if u.Instance == "" {
+		return u.App
+	}
+	return u.App + "/" + u.Instance
+}
+
+// TCURL Returns a tcUrl sent by connect, which does not include a stream name.
+func (u *URL) TCURL() string {
+	return u.Scheme + "://" + u.Host + "/" + u.ConnectApp()
+}
+
+// PublishingName Returns a stream name used by publish and play, which includes a query if it exists.
+// Tokens are usually passed by queries of stream names.
+func (u *URL) PublishingName() string {
+	if u.RawQuery == "" {
+		return u.StreamName
+	}
+	return u.StreamName + "?" + u.RawQuery
+}
+
+// ConnectCommand Returns a connect command filled by the URL.
+func (u *URL) ConnectCommand() *message.NetConnectionConnect {
+	return &message.NetConnectionConnect{
+		Command: message.NetConnectionConnectCommand{
+			App:      u.ConnectApp(),
+			Type:     "nonprivate",
+			FlashVer: DefaultFlashVer,
+			TCURL:    u.TCURL(),
+		},
+	}
+}
+
+func (u *URL) String() string {
+	s := u.TCURL()
+	if u.StreamName != "" {
+		s += "/" + u.PublishingName()
+	}
+	return s
+}
+
+func DialURL(rawURL string, config *ConnConfig) (*ClientConn, error) {
+	return DialURLContext(context.Background(), rawURL, config)
+}
+
+// DialURLContext Dials to the server of the URL, and sends connect filled by the URL.
+// config.TLSConfig is used for rtmps URLs.
+func DialURLContext(ctx context.Context, rawURL string, config *ConnConfig) (*ClientConn, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return dialURLContext(ctx, u, config)
+}
+
+func PublishURL(rawURL string, config *ConnConfig) (*ClientConn, *Stream, error) {
+	return PublishURLContext(context.Background(), rawURL, config)
+}
+
+// PublishURLContext Dials to the server of the URL, creates a stream and publishes it as a live stream.
+func PublishURLContext(ctx context.Context, rawURL string, config *ConnConfig) (*ClientConn, *Stream, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.StreamName == "" {
+		return nil, nil, errors.Errorf("Stream name is empty: URL = %s", rawURL)
+	}
+
+	return openStreamURLContext(ctx, u, config, func(s *Stream) error {
+		return s.PublishContext(ctx, &message.NetStreamPublish{
+			PublishingName: u.PublishingName(),
+			PublishingType: "live",
+		})
+	})
+}
+
+func PlayURL(rawURL string, config *ConnConfig, handler PlayHandler) (*ClientConn, *Stream, error) {
+	return PlayURLContext(context.Background(), rawURL, config, handler)
+}
+
+// PlayURLContext Dials to the server of the URL, creates a stream and plays it. Media are passed to handler.
+func PlayURLContext(
+	ctx context.Context,
+	rawURL string,
+	config *ConnConfig,
+	handler PlayHandler,
+) (*ClientConn, *Stream, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.StreamName == "" {
+		return nil, nil, errors.Errorf("Stream name is empty: URL = %s", rawURL)
+	}
+
+	return openStreamURLContext(ctx, u, config, func(s *Stream) error {
+		return s.PlayContext(ctx, &message.NetStreamPlay{
+			StreamName: u.PublishingName(),
+			StartMode:  message.NetStreamPlayStartModeLiveOrRecorded,
+		}, handler)
+	})
+}
+
+func dialURLContext(ctx context.Context, u *URL, config *ConnConfig) (*ClientConn, error) {
+	var cc *ClientConn
+	var err error
+	switch u.Scheme {
+	case "rtmps":
+		var tlsConfig *tls.Config
+		if config != nil {
+			tlsConfig = config.TLSConfig
+		}
+		cc, err = TLSDialContext(ctx, u.Scheme, u.Host, config, tlsConfig)
+	default:
+		cc, err = DialContext(ctx, u.Scheme, u.Host, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cc.ConnectContext(ctx, u.ConnectCommand()); err != nil {
+		_ = cc.Close()
+		return nil, err
+	}
+
+	return cc, nil
+}
+
+func openStreamURLContext(
+	ctx context.Context,
+	u *URL,
+	config *ConnConfig,
+	open func(s *Stream) error,
+) (*ClientConn, *Stream, error) {
+	cc, err := dialURLContext(ctx, u, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s, err := cc.CreateStreamContext(ctx, nil, 0) // Keep the chunk size
+	if err != nil {
+		_ = cc.Close()
+		return nil, nil, err
+	}
+
+	if err := open(s); err != nil {
+		_ = cc.Close()
+		return nil, nil, err
+	}
+
+	return cc, s, nil
+}
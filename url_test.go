@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yutopp/go-rtmp/message"
+)
+
+func TestParseURL(t *testing.T) {
+	type testCase struct {
+		name        string
+		rawURL      string
+		expected    *URL
+		expectedErr bool
+	}
+
+	testCases := []testCase{
+		{
+			name:   "App and stream name",
+			rawURL: "rtmp://example.com/live/streamkey",
+			expected: &URL{
+				Scheme:     "rtmp",
+				Host:       "example.com:1935",
+				App:        "live",
+				StreamName: "streamkey",
+			},
+		},
+		{
+			name:   "Instance and query",
+			rawURL: "rtmps://ingest.example.com:8443/live/instance/streamkey?token=abc&x=1",
+			expected: &URL{
+				Scheme:     "rtmps",
+				Host:       "ingest.example.com:8443",
+				App:        "live",
+				Instance:   "instance",
+				StreamName: "streamkey",
+				RawQuery:   "token=abc&x=1",
+			},
+		},
+		{
+			name:   "Stream name which contains slashes",
+			rawURL: "rtmps://ingest.example.com/live/instance/mp4:dir/file.mp4",
+			expected: &URL{
+				Scheme:     "rtmps",
+				Host:       "ingest.example.com:443",
+				App:        "live",
+				Instance:   "instance",
+				StreamName: "mp4:dir/file.mp4",
+			},
+		},
+		{
+			name:   "App only",
+			rawURL: "rtmp://127.0.0.1:19350/live/",
+			expected: &URL{
+				Scheme: "rtmp",
+				Host:   "127.0.0.1:19350",
+				App:    "live",
+			},
+		},
+		{
+			name:        "Unknown scheme",
+			rawURL:      "http://example.com/live/streamkey",
+			expectedErr: true,
+		},
+		{
+			name:        "No app",
+			rawURL:      "rtmp://example.com",
+			expectedErr: true,
+		},
+		{
+			name:        "No host",
+			rawURL:      "rtmp:///live/streamkey",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := ParseURL(tc.rawURL)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, u)
+		})
+	}
+}
+
+func TestURLConnectCommand(t *testing.T) {
+	u, err := ParseURL("rtmps://ingest.example.com/live/instance/streamkey?token=abc")
+	require.Nil(t, err)
+
+	require.Equal(t, "streamkey?token=abc", u.PublishingName())
+	require.Equal(t, "rtmps://ingest.example.com:443/live/instance/streamkey?token=abc", u.String())
+	require.Equal(t, &message.NetConnectionConnect{
+		Command: message.NetConnectionConnectCommand{
+			App:      "live/instance",
+			Type:     "nonprivate",
+			FlashVer: DefaultFlashVer,
+			TCURL:    "rtmps://ingest.example.com:443/live/instance",
+		},
+	}, u.ConnectCommand())
+}